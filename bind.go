@@ -0,0 +1,113 @@
+package goapi
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+)
+
+// Bind decodes the JSON body of the request into v, fills the fields tagged with `param:"name"`
+// from the route parameters and then validates the result with Validate.
+//
+// The body is decoded with DecodeJSON, so the group's body size and JSON limits apply; an empty body
+// is accepted and leaves v untouched. v must be a pointer to a struct. Decoding problems are reported
+// as an *HTTPError, malformed parameters as a 400 *HTTPError and failed validation as ValidationErrors,
+// so the returned error can be passed directly to WriteError.
+//
+// Example:
+//
+//	type UpdateUser struct {
+//		ID   int    `param:"id" validate:"min=1"`
+//		Name string `json:"name" validate:"required,max=64"`
+//	}
+//
+//	api.PUT("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+//		var input UpdateUser
+//		if err := goapi.Bind(r, &input); err != nil {
+//			goapi.WriteError(w, r, err)
+//			return
+//		}
+//		// ...
+//	})
+func Bind(r *http.Request, v any) error {
+	target := reflect.ValueOf(v)
+	if target.Kind() != reflect.Pointer || target.IsNil() || target.Elem().Kind() != reflect.Struct {
+		panic("goapi: Bind requires a non-nil pointer to a struct")
+	}
+
+	if r.Body != nil && r.Body != http.NoBody {
+		if err := DecodeJSON(r, v); err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+	}
+
+	if err := bindParams(target.Elem(), ParamsFromContext(r)); err != nil {
+		return err
+	}
+
+	return Validate(v)
+}
+
+// bindParams sets every field tagged with `param` to the value of the matching route parameter.
+func bindParams(value reflect.Value, params map[string]string) error {
+	structType := value.Type()
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		name, ok := field.Tag.Lookup("param")
+		if !ok || !field.IsExported() {
+			continue
+		}
+
+		raw, ok := params[name]
+		if !ok {
+			continue
+		}
+
+		if err := setFromString(value.Field(i), raw); err != nil {
+			return &HTTPError{
+				Status:  http.StatusBadRequest,
+				Message: fmt.Sprintf("invalid value for parameter %s", name),
+				Err:     err,
+			}
+		}
+	}
+	return nil
+}
+
+// setFromString parses raw according to the kind of field and stores it.
+func setFromString(field reflect.Value, raw string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(raw)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(raw, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+	return nil
+}
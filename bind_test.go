@@ -0,0 +1,96 @@
+package goapi
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type updateUser struct {
+	ID     int    `param:"id" validate:"min=1"`
+	Name   string `json:"name" validate:"required,max=16"`
+	Active bool   `json:"active"`
+}
+
+func TestBind(t *testing.T) {
+	tests := []struct {
+		name           string
+		path           string
+		body           string
+		expectedStatus int
+		expected       updateUser
+	}{
+		{
+			name:     "Body and params",
+			path:     "/users/7",
+			body:     `{"name":"Ana","active":true}`,
+			expected: updateUser{ID: 7, Name: "Ana", Active: true},
+		},
+		{
+			name:           "Malformed JSON",
+			path:           "/users/7",
+			body:           `{"name":`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Invalid param",
+			path:           "/users/abc",
+			body:           `{"name":"Ana"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Validation failure",
+			path:           "/users/0",
+			body:           `{}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := &Group{}
+			var bound updateUser
+			var bindErr error
+			root.PUT("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+				bindErr = Bind(r, &bound)
+			})
+
+			req := httptest.NewRequest(http.MethodPut, test.path, strings.NewReader(test.body))
+			root.handleRequest(httptest.NewRecorder(), req)
+
+			if test.expectedStatus == 0 {
+				if bindErr != nil {
+					t.Fatalf("expected no error, got %v", bindErr)
+				}
+				if bound != test.expected {
+					t.Errorf("expected %+v, got %+v", test.expected, bound)
+				}
+				return
+			}
+
+			if status := toHTTPError(bindErr).Status; status != test.expectedStatus {
+				t.Errorf("expected status %d, got %d (%v)", test.expectedStatus, status, bindErr)
+			}
+		})
+	}
+}
+
+func TestBindEmptyBody(t *testing.T) {
+	type optional struct {
+		Name string `json:"name"`
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	var input optional
+	if err := Bind(req, &input); err != nil {
+		t.Errorf("expected empty body to be accepted, got %v", err)
+	}
+
+	var validationErrs ValidationErrors
+	req = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(""))
+	if err := Bind(req, &updateUser{}); !errors.As(err, &validationErrs) {
+		t.Errorf("expected validation errors for an empty body, got %v", err)
+	}
+}
@@ -0,0 +1,125 @@
+package goapi
+
+import (
+	"encoding"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// JSONCodec encodes values with encoding/json.
+type JSONCodec struct{}
+
+func (JSONCodec) ContentType() string { return "application/json" }
+
+func (JSONCodec) Encode(w io.Writer, v any) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+// XMLCodec encodes values with encoding/xml.
+type XMLCodec struct{}
+
+func (XMLCodec) ContentType() string { return "application/xml; charset=utf-8" }
+
+func (XMLCodec) Encode(w io.Writer, v any) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	return xml.NewEncoder(w).Encode(v)
+}
+
+// CSVMarshaler is implemented by values that know how to represent themselves as CSV records.
+type CSVMarshaler interface {
+	MarshalCSV() ([][]string, error)
+}
+
+// CSVCodec encodes values as CSV. It accepts values implementing CSVMarshaler, [][]string, and
+// slices of structs. Struct slices produce a header row built from the `csv` tag of each exported
+// field, falling back to the field name; fields tagged `csv:"-"` are skipped.
+type CSVCodec struct{}
+
+func (CSVCodec) ContentType() string { return "text/csv; charset=utf-8" }
+
+func (CSVCodec) Encode(w io.Writer, v any) error {
+	records, err := csvRecords(v)
+	if err != nil {
+		return err
+	}
+	writer := csv.NewWriter(w)
+	if err := writer.WriteAll(records); err != nil {
+		return err
+	}
+	return writer.Error()
+}
+
+// csvRecords converts a supported value into CSV records.
+func csvRecords(v any) ([][]string, error) {
+	switch value := v.(type) {
+	case CSVMarshaler:
+		return value.MarshalCSV()
+	case [][]string:
+		return value, nil
+	}
+
+	slice := reflect.Indirect(reflect.ValueOf(v))
+	if slice.Kind() != reflect.Slice && slice.Kind() != reflect.Array {
+		return nil, fmt.Errorf("csv: unsupported type %T", v)
+	}
+
+	elemType := slice.Type().Elem()
+	for elemType.Kind() == reflect.Pointer {
+		elemType = elemType.Elem()
+	}
+	if elemType.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("csv: unsupported element type %s", elemType)
+	}
+
+	var header []string
+	var indexes []int
+	for i := 0; i < elemType.NumField(); i++ {
+		field := elemType.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("csv"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		header = append(header, name)
+		indexes = append(indexes, i)
+	}
+
+	records := [][]string{header}
+	for i := 0; i < slice.Len(); i++ {
+		elem := reflect.Indirect(slice.Index(i))
+		record := make([]string, len(indexes))
+		if elem.IsValid() {
+			for j, index := range indexes {
+				record[j] = csvField(elem.Field(index))
+			}
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// csvField formats a single struct field as a CSV cell.
+func csvField(value reflect.Value) string {
+	if value.Kind() == reflect.Pointer && value.IsNil() {
+		return ""
+	}
+	if marshaler, ok := value.Interface().(encoding.TextMarshaler); ok {
+		text, err := marshaler.MarshalText()
+		if err == nil {
+			return string(text)
+		}
+	}
+	return fmt.Sprint(reflect.Indirect(value).Interface())
+}
@@ -0,0 +1,58 @@
+package goapi
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+type csvRow struct {
+	Name    string     `csv:"name"`
+	Secret  string     `csv:"-"`
+	Count   int        `csv:"count"`
+	Created *time.Time `csv:"created"`
+	hidden  string
+}
+
+func TestCSVCodec(t *testing.T) {
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	rows := []csvRow{
+		{Name: "a", Secret: "x", Count: 1, Created: &created, hidden: "y"},
+		{Name: "b,c", Count: 2},
+	}
+
+	var buf bytes.Buffer
+	if err := (CSVCodec{}).Encode(&buf, rows); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expected := "name,count,created\na,1,2024-01-02T03:04:05Z\n\"b,c\",2,\n"
+	if buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+}
+
+func TestCSVCodecUnsupported(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (CSVCodec{}).Encode(&buf, map[string]string{}); err == nil {
+		t.Errorf("expected an error for unsupported values")
+	}
+	if err := (CSVCodec{}).Encode(&buf, []int{1}); err == nil {
+		t.Errorf("expected an error for slices of non-structs")
+	}
+}
+
+func TestXMLCodec(t *testing.T) {
+	type item struct {
+		Name string `xml:"name"`
+	}
+
+	var buf bytes.Buffer
+	if err := (XMLCodec{}).Encode(&buf, item{Name: "a"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), "<?xml") || !strings.Contains(buf.String(), "<item><name>a</name></item>") {
+		t.Errorf("unexpected XML output %q", buf.String())
+	}
+}
@@ -0,0 +1,161 @@
+package goapi
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// SetETag sets the ETag response header to the tag formatted by FormatETag.
+// Call it before CheckPreconditions so that conditional requests are evaluated against it.
+//
+// Example:
+//
+//	goapi.SetETag(w, strconv.Itoa(order.Version)) // sent as "3"
+func SetETag(w http.ResponseWriter, etag string) {
+	if etag == "" {
+		return
+	}
+	w.Header().Set("ETag", FormatETag(etag))
+}
+
+// FormatETag returns etag as an entity tag: it is quoted when needed, and a "W/" prefix marks it as weak.
+func FormatETag(etag string) string {
+	weak := strings.HasPrefix(etag, "W/")
+	opaque := strings.TrimPrefix(etag, "W/")
+	if len(opaque) < 2 || !strings.HasPrefix(opaque, `"`) || !strings.HasSuffix(opaque, `"`) {
+		opaque = `"` + strings.ReplaceAll(opaque, `"`, "") + `"`
+	}
+	if weak {
+		return "W/" + opaque
+	}
+	return opaque
+}
+
+// SetLastModified sets the Last-Modified response header. The zero time is ignored.
+// Call it before CheckPreconditions so that conditional requests are evaluated against it.
+func SetLastModified(w http.ResponseWriter, modified time.Time) {
+	if modified.IsZero() || modified.Equal(time.Unix(0, 0)) {
+		return
+	}
+	w.Header().Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+}
+
+// CheckPreconditions evaluates the conditional headers of the request (If-Match, If-Unmodified-Since,
+// If-None-Match and If-Modified-Since) against the ETag and Last-Modified headers already set on the
+// response, typically with SetETag and SetLastModified. If a precondition fails it writes the response,
+// 304 Not Modified or 412 Precondition Failed, and returns false; the handler should then return without
+// making any change. It returns true when the request should proceed.
+//
+// This enables optimistic concurrency: clients send back the ETag they read in If-Match, and updates
+// based on a stale version are rejected.
+//
+// Example:
+//
+//	api.PUT("/orders/:id", func(w http.ResponseWriter, r *http.Request) {
+//		order := loadOrder(goapi.ParamsFromContext(r)["id"])
+//		goapi.SetETag(w, strconv.Itoa(order.Version))
+//		goapi.SetLastModified(w, order.UpdatedAt)
+//		if !goapi.CheckPreconditions(w, r) {
+//			return
+//		}
+//		// Apply the update.
+//	})
+func CheckPreconditions(w http.ResponseWriter, r *http.Request) bool {
+	var modified time.Time
+	if value := w.Header().Get("Last-Modified"); value != "" {
+		modified, _ = http.ParseTime(value)
+	}
+
+	switch EvaluatePreconditions(r, w.Header().Get("ETag"), modified) {
+	case http.StatusNotModified:
+		WriteNotModified(w)
+		return false
+	case http.StatusPreconditionFailed:
+		WriteError(w, r, NewHTTPError(http.StatusPreconditionFailed, ""))
+		return false
+	}
+	return true
+}
+
+// EvaluatePreconditions evaluates the conditional headers of the request against the current ETag and
+// modification time of the resource, following the order of RFC 9110, section 13.2.2. An empty etag means
+// the resource has no current representation, and a zero modified time that it is unknown.
+//
+// Returns:
+// - http.StatusPreconditionFailed (412) if If-Match or If-Unmodified-Since fails, or if If-None-Match
+// matches on a method other than GET and HEAD.
+// - http.StatusNotModified (304) if If-None-Match or If-Modified-Since shows that a GET or HEAD client
+// already has the current representation.
+// - 0 when the request should proceed.
+func EvaluatePreconditions(r *http.Request, etag string, modified time.Time) int {
+	modified = modified.Truncate(time.Second)
+	safe := r.Method == http.MethodGet || r.Method == http.MethodHead
+
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" {
+		if !etagListMatches(ifMatch, etag, true) {
+			return http.StatusPreconditionFailed
+		}
+	} else if since, err := http.ParseTime(r.Header.Get("If-Unmodified-Since")); err == nil && !modified.IsZero() {
+		if modified.After(since) {
+			return http.StatusPreconditionFailed
+		}
+	}
+
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		if etagListMatches(ifNoneMatch, etag, false) {
+			if safe {
+				return http.StatusNotModified
+			}
+			return http.StatusPreconditionFailed
+		}
+	} else if since, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err == nil && safe && !modified.IsZero() {
+		if !modified.After(since) {
+			return http.StatusNotModified
+		}
+	}
+
+	return 0
+}
+
+// WriteNotModified writes a 304 Not Modified response, removing the headers that describe a body.
+func WriteNotModified(w http.ResponseWriter) {
+	header := w.Header()
+	header.Del("Content-Type")
+	header.Del("Content-Length")
+	header.Del("Content-Encoding")
+	w.WriteHeader(http.StatusNotModified)
+}
+
+// etagListMatches reports whether an If-Match or If-None-Match header matches the current entity tag,
+// using the strong comparison for If-Match and the weak one for If-None-Match.
+func etagListMatches(list, etag string, strong bool) bool {
+	if etag == "" {
+		return false
+	}
+	if strings.TrimSpace(list) == "*" {
+		return true
+	}
+
+	current, currentWeak := parseETag(etag)
+	for _, candidate := range strings.Split(list, ",") {
+		opaque, weak := parseETag(strings.TrimSpace(candidate))
+		if opaque == "" || opaque != current {
+			continue
+		}
+		if !strong || (!weak && !currentWeak) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseETag splits an entity tag into its quoted opaque part and whether it is weak.
+func parseETag(etag string) (string, bool) {
+	weak := strings.HasPrefix(etag, "W/")
+	opaque := strings.TrimPrefix(etag, "W/")
+	if len(opaque) < 2 || opaque[0] != '"' || opaque[len(opaque)-1] != '"' {
+		return "", false
+	}
+	return opaque, weak
+}
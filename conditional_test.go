@@ -0,0 +1,120 @@
+package goapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestFormatETag(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{input: "3", expected: `"3"`},
+		{input: `"abc"`, expected: `"abc"`},
+		{input: `W/"abc"`, expected: `W/"abc"`},
+		{input: "W/abc", expected: `W/"abc"`},
+		{input: `a"b`, expected: `"ab"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := FormatETag(tt.input); got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestEvaluatePreconditions(t *testing.T) {
+	modified := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	before := modified.Add(-time.Hour).Format(http.TimeFormat)
+	after := modified.Add(time.Hour).Format(http.TimeFormat)
+
+	tests := []struct {
+		name     string
+		method   string
+		headers  map[string]string
+		etag     string
+		expected int
+	}{
+		{name: "no conditions", method: "GET", etag: `"v1"`, expected: 0},
+		{name: "if-none-match hit", method: "GET", headers: map[string]string{"If-None-Match": `"v0", "v1"`}, etag: `"v1"`, expected: http.StatusNotModified},
+		{name: "if-none-match weak hit", method: "GET", headers: map[string]string{"If-None-Match": `W/"v1"`}, etag: `"v1"`, expected: http.StatusNotModified},
+		{name: "if-none-match miss", method: "GET", headers: map[string]string{"If-None-Match": `"v0"`}, etag: `"v1"`, expected: 0},
+		{name: "if-none-match star on put", method: "PUT", headers: map[string]string{"If-None-Match": "*"}, etag: `"v1"`, expected: http.StatusPreconditionFailed},
+		{name: "if-none-match star on missing resource", method: "PUT", headers: map[string]string{"If-None-Match": "*"}, etag: "", expected: 0},
+		{name: "if-match hit", method: "PUT", headers: map[string]string{"If-Match": `"v1"`}, etag: `"v1"`, expected: 0},
+		{name: "if-match stale", method: "PUT", headers: map[string]string{"If-Match": `"v0"`}, etag: `"v1"`, expected: http.StatusPreconditionFailed},
+		{name: "if-match weak uses strong comparison", method: "PATCH", headers: map[string]string{"If-Match": `W/"v1"`}, etag: `W/"v1"`, expected: http.StatusPreconditionFailed},
+		{name: "if-match star", method: "DELETE", headers: map[string]string{"If-Match": "*"}, etag: `"v1"`, expected: 0},
+		{name: "if-match star on missing resource", method: "DELETE", headers: map[string]string{"If-Match": "*"}, etag: "", expected: http.StatusPreconditionFailed},
+		{name: "if-unmodified-since passes", method: "PUT", headers: map[string]string{"If-Unmodified-Since": after}, etag: `"v1"`, expected: 0},
+		{name: "if-unmodified-since fails", method: "PUT", headers: map[string]string{"If-Unmodified-Since": before}, etag: `"v1"`, expected: http.StatusPreconditionFailed},
+		{name: "if-match takes precedence", method: "PUT", headers: map[string]string{"If-Match": `"v1"`, "If-Unmodified-Since": before}, etag: `"v1"`, expected: 0},
+		{name: "if-modified-since not modified", method: "GET", headers: map[string]string{"If-Modified-Since": after}, etag: `"v1"`, expected: http.StatusNotModified},
+		{name: "if-modified-since modified", method: "GET", headers: map[string]string{"If-Modified-Since": before}, etag: `"v1"`, expected: 0},
+		{name: "if-none-match takes precedence", method: "GET", headers: map[string]string{"If-None-Match": `"v0"`, "If-Modified-Since": after}, etag: `"v1"`, expected: 0},
+		{name: "if-modified-since ignored for post", method: "POST", headers: map[string]string{"If-Modified-Since": after}, etag: `"v1"`, expected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/", nil)
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			if got := EvaluatePreconditions(req, tt.etag, modified); got != tt.expected {
+				t.Errorf("expected %d, got %d", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestCheckPreconditions(t *testing.T) {
+	version := "2"
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		SetETag(w, version)
+		SetLastModified(w, time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+		if !CheckPreconditions(w, r) {
+			return
+		}
+		w.Write([]byte("updated"))
+	}
+
+	tests := []struct {
+		name           string
+		method         string
+		header         string
+		value          string
+		expectedStatus int
+	}{
+		{name: "current version", method: "PUT", header: "If-Match", value: `"2"`, expectedStatus: http.StatusOK},
+		{name: "stale version", method: "PUT", header: "If-Match", value: `"1"`, expectedStatus: http.StatusPreconditionFailed},
+		{name: "cached copy", method: "GET", header: "If-None-Match", value: `"2"`, expectedStatus: http.StatusNotModified},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/", nil)
+			req.Header.Set(tt.header, tt.value)
+			rec := httptest.NewRecorder()
+			handler(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if rec.Header().Get("ETag") != `"2"` {
+				t.Errorf("expected ETag %q, got %q", `"2"`, rec.Header().Get("ETag"))
+			}
+			if rec.Header().Get("Last-Modified") != "Wed, 01 May 2024 12:00:00 GMT" {
+				t.Errorf("unexpected Last-Modified %q", rec.Header().Get("Last-Modified"))
+			}
+			if tt.expectedStatus == http.StatusNotModified && rec.Body.Len() != 0 {
+				t.Errorf("expected no body on 304, got %q", rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package goapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+var decodeOptionsKey = contextKey("decode_options")
+
+// DecodeOptions limits the shape of the JSON documents accepted by DecodeJSON.
+type DecodeOptions struct {
+	// MaxDepth is the maximum nesting depth of objects and arrays. Zero means unlimited.
+	MaxDepth int
+	// MaxFields is the maximum number of object keys in the whole document. Zero means unlimited.
+	MaxFields int
+	// AllowUnknownFields disables the rejection of object keys that do not match a field of the target struct.
+	AllowUnknownFields bool
+}
+
+// DefaultDecodeOptions are used by DecodeJSON when no group along the route configured its own.
+var DefaultDecodeOptions = DecodeOptions{
+	MaxDepth:  32,
+	MaxFields: 10000,
+}
+
+// MaxBodySize limits the size of request bodies for the routes of the current group and its subgroups.
+// Requests announcing a larger Content-Length are rejected with 413 Request Entity Too Large after the
+// middlewares ran but before the handler; other bodies are wrapped with http.MaxBytesReader so reading past
+// the limit fails.
+// A limit of zero or less removes the limit inherited from parent groups.
+//
+// Example:
+//
+//	api := goapi.NewRouter()
+//	api.MaxBodySize(1 << 20)
+//	uploads := api.Group("/uploads")
+//	uploads.MaxBodySize(100 << 20)
+func (g *Group) MaxBodySize(limit int64) {
+	g.maxBodySize = &limit
+}
+
+// DecodeOptions sets the JSON limits used by DecodeJSON and Bind for the routes of the current group and its subgroups.
+func (g *Group) DecodeOptions(options DecodeOptions) {
+	g.decodeOptions = &options
+}
+
+// lookupMaxBodySize returns the body size limit of the nearest group that configured one.
+func (g *Group) lookupMaxBodySize() int64 {
+	for group := g; group != nil; group = group.parent {
+		if group.maxBodySize != nil {
+			return *group.maxBodySize
+		}
+	}
+	return 0
+}
+
+// lookupDecodeOptions returns the decode options of the nearest group that configured them.
+func (g *Group) lookupDecodeOptions() *DecodeOptions {
+	for group := g; group != nil; group = group.parent {
+		if group.decodeOptions != nil {
+			return group.decodeOptions
+		}
+	}
+	return nil
+}
+
+// limitBody wraps the request body with http.MaxBytesReader when a body size limit applies, so that
+// middlewares and handlers reading past the limit get an *http.MaxBytesError.
+func limitBody(w http.ResponseWriter, r *http.Request, limit int64) {
+	if limit <= 0 || r.Body == nil || r.Body == http.NoBody {
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, limit)
+}
+
+// rejectLargeBody wraps the handler of a route so that requests announcing a Content-Length over the limit
+// are answered with 413 without running it. It runs inside the middleware chain, so the rejection still
+// gets CORS headers, a request ID and an access log entry.
+func rejectLargeBody(handler HandlerFunc, limit int64) HandlerFunc {
+	if limit <= 0 {
+		return handler
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > limit {
+			WriteError(w, r, bodyTooLargeError(&http.MaxBytesError{Limit: limit}))
+			return
+		}
+		handler(w, r)
+	}
+}
+
+// DecodeJSON decodes exactly one JSON value from the request body into v.
+//
+// The document must respect the DecodeOptions of the route's group, or DefaultDecodeOptions, and must
+// not be followed by anything other than whitespace. Unless AllowUnknownFields is set, keys that do not
+// match a field of the target struct are rejected.
+//
+// The returned error is an *HTTPError ready for WriteError: 413 Request Entity Too Large when the body
+// exceeds the group's MaxBodySize, 400 Bad Request for every other problem. An empty body is reported
+// as a 400 error wrapping io.EOF.
+//
+// Example:
+//
+//	var input CreateUser
+//	if err := goapi.DecodeJSON(r, &input); err != nil {
+//		goapi.WriteError(w, r, err)
+//		return
+//	}
+func DecodeJSON(r *http.Request, v any) error {
+	options := DefaultDecodeOptions
+	if configured, ok := r.Context().Value(decodeOptionsKey).(*DecodeOptions); ok {
+		options = *configured
+	}
+
+	if r.Body == nil {
+		return &HTTPError{Status: http.StatusBadRequest, Message: "request body is empty", Err: io.EOF}
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return bodyTooLargeError(maxBytesErr)
+		}
+		return &HTTPError{Status: http.StatusBadRequest, Message: "failed to read request body", Err: err}
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return &HTTPError{Status: http.StatusBadRequest, Message: "request body is empty", Err: io.EOF}
+	}
+
+	if err := checkJSONLimits(body, options); err != nil {
+		return &HTTPError{Status: http.StatusBadRequest, Message: err.Error(), Err: err}
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	if !options.AllowUnknownFields {
+		decoder.DisallowUnknownFields()
+	}
+	if err := decoder.Decode(v); err != nil {
+		return &HTTPError{Status: http.StatusBadRequest, Message: "malformed JSON body: " + err.Error(), Err: err}
+	}
+	if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+		return &HTTPError{Status: http.StatusBadRequest, Message: "request body must contain a single JSON value"}
+	}
+	return nil
+}
+
+// bodyTooLargeError builds the 413 error reported when a body exceeds its limit.
+func bodyTooLargeError(err *http.MaxBytesError) *HTTPError {
+	return &HTTPError{
+		Status:  http.StatusRequestEntityTooLarge,
+		Message: fmt.Sprintf("request body exceeds the limit of %d bytes", err.Limit),
+		Err:     err,
+	}
+}
+
+// checkJSONLimits scans a JSON document and verifies its nesting depth and total number of object keys.
+// It only tracks structure; syntax errors are left for the decoder to report.
+func checkJSONLimits(data []byte, options DecodeOptions) error {
+	if options.MaxDepth <= 0 && options.MaxFields <= 0 {
+		return nil
+	}
+
+	depth, fields := 0, 0
+	inString, escaped := false, false
+	for _, c := range data {
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+
+		switch c {
+		case '"':
+			inString = true
+		case '{', '[':
+			depth++
+			if options.MaxDepth > 0 && depth > options.MaxDepth {
+				return fmt.Errorf("JSON body exceeds the maximum depth of %d", options.MaxDepth)
+			}
+		case '}', ']':
+			depth--
+		case ':':
+			fields++
+			if options.MaxFields > 0 && fields > options.MaxFields {
+				return fmt.Errorf("JSON body exceeds the maximum of %d fields", options.MaxFields)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,204 @@
+package goapi
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type decodeTarget struct {
+	Name   string         `json:"name"`
+	Nested map[string]any `json:"nested"`
+}
+
+func TestDecodeJSON(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		options        *DecodeOptions
+		expectedStatus int
+	}{
+		{
+			name: "Valid body",
+			body: `{"name":"ana","nested":{"a":[1,2]}}`,
+		},
+		{
+			name:           "Unknown field",
+			body:           `{"name":"ana","admin":true}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:    "Unknown field allowed",
+			body:    `{"name":"ana","admin":true}`,
+			options: &DecodeOptions{AllowUnknownFields: true},
+		},
+		{
+			name:           "Trailing data",
+			body:           `{"name":"ana"} {"name":"bob"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Trailing whitespace",
+			body: "{\"name\":\"ana\"}\n\t ",
+		},
+		{
+			name:           "Too deep",
+			body:           `{"nested":{"a":{"b":{"c":1}}}}`,
+			options:        &DecodeOptions{MaxDepth: 3},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:    "Brackets inside strings do not count",
+			body:    `{"name":"{{{[[[:::"}`,
+			options: &DecodeOptions{MaxDepth: 1, MaxFields: 1},
+		},
+		{
+			name:           "Too many fields",
+			body:           `{"name":"ana","nested":{"a":1,"b":2}}`,
+			options:        &DecodeOptions{MaxFields: 3},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Malformed",
+			body:           `{"name":`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Empty",
+			body:           "  ",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := &Group{}
+			if test.options != nil {
+				root.DecodeOptions(*test.options)
+			}
+			var decodeErr error
+			root.POST("/decode", func(w http.ResponseWriter, r *http.Request) {
+				var target decodeTarget
+				decodeErr = DecodeJSON(r, &target)
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/decode", strings.NewReader(test.body))
+			root.handleRequest(httptest.NewRecorder(), req)
+
+			if test.expectedStatus == 0 {
+				if decodeErr != nil {
+					t.Errorf("expected no error, got %v", decodeErr)
+				}
+				return
+			}
+			if decodeErr == nil {
+				t.Fatalf("expected an error")
+			}
+			if status := toHTTPError(decodeErr).Status; status != test.expectedStatus {
+				t.Errorf("expected status %d, got %d", test.expectedStatus, status)
+			}
+		})
+	}
+}
+
+func TestDecodeJSONEmptyBodyWrapsEOF(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(""))
+	if err := DecodeJSON(req, &decodeTarget{}); !errors.Is(err, io.EOF) {
+		t.Errorf("expected error wrapping io.EOF, got %v", err)
+	}
+}
+
+func TestGroupMaxBodySize(t *testing.T) {
+	root := &Group{}
+	root.MaxBodySize(16)
+	root.Use(func(next HandlerFunc) HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Middleware", "ran")
+			next(w, r)
+		}
+	})
+	var decodeErr error
+	root.POST("/small", func(w http.ResponseWriter, r *http.Request) {
+		var target decodeTarget
+		decodeErr = DecodeJSON(r, &target)
+		if decodeErr != nil {
+			WriteError(w, r, decodeErr)
+		}
+	})
+
+	large := root.Group("/large")
+	large.MaxBodySize(1024)
+	large.POST("/upload", func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			WriteError(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	unlimited := root.Group("/unlimited")
+	unlimited.MaxBodySize(0)
+	unlimited.POST("/upload", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	body := `{"name":"a name longer than the limit"}`
+
+	t.Run("Content-Length over the limit is rejected early", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/small", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		decodeErr = nil
+
+		root.handleRequest(rec, req)
+
+		if rec.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("expected status %d, got %d", http.StatusRequestEntityTooLarge, rec.Code)
+		}
+		if decodeErr != nil {
+			t.Errorf("expected the handler not to run")
+		}
+		if rec.Header().Get("X-Middleware") != "ran" {
+			t.Errorf("expected the middlewares to run around the rejection")
+		}
+	})
+
+	t.Run("Unknown length is limited while reading", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/small", strings.NewReader(body))
+		req.ContentLength = -1
+		rec := httptest.NewRecorder()
+
+		root.handleRequest(rec, req)
+
+		if rec.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("expected status %d, got %d", http.StatusRequestEntityTooLarge, rec.Code)
+		}
+		if toHTTPError(decodeErr).Status != http.StatusRequestEntityTooLarge {
+			t.Errorf("expected a 413 error from DecodeJSON, got %v", decodeErr)
+		}
+	})
+
+	t.Run("Subgroup raises the limit", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/large/upload", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+
+		root.handleRequest(rec, req)
+
+		if rec.Code != http.StatusNoContent {
+			t.Errorf("expected status %d, got %d", http.StatusNoContent, rec.Code)
+		}
+	})
+
+	t.Run("Subgroup removes the limit", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/unlimited/upload", strings.NewReader(strings.Repeat("a", 4096)))
+		rec := httptest.NewRecorder()
+
+		root.handleRequest(rec, req)
+
+		if rec.Code != http.StatusNoContent {
+			t.Errorf("expected status %d, got %d", http.StatusNoContent, rec.Code)
+		}
+	})
+}
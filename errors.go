@@ -0,0 +1,126 @@
+package goapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+var errorHandlerKey = contextKey("error_handler")
+
+// ErrorHandlerFunc writes the response for an error raised while handling a request.
+type ErrorHandlerFunc func(w http.ResponseWriter, r *http.Request, err error)
+
+// HTTPError is an error that carries the HTTP status code it should be reported with.
+// Handlers and middlewares return or write it through WriteError so that every error
+// response produced by the package shares the same JSON format.
+type HTTPError struct {
+	Status  int
+	Message string
+	Details any
+	Err     error
+}
+
+// NewHTTPError creates an HTTPError with the given status code and message.
+// If message is empty, the standard status text is used.
+func NewHTTPError(status int, message string) *HTTPError {
+	if message == "" {
+		message = http.StatusText(status)
+	}
+	return &HTTPError{Status: status, Message: message}
+}
+
+func (e *HTTPError) Error() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	if e.Err != nil {
+		return e.Err.Error()
+	}
+	return http.StatusText(e.Status)
+}
+
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+// errorResponse is the body written by DefaultErrorHandler.
+type errorResponse struct {
+	Status  int    `json:"status"`
+	Error   string `json:"error"`
+	Message string `json:"message,omitempty"`
+	Details any    `json:"details,omitempty"`
+}
+
+// WriteError writes the response for err using the error handler configured on the router with
+// Router.ErrorHandler, or DefaultErrorHandler when none was configured. Handlers and middlewares should
+// report errors through it so that every error response of the application has the same format.
+//
+// Parameters:
+// - w: The http.ResponseWriter to write the response.
+// - r: The *http.Request being answered.
+// - err: The error to report.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	if r != nil {
+		if handler, ok := r.Context().Value(errorHandlerKey).(ErrorHandlerFunc); ok {
+			handler(w, r, err)
+			return
+		}
+	}
+	DefaultErrorHandler(w, r, err)
+}
+
+// DefaultErrorHandler writes err as a JSON error response.
+//
+// The status code is taken from an *HTTPError found in the error chain. ValidationErrors are
+// reported as 422 Unprocessable Entity with the per-field errors as details. Any other error
+// is reported as 500 Internal Server Error without exposing its message to the client.
+//
+// Parameters:
+// - w: The http.ResponseWriter to write the response.
+// - r: The *http.Request being answered. HEAD requests receive headers only.
+// - err: The error to report.
+func DefaultErrorHandler(w http.ResponseWriter, r *http.Request, err error) {
+	httpErr := toHTTPError(err)
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(httpErr.Status)
+	if r != nil && r.Method == http.MethodHead {
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(errorResponse{
+		Status:  httpErr.Status,
+		Error:   http.StatusText(httpErr.Status),
+		Message: httpErr.Message,
+		Details: httpErr.Details,
+	})
+}
+
+// toHTTPError converts any error into the *HTTPError used to render it.
+func toHTTPError(err error) *HTTPError {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		if httpErr.Status == 0 {
+			httpErr.Status = http.StatusInternalServerError
+		}
+		return httpErr
+	}
+
+	var validationErrs ValidationErrors
+	if errors.As(err, &validationErrs) {
+		return &HTTPError{
+			Status:  http.StatusUnprocessableEntity,
+			Message: "validation failed",
+			Details: []FieldError(validationErrs),
+			Err:     err,
+		}
+	}
+
+	return &HTTPError{
+		Status:  http.StatusInternalServerError,
+		Message: http.StatusText(http.StatusInternalServerError),
+		Err:     err,
+	}
+}
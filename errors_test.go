@@ -0,0 +1,89 @@
+package goapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWriteError(t *testing.T) {
+	tests := []struct {
+		name            string
+		err             error
+		expectedStatus  int
+		expectedMessage string
+		expectDetails   bool
+	}{
+		{
+			name:            "HTTPError",
+			err:             NewHTTPError(http.StatusBadRequest, "bad input"),
+			expectedStatus:  http.StatusBadRequest,
+			expectedMessage: "bad input",
+		},
+		{
+			name:            "Wrapped HTTPError",
+			err:             errors.Join(errors.New("context"), NewHTTPError(http.StatusConflict, "")),
+			expectedStatus:  http.StatusConflict,
+			expectedMessage: "Conflict",
+		},
+		{
+			name:            "ValidationErrors",
+			err:             ValidationErrors{{Field: "name", Rule: "required", Message: "name is required"}},
+			expectedStatus:  http.StatusUnprocessableEntity,
+			expectedMessage: "validation failed",
+			expectDetails:   true,
+		},
+		{
+			name:            "Plain error is hidden",
+			err:             errors.New("database password is hunter2"),
+			expectedStatus:  http.StatusInternalServerError,
+			expectedMessage: "Internal Server Error",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			rec := httptest.NewRecorder()
+
+			WriteError(rec, req, test.err)
+
+			if rec.Code != test.expectedStatus {
+				t.Errorf("expected status %d, got %d", test.expectedStatus, rec.Code)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "application/json; charset=utf-8" {
+				t.Errorf("expected JSON content type, got %q", ct)
+			}
+
+			var body errorResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("expected JSON body, got %q", rec.Body.String())
+			}
+			if body.Status != test.expectedStatus {
+				t.Errorf("expected body status %d, got %d", test.expectedStatus, body.Status)
+			}
+			if body.Message != test.expectedMessage {
+				t.Errorf("expected message %q, got %q", test.expectedMessage, body.Message)
+			}
+			if test.expectDetails && body.Details == nil {
+				t.Errorf("expected details in body")
+			}
+		})
+	}
+}
+
+func TestWriteErrorHead(t *testing.T) {
+	req := httptest.NewRequest(http.MethodHead, "/", nil)
+	rec := httptest.NewRecorder()
+
+	WriteError(rec, req, NewHTTPError(http.StatusNotFound, ""))
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected status %d, got %d", http.StatusNotFound, rec.Code)
+	}
+	if rec.Body.Len() != 0 {
+		t.Errorf("expected empty body for HEAD request, got %q", rec.Body.String())
+	}
+}
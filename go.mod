@@ -1,3 +1,5 @@
 module github.com/carlosealves2/go-api
 
-go 1.23
+go 1.23.0
+
+require golang.org/x/crypto v0.41.0
@@ -3,12 +3,17 @@ package goapi
 import (
 	"context"
 	"net/http"
+	"sort"
 	"strings"
 )
 
 type contextKey string
 
-var paramsKey = contextKey("route_params")
+var (
+	paramsKey         = contextKey("route_params")
+	routeKey          = contextKey("route")
+	allowedMethodsKey = contextKey("allowed_methods")
+)
 
 type Group struct {
 	prefix     string
@@ -16,6 +21,10 @@ type Group struct {
 	routes     []route
 	subgroups  []*Group
 	parent     *Group
+	codecs     *CodecRegistry
+
+	maxBodySize   *int64
+	decodeOptions *DecodeOptions
 }
 
 // Group creates a new subgroup with the specified prefix and adds it to the current group.
@@ -64,6 +73,8 @@ func (g *Group) Use(middleware ...MiddlewareFunc) {
 // pattern: The pattern for the route, which may contain dynamic parameters enclosed in curly braces (e.g., "/users/{id}").
 // handler: The handler function to be executed when the route is matched.
 //
+// Returns: The registered *Route, which can be configured further (e.g., named with Route.Name).
+//
 // Example:
 //
 //	api := goapi.New()
@@ -73,15 +84,17 @@ func (g *Group) Use(middleware ...MiddlewareFunc) {
 //		userID := params["id"]
 //		// ...
 //	})
-func (g *Group) Handle(method, pattern string, handler HandlerFunc) {
+func (g *Group) Handle(method, pattern string, handler HandlerFunc) *Route {
 	fullPattern := strings.TrimRight(g.prefix, "/") + "/" + strings.TrimLeft(pattern, "/")
 	regexPattern, paramNames := parsePattern(fullPattern)
 	g.routes = append(g.routes, route{
 		method:     method,
+		path:       fullPattern,
 		pattern:    regexPattern,
 		paramNames: paramNames,
 		handler:    handler,
 	})
+	return &Route{group: g, index: len(g.routes) - 1}
 }
 
 // GET is a shortcut method for adding a new route with the HTTP method "GET" to the current group.
@@ -100,8 +113,10 @@ func (g *Group) Handle(method, pattern string, handler HandlerFunc) {
 //		userID := params["id"]
 //		// ...
 //	})
-func (g *Group) GET(pattern string, handler HandlerFunc) {
-	g.Handle("GET", pattern, handler)
+//
+// Returns: The registered *Route, which can be configured further (e.g., named).
+func (g *Group) GET(pattern string, handler HandlerFunc) *Route {
+	return g.Handle("GET", pattern, handler)
 }
 
 // POST adds a new route with the HTTP method "POST" to the current group.
@@ -113,9 +128,9 @@ func (g *Group) GET(pattern string, handler HandlerFunc) {
 // - handler: The handler function to be executed when the route is matched.
 //
 // Returns:
-// This function does not return any value. It adds a new route to the current group.
-func (g *Group) POST(pattern string, handler HandlerFunc) {
-	g.Handle("POST", pattern, handler)
+// - *Route: The registered route, which can be configured further (e.g., named).
+func (g *Group) POST(pattern string, handler HandlerFunc) *Route {
+	return g.Handle("POST", pattern, handler)
 }
 
 // PUT adds a new route with the HTTP method "PUT" to the current group.
@@ -127,9 +142,9 @@ func (g *Group) POST(pattern string, handler HandlerFunc) {
 // - handler: The handler function to be executed when the route is matched.
 //
 // Returns:
-// This function does not return any value. It adds a new route to the current group.
-func (g *Group) PUT(pattern string, handler HandlerFunc) {
-	g.Handle("PUT", pattern, handler)
+// - *Route: The registered route, which can be configured further (e.g., named).
+func (g *Group) PUT(pattern string, handler HandlerFunc) *Route {
+	return g.Handle("PUT", pattern, handler)
 }
 
 // DELETE adds a new route with the HTTP method "DELETE" to the current group.
@@ -141,9 +156,9 @@ func (g *Group) PUT(pattern string, handler HandlerFunc) {
 // - handler: The handler function to be executed when the route is matched.
 //
 // Returns:
-// This function does not return any value. It adds a new route to the current group.
-func (g *Group) DELETE(pattern string, handler HandlerFunc) {
-	g.Handle("DELETE", pattern, handler)
+// - *Route: The registered route, which can be configured further (e.g., named).
+func (g *Group) DELETE(pattern string, handler HandlerFunc) *Route {
+	return g.Handle("DELETE", pattern, handler)
 }
 
 // PATCH adds a new route with the HTTP method "PATCH" to the current group.
@@ -155,9 +170,9 @@ func (g *Group) DELETE(pattern string, handler HandlerFunc) {
 // - handler: The handler function to be executed when the route is matched.
 //
 // Returns:
-// This function does not return any value. It adds a new route to the current group.
-func (g *Group) PATCH(pattern string, handler HandlerFunc) {
-	g.Handle("PATCH", pattern, handler)
+// - *Route: The registered route, which can be configured further (e.g., named).
+func (g *Group) PATCH(pattern string, handler HandlerFunc) *Route {
+	return g.Handle("PATCH", pattern, handler)
 }
 
 // HEAD adds a new route with the HTTP method "HEAD" to the current group.
@@ -169,9 +184,9 @@ func (g *Group) PATCH(pattern string, handler HandlerFunc) {
 // - handler: The handler function to be executed when the route is matched.
 //
 // Returns:
-// This function does not return any value. It adds a new route to the current group.
-func (g *Group) HEAD(pattern string, handler HandlerFunc) {
-	g.Handle("HEAD", pattern, handler)
+// - *Route: The registered route, which can be configured further (e.g., named).
+func (g *Group) HEAD(pattern string, handler HandlerFunc) *Route {
+	return g.Handle("HEAD", pattern, handler)
 }
 
 // OPTIONS adds a new route with the HTTP method "OPTIONS" to the current group.
@@ -183,16 +198,17 @@ func (g *Group) HEAD(pattern string, handler HandlerFunc) {
 // - handler: The handler function to be executed when the route is matched.
 //
 // Returns:
-// This function does not return any value. It adds a new route to the current group.
-func (g *Group) OPTIONS(pattern string, handler HandlerFunc) {
-	g.Handle("OPTIONS", pattern, handler)
+// - *Route: The registered route, which can be configured further (e.g., named).
+func (g *Group) OPTIONS(pattern string, handler HandlerFunc) *Route {
+	return g.Handle("OPTIONS", pattern, handler)
 }
 
 // handleRequest processes incoming HTTP requests and matches them to the appropriate route within the group.
 // It iterates through the routes and checks if the request method and URL path match. If a match is found,
 // it extracts any dynamic parameters from the URL path and adds them to the request context. It then applies
 // the middleware functions associated with the group and its parent groups, and finally executes the handler
-// function for the matched route. If no match is found, it returns a 404 Not Found response.
+// function for the matched route. OPTIONS requests for paths without an OPTIONS route are answered
+// automatically by dispatchOptions. If no match is found, it returns a 404 Not Found response.
 //
 // Parameters:
 // - w: The http.ResponseWriter to write the response.
@@ -202,6 +218,22 @@ func (g *Group) OPTIONS(pattern string, handler HandlerFunc) {
 //   - A boolean indicating whether a route was matched and processed. If true, the request was handled; otherwise,
 //     a 404 Not Found response was sent
 func (g *Group) handleRequest(w http.ResponseWriter, r *http.Request) bool {
+	if g.dispatch(w, r) {
+		return true
+	}
+	if r.Method == http.MethodOptions && g.dispatchOptions(w, r) {
+		return true
+	}
+	w.WriteHeader(http.StatusNotFound)
+	return false
+}
+
+// dispatch looks for a route matching the request in the group and then in its subgroups, and executes it.
+// Unlike handleRequest it writes nothing when no route matches, so sibling subgroups can still be tried.
+//
+// Returns:
+// - A boolean indicating whether a route was matched and processed.
+func (g *Group) dispatch(w http.ResponseWriter, r *http.Request) bool {
 	for _, requestedRoute := range g.routes {
 		if r.Method != requestedRoute.method {
 			continue
@@ -209,35 +241,133 @@ func (g *Group) handleRequest(w http.ResponseWriter, r *http.Request) bool {
 
 		matches := requestedRoute.pattern.FindStringSubmatch(r.URL.Path)
 		if len(matches) > 0 {
-			params := make(map[string]string)
-			for i, name := range requestedRoute.paramNames {
-				params[name] = matches[i+1]
-			}
-
-			ctx := context.WithValue(r.Context(), paramsKey, params)
-			r = r.WithContext(ctx)
-
-			middlewares := g.collectMiddlewares()
-
-			finalHandler := requestedRoute.handler
-			for i := len(middlewares) - 1; i >= 0; i-- {
-				finalHandler = middlewares[i](finalHandler)
-			}
-
-			finalHandler(w, r)
+			g.serve(w, r, requestedRoute, matches)
 			return true
 		}
 	}
 
 	for _, subgroup := range g.subgroups {
-		if subgroup.handleRequest(w, r) {
+		if subgroup.dispatch(w, r) {
 			return true
 		}
 	}
-	w.WriteHeader(http.StatusNotFound)
 	return false
 }
 
+// dispatchOptions answers an OPTIONS request for a path that has routes but no OPTIONS route of its own.
+// The request goes through the middlewares of the group owning the route for the method named by the
+// Access-Control-Request-Method header, or else the first matching route, so middlewares such as CORS can
+// answer preflight requests with the configuration of the route the browser is about to call. It ends in
+// a 204 No Content response whose Allow header lists the methods registered for the path. The same
+// methods are available to the middlewares through AllowedMethodsFromContext.
+//
+// Returns:
+// - A boolean indicating whether any route matched the path.
+func (g *Group) dispatchOptions(w http.ResponseWriter, r *http.Request) bool {
+	var (
+		owner   *Group
+		matched route
+		matches []string
+	)
+	requested := r.Header.Get("Access-Control-Request-Method")
+	ownerFound := false
+	methods := map[string]bool{http.MethodOptions: true}
+	g.walkRoutes(func(group *Group, rt route) {
+		submatches := rt.pattern.FindStringSubmatch(r.URL.Path)
+		if len(submatches) == 0 {
+			return
+		}
+		if owner == nil || (!ownerFound && rt.method == requested) {
+			owner, matched, matches = group, rt, submatches
+			ownerFound = rt.method == requested
+		}
+		methods[rt.method] = true
+	})
+	if owner == nil {
+		return false
+	}
+
+	allowed := make([]string, 0, len(methods))
+	for method := range methods {
+		allowed = append(allowed, method)
+	}
+	sort.Strings(allowed)
+
+	// The preflight answer is not the route itself, so its requirements do not apply.
+	matched.method = http.MethodOptions
+	matched.requirements = nil
+	matched.handler = func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		w.WriteHeader(http.StatusNoContent)
+	}
+	r = r.WithContext(context.WithValue(r.Context(), allowedMethodsKey, allowed))
+	owner.serve(w, r, matched, matches)
+	return true
+}
+
+// serve executes a matched route: it stores the route parameters and the group settings in the request
+// context, applies the body size limit, and runs the handler behind the middlewares of the group and its parents.
+func (g *Group) serve(w http.ResponseWriter, r *http.Request, requestedRoute route, matches []string) {
+	params := make(map[string]string)
+	for i, name := range requestedRoute.paramNames {
+		params[name] = matches[i+1]
+	}
+
+	ctx := context.WithValue(r.Context(), paramsKey, params)
+	ctx = context.WithValue(ctx, routeKey, requestedRoute.info())
+	if stateFromContext(r) == nil {
+		ctx = context.WithValue(ctx, requestStateKey, &requestState{})
+	}
+	if codecs := g.lookupCodecs(); codecs != nil {
+		ctx = context.WithValue(ctx, codecsKey, codecs)
+	}
+	if options := g.lookupDecodeOptions(); options != nil {
+		ctx = context.WithValue(ctx, decodeOptionsKey, options)
+	}
+	r = r.WithContext(ctx)
+
+	limit := g.lookupMaxBodySize()
+	limitBody(w, r, limit)
+
+	middlewares := g.collectMiddlewares()
+
+	finalHandler := rejectLargeBody(requestedRoute.handler, limit)
+	if len(requestedRoute.requirements) > 0 {
+		finalHandler = requireAuthorization(finalHandler)
+	}
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		finalHandler = middlewares[i](finalHandler)
+	}
+
+	finalHandler(w, r)
+}
+
+// Routes lists the routes of the group and its subgroups, in the order they are matched. Called on the
+// router, it describes the whole API, for example to audit the permissions required by each route.
+//
+// Example:
+//
+//	for _, route := range router.Routes() {
+//		fmt.Printf("%-6s %-30s %v\n", route.Method, route.Pattern, route.Requirements)
+//	}
+func (g *Group) Routes() []RouteInfo {
+	var routes []RouteInfo
+	g.walkRoutes(func(group *Group, rt route) {
+		routes = append(routes, rt.info())
+	})
+	return routes
+}
+
+// walkRoutes calls fn for every route of the group and its subgroups, in the order they are matched.
+func (g *Group) walkRoutes(fn func(group *Group, rt route)) {
+	for _, rt := range g.routes {
+		fn(g, rt)
+	}
+	for _, subgroup := range g.subgroups {
+		subgroup.walkRoutes(fn)
+	}
+}
+
 // collectMiddlewares recursively collects all middleware functions associated with the current group
 // and its parent groups. The middleware functions are returned in the order they were added.
 //
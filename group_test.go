@@ -3,6 +3,7 @@ package goapi
 import (
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -302,3 +303,92 @@ func TestGroupHandleRequestNoMatch(t *testing.T) {
 		}
 	})
 }
+
+func TestGroupSiblingSubgroups(t *testing.T) {
+	root := &Group{}
+	users := root.Group("/users")
+	users.GET("/list", mockHandler("Users"))
+	orders := root.Group("/orders")
+	orders.GET("/list", mockHandler("Orders"))
+
+	t.Run("GET /orders/list", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/orders/list", nil)
+		resp := httptest.NewRecorder()
+
+		if !root.handleRequest(resp, req) {
+			t.Fatalf("Expected /orders/list to be handled")
+		}
+
+		if resp.Code != http.StatusOK {
+			t.Errorf("Expected status 200, got %d", resp.Code)
+		}
+
+		if resp.Body.String() != "Orders" {
+			t.Errorf("Expected body 'Orders', got '%s'", resp.Body.String())
+		}
+	})
+}
+
+func TestGroupAutomaticOPTIONS(t *testing.T) {
+	root := &Group{}
+	root.GET("/items/:id", mockHandler("Item"))
+	items := root.Group("/items")
+	items.DELETE("/:id", mockHandler("Deleted"))
+	items.PUT("/:id", mockHandler("Updated"))
+
+	t.Run("OPTIONS /items/7", func(t *testing.T) {
+		req := httptest.NewRequest("OPTIONS", "/items/7", nil)
+		resp := httptest.NewRecorder()
+
+		if !root.handleRequest(resp, req) {
+			t.Fatalf("Expected /items/7 to be handled")
+		}
+
+		if resp.Code != http.StatusNoContent {
+			t.Errorf("Expected status 204, got %d", resp.Code)
+		}
+
+		if allow := resp.Header().Get("Allow"); allow != "DELETE, GET, OPTIONS, PUT" {
+			t.Errorf("Expected Allow 'DELETE, GET, OPTIONS, PUT', got '%s'", allow)
+		}
+	})
+
+	t.Run("OPTIONS /unknown", func(t *testing.T) {
+		req := httptest.NewRequest("OPTIONS", "/unknown", nil)
+		resp := httptest.NewRecorder()
+
+		if root.handleRequest(resp, req) {
+			t.Fatalf("Expected /unknown not to be handled")
+		}
+
+		if resp.Code != http.StatusNotFound {
+			t.Errorf("Expected status 404, got %d", resp.Code)
+		}
+	})
+}
+
+func TestGroupAutomaticOPTIONSMiddleware(t *testing.T) {
+	root := &Group{}
+	users := root.Group("/users")
+	var allowed []string
+	users.Use(mockMiddleware, func(next HandlerFunc) HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			allowed = AllowedMethodsFromContext(r)
+			next(w, r)
+		}
+	})
+	users.GET("/:id", mockHandler("User"))
+	users.PATCH("/:id", mockHandler("Patched"))
+
+	req := httptest.NewRequest("OPTIONS", "/users/7", nil)
+	resp := httptest.NewRecorder()
+	root.handleRequest(resp, req)
+
+	if resp.Header().Get("X-Middleware") != "true" {
+		t.Errorf("Expected the group middleware to run for the OPTIONS request")
+	}
+
+	if strings.Join(allowed, ",") != "GET,OPTIONS,PATCH" {
+		t.Errorf("Expected allowed methods GET,OPTIONS,PATCH in the context, got %v", allowed)
+	}
+}
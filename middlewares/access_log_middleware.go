@@ -0,0 +1,254 @@
+package middlewares
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	goapi "github.com/carlosealves2/go-api"
+)
+
+// AccessLogFormat selects how AccessLog writes its entries.
+type AccessLogFormat int
+
+const (
+	// AccessLogSlog sends each entry to a slog.Handler as structured attributes.
+	AccessLogSlog AccessLogFormat = iota
+	// AccessLogCombined writes entries in the Apache Combined Log Format.
+	AccessLogCombined
+	// AccessLogJSON writes one JSON object per line.
+	AccessLogJSON
+)
+
+// AccessLogConfig configures the AccessLog middleware.
+type AccessLogConfig struct {
+	// Format selects the output format. Defaults to AccessLogSlog.
+	Format AccessLogFormat
+	// Handler receives the entries in the AccessLogSlog format. Defaults to slog.Default().Handler().
+	Handler slog.Handler
+	// Output receives the entries in the AccessLogCombined and AccessLogJSON formats. Defaults to os.Stdout.
+	Output io.Writer
+	// SampleRate is the fraction of requests logged, between 0 and 1. Server errors (5xx) are always
+	// logged. Zero means every request is logged.
+	SampleRate float64
+	// ExcludePaths lists request paths or route patterns that are never logged, such as health checks.
+	ExcludePaths []string
+}
+
+// AccessLogEntry is the information recorded for each request.
+type AccessLogEntry struct {
+	Time       time.Time     `json:"time"`
+	Method     string        `json:"method"`
+	Path       string        `json:"path"`
+	URI        string        `json:"uri"`
+	Route      string        `json:"route,omitempty"`
+	Proto      string        `json:"proto"`
+	Status     int           `json:"status"`
+	Size       int64         `json:"size"`
+	Duration   time.Duration `json:"duration_ns"`
+	RequestID  string        `json:"request_id,omitempty"`
+	RemoteIP   string        `json:"remote_ip"`
+	UserAgent  string        `json:"user_agent,omitempty"`
+	Referer    string        `json:"referer,omitempty"`
+	RemoteUser string        `json:"remote_user,omitempty"`
+}
+
+// AccessLog returns a middleware that logs every request after its response has been written, including
+// the status, duration, response size, matched route pattern, request ID, client IP and user agent.
+// Requests whose handler panics are logged too, with a 500 status unless a response was already started.
+//
+// Example:
+//
+//	api := goapi.NewRouter()
+//	api.Use(middlewares.AccessLog(middlewares.AccessLogConfig{
+//		Format:       middlewares.AccessLogJSON,
+//		SampleRate:   0.1,
+//		ExcludePaths: []string{"/healthz"},
+//	}))
+func AccessLog(config AccessLogConfig) goapi.MiddlewareFunc {
+	excluded := make(map[string]bool, len(config.ExcludePaths))
+	for _, path := range config.ExcludePaths {
+		excluded[path] = true
+	}
+
+	write := accessLogWriter(config)
+
+	return func(next goapi.HandlerFunc) goapi.HandlerFunc {
+		return func(w http.ResponseWriter, req *http.Request) {
+			start := time.Now()
+			ww := goapi.WrapResponseWriter(w)
+
+			// The entry is written while a panic unwinds, without recovering it, so that the
+			// Recovery middleware or the server still see it.
+			completed := false
+			defer func() {
+				entry := newAccessLogEntry(req, ww, start, !completed)
+				if excluded[entry.Path] || (entry.Route != "" && excluded[entry.Route]) {
+					return
+				}
+				if config.SampleRate > 0 && config.SampleRate < 1 && entry.Status < 500 && rand.Float64() >= config.SampleRate {
+					return
+				}
+				write(req.Context(), entry)
+			}()
+
+			next(ww, req)
+			completed = true
+		}
+	}
+}
+
+// newAccessLogEntry collects the details of a completed request. panicked reports whether the handler
+// panicked, in which case a request without response is recorded as 500 Internal Server Error.
+func newAccessLogEntry(req *http.Request, ww goapi.ResponseWriter, start time.Time, panicked bool) AccessLogEntry {
+	status := ww.Status()
+	switch {
+	case status != 0:
+	case panicked:
+		status = http.StatusInternalServerError
+	default:
+		status = http.StatusOK
+	}
+	uri := req.RequestURI
+	if uri == "" {
+		uri = req.URL.RequestURI()
+	}
+
+	entry := AccessLogEntry{
+		Time:      start,
+		Method:    req.Method,
+		Path:      req.URL.Path,
+		URI:       uri,
+		Proto:     req.Proto,
+		Status:    status,
+		Size:      ww.BytesWritten(),
+		Duration:  time.Since(start),
+		RequestID: goapi.RequestIDFromContext(req),
+		RemoteIP:  goapi.ClientIPFromContext(req),
+		UserAgent: req.UserAgent(),
+		Referer:   req.Referer(),
+	}
+	if route, ok := goapi.RouteFromContext(req); ok {
+		entry.Route = route.Pattern
+	}
+	// The principal authenticated by a middleware is preferred over the unverified Basic auth user name.
+	if principal, ok := goapi.PrincipalFromContext(req); ok {
+		entry.RemoteUser = principal.ID
+	} else if user, _, ok := req.BasicAuth(); ok {
+		entry.RemoteUser = user
+	}
+	return entry
+}
+
+// accessLogWriter returns the function that outputs entries in the configured format.
+func accessLogWriter(config AccessLogConfig) func(ctx context.Context, entry AccessLogEntry) {
+	if config.Format == AccessLogSlog {
+		handler := config.Handler
+		if handler == nil {
+			handler = slog.Default().Handler()
+		}
+		logger := slog.New(handler)
+		return func(ctx context.Context, entry AccessLogEntry) {
+			logger.LogAttrs(ctx, accessLogLevel(entry.Status), "request",
+				slog.String("method", entry.Method),
+				slog.String("path", entry.Path),
+				slog.String("route", entry.Route),
+				slog.Int("status", entry.Status),
+				slog.Int64("size", entry.Size),
+				slog.Duration("duration", entry.Duration),
+				slog.String("request_id", entry.RequestID),
+				slog.String("remote_ip", entry.RemoteIP),
+				slog.String("user_agent", entry.UserAgent),
+			)
+		}
+	}
+
+	output := config.Output
+	if output == nil {
+		output = os.Stdout
+	}
+	var mu sync.Mutex
+	format := formatCombined
+	if config.Format == AccessLogJSON {
+		format = formatJSON
+	}
+	return func(ctx context.Context, entry AccessLogEntry) {
+		line := format(entry)
+		mu.Lock()
+		defer mu.Unlock()
+		_, _ = io.WriteString(output, line)
+	}
+}
+
+// accessLogLevel maps a status code to a log level.
+func accessLogLevel(status int) slog.Level {
+	switch {
+	case status >= 500:
+		return slog.LevelError
+	case status >= 400:
+		return slog.LevelWarn
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// formatCombined renders an entry in the Apache Combined Log Format.
+func formatCombined(entry AccessLogEntry) string {
+	size := "-"
+	if entry.Size > 0 {
+		size = fmt.Sprint(entry.Size)
+	}
+	return fmt.Sprintf("%s - %s [%s] \"%s %s %s\" %d %s \"%s\" \"%s\"\n",
+		entry.RemoteIP,
+		escapeCombined(dashIfEmpty(entry.RemoteUser)),
+		entry.Time.Format("02/Jan/2006:15:04:05 -0700"),
+		entry.Method,
+		escapeCombined(entry.URI),
+		entry.Proto,
+		entry.Status,
+		size,
+		escapeCombined(dashIfEmpty(entry.Referer)),
+		escapeCombined(dashIfEmpty(entry.UserAgent)),
+	)
+}
+
+// formatJSON renders an entry as a JSON line.
+func formatJSON(entry AccessLogEntry) string {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return ""
+	}
+	return string(data) + "\n"
+}
+
+func dashIfEmpty(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+// escapeCombined escapes quotes and control characters so a value cannot break the log line.
+func escapeCombined(value string) string {
+	var b strings.Builder
+	for _, r := range value {
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&b, "\\x%02x", r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
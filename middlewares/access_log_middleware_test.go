@@ -0,0 +1,219 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	goapi "github.com/carlosealves2/go-api"
+)
+
+func newAccessLogRouter(config AccessLogConfig) *goapi.Router {
+	router := goapi.NewRouter()
+	// The ID is assigned after AccessLog, under a custom header; a handler setting the default header
+	// must not change the logged ID.
+	router.Use(AccessLog(config), RequestIDWithConfig(RequestIDConfig{
+		Header:    "X-Correlation-ID",
+		Generator: func() string { return "req-1" },
+	}))
+	router.GET("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(DefaultRequestIDHeader, "spoofed")
+		_, _ = w.Write([]byte("user"))
+	})
+	router.GET("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	router.GET("/fail", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	router.GET("/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+	return router
+}
+
+func serve(router *goapi.Router, method, path string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req.RemoteAddr = "192.0.2.10:4321"
+	req.Header.Set("User-Agent", "test-agent/1.0")
+	req.Header.Set("Referer", "https://example.com/")
+	rec := httptest.NewRecorder()
+	router.ServerHTTP(rec, req)
+	return rec
+}
+
+func TestAccessLogJSON(t *testing.T) {
+	var output bytes.Buffer
+	router := newAccessLogRouter(AccessLogConfig{Format: AccessLogJSON, Output: &output})
+
+	serve(router, http.MethodGet, "/users/42")
+
+	var entry AccessLogEntry
+	if err := json.Unmarshal(output.Bytes(), &entry); err != nil {
+		t.Fatalf("expected a JSON line, got %q", output.String())
+	}
+
+	expected := AccessLogEntry{
+		Method:    "GET",
+		Path:      "/users/42",
+		URI:       "/users/42",
+		Route:     "/users/:id",
+		Proto:     "HTTP/1.1",
+		Status:    http.StatusOK,
+		Size:      4,
+		RequestID: "req-1",
+		RemoteIP:  "192.0.2.10",
+		UserAgent: "test-agent/1.0",
+		Referer:   "https://example.com/",
+	}
+	entry.Time, entry.Duration = expected.Time, expected.Duration
+	if entry != expected {
+		t.Errorf("expected entry %+v, got %+v", expected, entry)
+	}
+}
+
+func TestAccessLogCombined(t *testing.T) {
+	var output bytes.Buffer
+	router := newAccessLogRouter(AccessLogConfig{Format: AccessLogCombined, Output: &output})
+
+	serve(router, http.MethodGet, "/users/42")
+
+	pattern := regexp.MustCompile(`^192\.0\.2\.10 - - \[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}\] "GET /users/42 HTTP/1\.1" 200 4 "https://example\.com/" "test-agent/1\.0"\n$`)
+	if !pattern.MatchString(output.String()) {
+		t.Errorf("unexpected combined log line %q", output.String())
+	}
+}
+
+func TestAccessLogCombinedRemoteUser(t *testing.T) {
+	withPrincipal := func(next goapi.HandlerFunc) goapi.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if id := r.Header.Get("X-Principal"); id != "" {
+				r = goapi.WithPrincipal(r, &goapi.Principal{ID: id})
+			}
+			next(w, r)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		principal string
+		basicUser string
+		expected  string
+	}{
+		{name: "anonymous", expected: `- - [`},
+		{name: "principal", principal: "alice", basicUser: "mallory", expected: `- alice [`},
+		{name: "escaped basic user", basicUser: "bob\" ok\n", expected: `- bob\" ok\x0a [`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var output bytes.Buffer
+			router := goapi.NewRouter()
+			router.Use(AccessLog(AccessLogConfig{Format: AccessLogCombined, Output: &output}), withPrincipal)
+			router.GET("/search", func(w http.ResponseWriter, r *http.Request) {})
+
+			req := httptest.NewRequest(http.MethodGet, "/search?q=a%20b&page=2", nil)
+			if tt.principal != "" {
+				req.Header.Set("X-Principal", tt.principal)
+			}
+			if tt.basicUser != "" {
+				req.SetBasicAuth(tt.basicUser, "secret")
+			}
+			router.ServerHTTP(httptest.NewRecorder(), req)
+
+			line := output.String()
+			if !strings.Contains(line, tt.expected) {
+				t.Errorf("expected remote user %q in %q", tt.expected, line)
+			}
+			if !strings.Contains(line, `"GET /search?q=a%20b&page=2 HTTP/1.1"`) {
+				t.Errorf("expected the request URI in the request line, got %q", line)
+			}
+		})
+	}
+}
+
+func TestAccessLogPanic(t *testing.T) {
+	var output bytes.Buffer
+	router := newAccessLogRouter(AccessLogConfig{Format: AccessLogJSON, Output: &output})
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("expected the panic to propagate")
+			}
+		}()
+		serve(router, http.MethodGet, "/panic")
+	}()
+
+	var entry AccessLogEntry
+	if err := json.Unmarshal(output.Bytes(), &entry); err != nil {
+		t.Fatalf("expected the request to be logged, got %q", output.String())
+	}
+	if entry.Status != http.StatusInternalServerError || entry.Route != "/panic" {
+		t.Errorf("expected a 500 entry for the panicking route, got %+v", entry)
+	}
+}
+
+func TestAccessLogSlog(t *testing.T) {
+	var output bytes.Buffer
+	handler := slog.NewJSONHandler(&output, nil)
+	router := newAccessLogRouter(AccessLogConfig{Handler: handler})
+
+	serve(router, http.MethodGet, "/fail")
+
+	var record map[string]any
+	if err := json.Unmarshal(output.Bytes(), &record); err != nil {
+		t.Fatalf("expected a slog JSON record, got %q", output.String())
+	}
+	if record["level"] != "ERROR" || record["msg"] != "request" {
+		t.Errorf("expected an error-level request record, got %v", record)
+	}
+	if record["route"] != "/fail" || record["status"] != float64(500) || record["remote_ip"] != "192.0.2.10" {
+		t.Errorf("unexpected attributes %v", record)
+	}
+}
+
+func TestAccessLogExcludeAndSample(t *testing.T) {
+	var output bytes.Buffer
+	router := newAccessLogRouter(AccessLogConfig{
+		Format:       AccessLogJSON,
+		Output:       &output,
+		SampleRate:   1e-12,
+		ExcludePaths: []string{"/healthz"},
+	})
+
+	for i := 0; i < 20; i++ {
+		serve(router, http.MethodGet, "/users/1")
+	}
+	serve(router, http.MethodGet, "/healthz")
+	if output.Len() != 0 {
+		t.Errorf("expected sampled and excluded requests not to be logged, got %q", output.String())
+	}
+
+	serve(router, http.MethodGet, "/fail")
+	if lines := strings.Count(output.String(), "\n"); lines != 1 {
+		t.Errorf("expected server errors to bypass sampling, got %d lines", lines)
+	}
+}
+
+func TestAccessLogExcludeRoutePattern(t *testing.T) {
+	var output bytes.Buffer
+	router := newAccessLogRouter(AccessLogConfig{Format: AccessLogJSON, Output: &output, ExcludePaths: []string{"/users/:id"}})
+
+	serve(router, http.MethodGet, "/users/7")
+
+	if output.Len() != 0 {
+		t.Errorf("expected requests matching an excluded route pattern not to be logged, got %q", output.String())
+	}
+}
+
+func TestEscapeCombined(t *testing.T) {
+	if got := escapeCombined("a\"b\\c\nd"); got != `a\"b\\c\x0ad` {
+		t.Errorf("unexpected escaping %q", got)
+	}
+}
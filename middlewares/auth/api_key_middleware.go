@@ -0,0 +1,103 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"net/http"
+
+	goapi "github.com/carlosealves2/go-api"
+)
+
+// APIKeyConfig configures the APIKey middleware.
+type APIKeyConfig struct {
+	// Header is the request header carrying the key. Defaults to "X-API-Key".
+	Header string
+	// Query is the query parameter carrying the key when the header is absent. Empty disables query keys,
+	// which is the default since URLs tend to end up in logs.
+	Query string
+	// Lookup returns the principal owning a key, for example StaticKeys or a database lookup. Required.
+	Lookup TokenValidator
+	// Optional lets requests without a key through unauthenticated, for another middleware or the handler
+	// to deal with. Invalid keys are still rejected.
+	Optional bool
+}
+
+// APIKey returns a middleware authenticating requests by an API key sent in a header or a query parameter.
+// Requests without a valid key are answered with 401 Unauthorized, through the router's error handler.
+//
+// Example:
+//
+//	api.Use(auth.APIKey(auth.APIKeyConfig{
+//		Lookup: func(r *http.Request, key string) (*goapi.Principal, error) {
+//			client, err := clients.ByKey(r.Context(), key)
+//			if errors.Is(err, sql.ErrNoRows) {
+//				return nil, auth.ErrInvalidCredentials
+//			}
+//			if err != nil {
+//				return nil, err
+//			}
+//			return &goapi.Principal{ID: client.Name, Scheme: "api-key", Scopes: client.Scopes}, nil
+//		},
+//	}))
+func APIKey(config APIKeyConfig) goapi.MiddlewareFunc {
+	if config.Lookup == nil {
+		panic("auth: APIKey requires a Lookup function")
+	}
+	if config.Header == "" {
+		config.Header = "X-API-Key"
+	}
+	challenge := "APIKey header=" + quote(config.Header)
+
+	return func(next goapi.HandlerFunc) goapi.HandlerFunc {
+		return func(w http.ResponseWriter, req *http.Request) {
+			if authenticated(req) {
+				next(w, req)
+				return
+			}
+
+			key := req.Header.Get(config.Header)
+			if key == "" && config.Query != "" {
+				key = req.URL.Query().Get(config.Query)
+			}
+			if key == "" {
+				if config.Optional {
+					next(w, req)
+					return
+				}
+				unauthorized(w, req, challenge)
+				return
+			}
+
+			principal, err := config.Lookup(req, key)
+			authenticate(w, req, next, principal, err, challenge)
+		}
+	}
+}
+
+// StaticKeys returns a TokenValidator accepting a fixed set of keys, mapped to the principals owning them.
+// Keys are looked up by their SHA-256 digest, so that lookup time does not depend on how much of a guessed
+// key matches. The principals are shared between requests and must not be modified.
+//
+// Example:
+//
+//	auth.APIKey(auth.APIKeyConfig{Lookup: auth.StaticKeys(map[string]*goapi.Principal{
+//		os.Getenv("BILLING_KEY"): {ID: "billing", Scopes: []string{"invoices:write"}},
+//	})})
+func StaticKeys(keys map[string]*goapi.Principal) TokenValidator {
+	digests := make(map[[32]byte]*goapi.Principal, len(keys))
+	for key, principal := range keys {
+		if principal.Scheme == "" {
+			copied := *principal
+			copied.Scheme = "api-key"
+			principal = &copied
+		}
+		digests[sha256.Sum256([]byte(key))] = principal
+	}
+
+	return func(r *http.Request, key string) (*goapi.Principal, error) {
+		principal, ok := digests[sha256.Sum256([]byte(key))]
+		if !ok {
+			return nil, ErrInvalidCredentials
+		}
+		return principal, nil
+	}
+}
@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	goapi "github.com/carlosealves2/go-api"
+)
+
+func TestAPIKey(t *testing.T) {
+	keys := StaticKeys(map[string]*goapi.Principal{
+		"k1": {ID: "billing", Scopes: []string{"invoices:write"}},
+		"k2": {ID: "reports", Scheme: "partner-key"},
+	})
+
+	tests := []struct {
+		name           string
+		config         APIKeyConfig
+		target         string
+		headers        map[string]string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{name: "default header", config: APIKeyConfig{Lookup: keys}, target: "/", headers: map[string]string{"X-API-Key": "k1"}, expectedStatus: http.StatusOK, expectedBody: "billing api-key"},
+		{name: "scheme kept", config: APIKeyConfig{Lookup: keys}, target: "/", headers: map[string]string{"X-API-Key": "k2"}, expectedStatus: http.StatusOK, expectedBody: "reports partner-key"},
+		{name: "custom header", config: APIKeyConfig{Header: "X-Token", Lookup: keys}, target: "/", headers: map[string]string{"X-Token": "k1"}, expectedStatus: http.StatusOK, expectedBody: "billing api-key"},
+		{name: "query parameter", config: APIKeyConfig{Query: "api_key", Lookup: keys}, target: "/?api_key=k1", expectedStatus: http.StatusOK, expectedBody: "billing api-key"},
+		{name: "header wins over query", config: APIKeyConfig{Query: "api_key", Lookup: keys}, target: "/?api_key=k1", headers: map[string]string{"X-API-Key": "k2"}, expectedStatus: http.StatusOK, expectedBody: "reports partner-key"},
+		{name: "query disabled", config: APIKeyConfig{Lookup: keys}, target: "/?api_key=k1", expectedStatus: http.StatusUnauthorized},
+		{name: "invalid key", config: APIKeyConfig{Lookup: keys}, target: "/", headers: map[string]string{"X-API-Key": "k3"}, expectedStatus: http.StatusUnauthorized},
+		{name: "missing key", config: APIKeyConfig{Lookup: keys}, target: "/", expectedStatus: http.StatusUnauthorized},
+		{name: "optional missing key", config: APIKeyConfig{Lookup: keys, Optional: true}, target: "/", expectedStatus: http.StatusOK, expectedBody: "anonymous"},
+		{name: "optional invalid key", config: APIKeyConfig{Lookup: keys, Optional: true}, target: "/", headers: map[string]string{"X-API-Key": "k3"}, expectedStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := authRouter(APIKey(tt.config))
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			rec := httptest.NewRecorder()
+			router.ServerHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Fatalf("expected %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if tt.expectedBody != "" && rec.Body.String() != tt.expectedBody {
+				t.Errorf("expected body %q, got %q", tt.expectedBody, rec.Body.String())
+			}
+			if rec.Code == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+				t.Errorf("expected a WWW-Authenticate challenge")
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+// Package auth provides authentication middlewares: HTTP Basic authentication, including htpasswd files,
+// API keys, bearer tokens and JWTs verified with a secret or a JSON Web Key Set. They store the
+// authenticated goapi.Principal in the request context, where handlers and authorization middlewares read
+// it with goapi.PrincipalFromContext. Authorize enforces the permissions declared on routes with
+// goapi.Route.Require.
+//
+// A request that a previous middleware already authenticated passes through, so several schemes can be
+// accepted by chaining middlewares whose Optional field is set, followed by one that is not.
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	goapi "github.com/carlosealves2/go-api"
+)
+
+// ErrInvalidCredentials is returned by validation functions to reject credentials. The request is then
+// answered with 401 Unauthorized. Validation functions may also return a nil principal without an error.
+var ErrInvalidCredentials = errors.New("auth: invalid credentials")
+
+// TokenValidator returns the principal identified by a token, such as an API key or a bearer token.
+// Errors other than ErrInvalidCredentials are written with goapi.WriteError.
+type TokenValidator func(r *http.Request, token string) (*goapi.Principal, error)
+
+// authenticated reports whether a previous middleware already authenticated the request.
+func authenticated(req *http.Request) bool {
+	_, ok := goapi.PrincipalFromContext(req)
+	return ok
+}
+
+// usesScheme reports whether the Authorization header of the request uses the scheme.
+func usesScheme(req *http.Request, scheme string) bool {
+	name, _, _ := strings.Cut(req.Header.Get("Authorization"), " ")
+	return strings.EqualFold(name, scheme)
+}
+
+// authenticate calls next with the principal returned by a validation function, or rejects the request.
+// challenge is the WWW-Authenticate header of the 401 response.
+func authenticate(w http.ResponseWriter, req *http.Request, next goapi.HandlerFunc, principal *goapi.Principal, err error, challenge string) {
+	if err == nil && principal != nil {
+		next(w, goapi.WithPrincipal(req, principal))
+		return
+	}
+	if err != nil && !errors.Is(err, ErrInvalidCredentials) {
+		goapi.WriteError(w, req, err)
+		return
+	}
+	unauthorized(w, req, challenge)
+}
+
+// unauthorized writes a 401 Unauthorized response with the given challenge.
+func unauthorized(w http.ResponseWriter, req *http.Request, challenge string) {
+	w.Header().Set("WWW-Authenticate", challenge)
+	goapi.WriteError(w, req, goapi.NewHTTPError(http.StatusUnauthorized, ""))
+}
+
+// quote returns s as an HTTP quoted string.
+func quote(s string) string {
+	out := make([]byte, 0, len(s)+2)
+	out = append(out, '"')
+	for i := 0; i < len(s); i++ {
+		if s[i] == '"' || s[i] == '\\' {
+			out = append(out, '\\')
+		}
+		out = append(out, s[i])
+	}
+	return string(append(out, '"'))
+}
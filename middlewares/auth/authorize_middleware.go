@@ -0,0 +1,127 @@
+package auth
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	goapi "github.com/carlosealves2/go-api"
+)
+
+// Policy decides whether a principal holds a permission declared with Route.Require. It receives the
+// request, so attribute-based policies can also look at route parameters, headers or the time of day.
+type Policy func(r *http.Request, principal *goapi.Principal, permission string) bool
+
+// AuthorizeConfig configures the Authorize middleware.
+type AuthorizeConfig struct {
+	// Policy decides whether the principal holds each permission required by the route.
+	// Defaults to ScopesAndRoles.
+	Policy Policy
+}
+
+// Authorize returns a middleware enforcing the permissions declared on routes with Route.Require. Routes
+// without requirements are not affected. Requests to other routes are answered with 401 Unauthorized when
+// no principal was authenticated, and 403 Forbidden unless the policy grants the principal every required
+// permission.
+//
+// Register it after the authentication middlewares, so that the principal is in the context. Granted
+// requests are marked with goapi.WithAuthorized: routes with requirements that are not covered by
+// Authorize answer 500 Internal Server Error instead of running their handler.
+//
+// Example:
+//
+//	api.Use(
+//		auth.JWT(jwtConfig),
+//		auth.Authorize(auth.AuthorizeConfig{Policy: auth.RBAC(map[string][]string{
+//			"admin":   {"*"},
+//			"support": {"orders:read", "users:read"},
+//		})}),
+//	)
+//	api.GET("/orders/:id", getOrder).Require("orders:read")
+//	api.DELETE("/orders/:id", deleteOrder).Require("orders:delete")
+func Authorize(config AuthorizeConfig) goapi.MiddlewareFunc {
+	if config.Policy == nil {
+		config.Policy = ScopesAndRoles
+	}
+
+	return func(next goapi.HandlerFunc) goapi.HandlerFunc {
+		return func(w http.ResponseWriter, req *http.Request) {
+			route, _ := goapi.RouteFromContext(req)
+			if len(route.Requirements) == 0 {
+				next(w, req)
+				return
+			}
+
+			principal, ok := goapi.PrincipalFromContext(req)
+			if !ok {
+				goapi.WriteError(w, req, goapi.NewHTTPError(http.StatusUnauthorized, ""))
+				return
+			}
+			for _, permission := range route.Requirements {
+				if !config.Policy(req, principal, permission) {
+					goapi.WriteError(w, req, goapi.NewHTTPError(http.StatusForbidden, ""))
+					return
+				}
+			}
+			next(w, goapi.WithAuthorized(req))
+		}
+	}
+}
+
+// ScopesAndRoles is a Policy granting a permission that is one of the principal's scopes or roles.
+func ScopesAndRoles(r *http.Request, principal *goapi.Principal, permission string) bool {
+	return principal.HasScope(permission) || principal.HasRole(permission)
+}
+
+// RBAC returns a role-based Policy: a principal holds the permissions granted to any of its roles by the
+// table. A permission of "*" grants everything, and one ending with ":*" grants every permission with
+// that prefix, so "orders:*" grants "orders:read" and "orders:delete".
+//
+// Example:
+//
+//	auth.RBAC(map[string][]string{
+//		"admin":   {"*"},
+//		"support": {"orders:read", "users:*"},
+//	})
+func RBAC(table map[string][]string) Policy {
+	return func(r *http.Request, principal *goapi.Principal, permission string) bool {
+		for _, role := range principal.Roles {
+			if slices.ContainsFunc(table[role], func(granted string) bool {
+				return grants(granted, permission)
+			}) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// AnyOf returns a Policy granting the permissions granted by any of the policies, for example to accept
+// both OAuth scopes and roles mapped by an RBAC table.
+//
+// Example:
+//
+//	// Owners may always read their own account.
+//	owner := func(r *http.Request, p *goapi.Principal, permission string) bool {
+//		return permission == "users:read" && goapi.ParamsFromContext(r)["id"] == p.ID
+//	}
+//	auth.AnyOf(auth.ScopesAndRoles, auth.RBAC(roles), owner)
+func AnyOf(policies ...Policy) Policy {
+	return func(r *http.Request, principal *goapi.Principal, permission string) bool {
+		for _, policy := range policies {
+			if policy(r, principal, permission) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// grants reports whether a permission of an RBAC table grants the requested permission.
+func grants(granted, permission string) bool {
+	if granted == "*" || granted == permission {
+		return true
+	}
+	prefix, ok := strings.CutSuffix(granted, "*")
+	return ok && strings.HasSuffix(prefix, ":") && strings.HasPrefix(permission, prefix)
+}
@@ -0,0 +1,115 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	goapi "github.com/carlosealves2/go-api"
+)
+
+// principalHeader authenticates requests with the principal described by the X-Roles and X-Scopes headers,
+// as comma-separated lists, and the X-User header as ID.
+func principalHeader(next goapi.HandlerFunc) goapi.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if id := r.Header.Get("X-User"); id != "" {
+			principal := &goapi.Principal{ID: id}
+			if roles := r.Header.Get("X-Roles"); roles != "" {
+				principal.Roles = strings.Split(roles, ",")
+			}
+			if scopes := r.Header.Get("X-Scopes"); scopes != "" {
+				principal.Scopes = strings.Split(scopes, ",")
+			}
+			r = goapi.WithPrincipal(r, principal)
+		}
+		next(w, r)
+	}
+}
+
+func authorizeRouter(policy Policy) *goapi.Router {
+	router := goapi.NewRouter()
+	router.Use(principalHeader, Authorize(AuthorizeConfig{Policy: policy}))
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}
+	router.GET("/public", ok)
+	router.GET("/orders/:id", ok).Require("orders:read")
+	router.DELETE("/orders/:id", ok).Require("orders:read", "orders:delete")
+	router.GET("/users/:id", ok).Require("users:read")
+	return router
+}
+
+func TestAuthorize(t *testing.T) {
+	owner := func(r *http.Request, principal *goapi.Principal, permission string) bool {
+		return permission == "users:read" && goapi.ParamsFromContext(r)["id"] == principal.ID
+	}
+	rbac := RBAC(map[string][]string{
+		"admin":   {"*"},
+		"support": {"orders:read", "users:*"},
+	})
+
+	tests := []struct {
+		name           string
+		policy         Policy
+		method         string
+		target         string
+		headers        map[string]string
+		expectedStatus int
+	}{
+		{name: "no requirements", method: http.MethodGet, target: "/public", expectedStatus: http.StatusOK},
+		{name: "unauthenticated", method: http.MethodGet, target: "/orders/1", expectedStatus: http.StatusUnauthorized},
+		{name: "scope granted", method: http.MethodGet, target: "/orders/1", headers: map[string]string{"X-User": "a", "X-Scopes": "orders:read"}, expectedStatus: http.StatusOK},
+		{name: "role granted", method: http.MethodGet, target: "/orders/1", headers: map[string]string{"X-User": "a", "X-Roles": "orders:read"}, expectedStatus: http.StatusOK},
+		{name: "missing scope", method: http.MethodGet, target: "/orders/1", headers: map[string]string{"X-User": "a", "X-Scopes": "orders:write"}, expectedStatus: http.StatusForbidden},
+		{name: "all requirements needed", method: http.MethodDelete, target: "/orders/1", headers: map[string]string{"X-User": "a", "X-Scopes": "orders:read"}, expectedStatus: http.StatusForbidden},
+		{name: "all requirements granted", method: http.MethodDelete, target: "/orders/1", headers: map[string]string{"X-User": "a", "X-Scopes": "orders:read,orders:delete"}, expectedStatus: http.StatusOK},
+		{name: "rbac wildcard", policy: rbac, method: http.MethodDelete, target: "/orders/1", headers: map[string]string{"X-User": "a", "X-Roles": "admin"}, expectedStatus: http.StatusOK},
+		{name: "rbac prefix", policy: rbac, method: http.MethodGet, target: "/users/7", headers: map[string]string{"X-User": "a", "X-Roles": "support"}, expectedStatus: http.StatusOK},
+		{name: "rbac denied", policy: rbac, method: http.MethodDelete, target: "/orders/1", headers: map[string]string{"X-User": "a", "X-Roles": "support"}, expectedStatus: http.StatusForbidden},
+		{name: "rbac ignores scopes", policy: rbac, method: http.MethodGet, target: "/orders/1", headers: map[string]string{"X-User": "a", "X-Scopes": "orders:read"}, expectedStatus: http.StatusForbidden},
+		{name: "attribute policy", policy: AnyOf(rbac, owner), method: http.MethodGet, target: "/users/7", headers: map[string]string{"X-User": "7"}, expectedStatus: http.StatusOK},
+		{name: "attribute policy denied", policy: AnyOf(rbac, owner), method: http.MethodGet, target: "/users/8", headers: map[string]string{"X-User": "7"}, expectedStatus: http.StatusForbidden},
+		{name: "preflight not restricted", method: http.MethodOptions, target: "/orders/1", expectedStatus: http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := authorizeRouter(tt.policy)
+			req := httptest.NewRequest(tt.method, tt.target, nil)
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			rec := httptest.NewRecorder()
+			router.ServerHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected %d, got %d", tt.expectedStatus, rec.Code)
+			}
+		})
+	}
+}
+
+func TestRBACGrants(t *testing.T) {
+	tests := []struct {
+		granted    string
+		permission string
+		expected   bool
+	}{
+		{granted: "*", permission: "orders:read", expected: true},
+		{granted: "orders:read", permission: "orders:read", expected: true},
+		{granted: "orders:*", permission: "orders:read", expected: true},
+		{granted: "orders:*", permission: "orders:items:read", expected: true},
+		{granted: "orders:*", permission: "ordersx:read", expected: false},
+		{granted: "orders*", permission: "ordersx:read", expected: false},
+		{granted: "orders:read", permission: "orders:write", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.granted+" "+tt.permission, func(t *testing.T) {
+			if got := grants(tt.granted, tt.permission); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+
+	goapi "github.com/carlosealves2/go-api"
+)
+
+// BasicValidator returns the principal identified by a user name and password.
+// Errors other than ErrInvalidCredentials are written with goapi.WriteError.
+type BasicValidator func(r *http.Request, username, password string) (*goapi.Principal, error)
+
+// BasicConfig configures the Basic middleware.
+type BasicConfig struct {
+	// Realm is the protection space announced to clients. Defaults to "Restricted".
+	Realm string
+	// Validate checks the credentials, for example Users or Htpasswd.Validate. Required.
+	Validate BasicValidator
+	// Optional lets requests without Basic credentials through unauthenticated, for another middleware or
+	// the handler to deal with. Invalid credentials are still rejected.
+	Optional bool
+}
+
+// Basic returns a middleware implementing HTTP Basic authentication (RFC 7617). Requests without valid
+// credentials are answered with 401 Unauthorized and a WWW-Authenticate challenge, through the router's
+// error handler.
+//
+// Basic credentials are sent in clear text on every request: only use it over HTTPS.
+//
+// Example:
+//
+//	users, err := auth.LoadHtpasswd("/etc/myapp/htpasswd")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	admin := api.Group("/admin")
+//	admin.Use(auth.Basic(auth.BasicConfig{Realm: "admin", Validate: users.Validate}))
+func Basic(config BasicConfig) goapi.MiddlewareFunc {
+	if config.Validate == nil {
+		panic("auth: Basic requires a Validate function")
+	}
+	if config.Realm == "" {
+		config.Realm = "Restricted"
+	}
+	challenge := "Basic realm=" + quote(config.Realm) + `, charset="UTF-8"`
+
+	return func(next goapi.HandlerFunc) goapi.HandlerFunc {
+		return func(w http.ResponseWriter, req *http.Request) {
+			if authenticated(req) {
+				next(w, req)
+				return
+			}
+
+			username, password, ok := req.BasicAuth()
+			if !ok {
+				if config.Optional && !usesScheme(req, "Basic") {
+					next(w, req)
+					return
+				}
+				unauthorized(w, req, challenge)
+				return
+			}
+
+			principal, err := config.Validate(req, username, password)
+			authenticate(w, req, next, principal, err, challenge)
+		}
+	}
+}
+
+// Users returns a BasicValidator accepting the given user names and clear-text passwords. Passwords are
+// compared in constant time. Prefer an htpasswd file with bcrypt hashes, so that passwords are not kept in
+// clear text.
+//
+// Example:
+//
+//	auth.Basic(auth.BasicConfig{Validate: auth.Users(map[string]string{"ops": os.Getenv("OPS_PASSWORD")})})
+func Users(users map[string]string) BasicValidator {
+	hashes := make(map[string][32]byte, len(users))
+	for username, password := range users {
+		hashes[username] = sha256.Sum256([]byte(password))
+	}
+
+	return func(r *http.Request, username, password string) (*goapi.Principal, error) {
+		// Comparing fixed-size digests hides the length of the password, and unknown users take as long
+		// as known ones.
+		expected, known := hashes[username]
+		given := sha256.Sum256([]byte(password))
+		if subtle.ConstantTimeCompare(given[:], expected[:]) != 1 || !known {
+			return nil, ErrInvalidCredentials
+		}
+		return &goapi.Principal{ID: username, Scheme: "basic"}, nil
+	}
+}
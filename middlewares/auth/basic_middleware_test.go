@@ -0,0 +1,167 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	goapi "github.com/carlosealves2/go-api"
+)
+
+// whoami writes the ID and scheme of the request principal, or "anonymous".
+func whoami(w http.ResponseWriter, r *http.Request) {
+	principal, ok := goapi.PrincipalFromContext(r)
+	if !ok {
+		w.Write([]byte("anonymous"))
+		return
+	}
+	w.Write([]byte(principal.ID + " " + principal.Scheme))
+}
+
+// authRouter returns a router serving whoami at "/" behind the middlewares.
+func authRouter(middleware ...goapi.MiddlewareFunc) *goapi.Router {
+	router := goapi.NewRouter()
+	router.Use(middleware...)
+	router.GET("/", whoami)
+	return router
+}
+
+func TestBasic(t *testing.T) {
+	router := authRouter(Basic(BasicConfig{
+		Realm:    "admin",
+		Validate: Users(map[string]string{"alice": "s3cret", "bob": ""}),
+	}))
+
+	tests := []struct {
+		name              string
+		authorization     string
+		username          string
+		password          string
+		expectedStatus    int
+		expectedBody      string
+		expectedChallenge string
+	}{
+		{name: "valid credentials", username: "alice", password: "s3cret", expectedStatus: http.StatusOK, expectedBody: "alice basic"},
+		{name: "empty password", username: "bob", password: "", expectedStatus: http.StatusOK, expectedBody: "bob basic"},
+		{name: "wrong password", username: "alice", password: "s3cret!", expectedStatus: http.StatusUnauthorized, expectedChallenge: `Basic realm="admin", charset="UTF-8"`},
+		{name: "unknown user", username: "mallory", password: "", expectedStatus: http.StatusUnauthorized},
+		{name: "missing credentials", expectedStatus: http.StatusUnauthorized, expectedChallenge: `Basic realm="admin", charset="UTF-8"`},
+		{name: "malformed credentials", authorization: "Basic !!!", expectedStatus: http.StatusUnauthorized},
+		{name: "other scheme", authorization: "Bearer token", expectedStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.username != "" {
+				req.SetBasicAuth(tt.username, tt.password)
+			}
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			router.ServerHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Fatalf("expected %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if tt.expectedBody != "" && rec.Body.String() != tt.expectedBody {
+				t.Errorf("expected body %q, got %q", tt.expectedBody, rec.Body.String())
+			}
+			if tt.expectedChallenge != "" && rec.Header().Get("WWW-Authenticate") != tt.expectedChallenge {
+				t.Errorf("expected challenge %q, got %q", tt.expectedChallenge, rec.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func TestBasicValidatorErrors(t *testing.T) {
+	router := authRouter(Basic(BasicConfig{
+		Validate: func(r *http.Request, username, password string) (*goapi.Principal, error) {
+			switch username {
+			case "down":
+				return nil, goapi.NewHTTPError(http.StatusServiceUnavailable, "user store unavailable")
+			case "nil":
+				return nil, nil
+			}
+			return nil, errors.Join(errors.New("locked"), ErrInvalidCredentials)
+		},
+	}))
+
+	tests := []struct {
+		name           string
+		username       string
+		expectedStatus int
+	}{
+		{name: "store error", username: "down", expectedStatus: http.StatusServiceUnavailable},
+		{name: "nil principal", username: "nil", expectedStatus: http.StatusUnauthorized},
+		{name: "wrapped invalid credentials", username: "locked", expectedStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.SetBasicAuth(tt.username, "x")
+			rec := httptest.NewRecorder()
+			router.ServerHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected %d, got %d", tt.expectedStatus, rec.Code)
+			}
+		})
+	}
+}
+
+func TestChainedSchemes(t *testing.T) {
+	router := authRouter(
+		Basic(BasicConfig{Validate: Users(map[string]string{"alice": "s3cret"}), Optional: true}),
+		APIKey(APIKeyConfig{Lookup: StaticKeys(map[string]*goapi.Principal{"k1": {ID: "billing"}}), Optional: true}),
+		Bearer(BearerConfig{Validate: func(r *http.Request, token string) (*goapi.Principal, error) {
+			if token != "t1" {
+				return nil, ErrInvalidCredentials
+			}
+			return &goapi.Principal{ID: "carol", Scheme: "bearer"}, nil
+		}}),
+	)
+
+	tests := []struct {
+		name           string
+		headers        map[string]string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{name: "basic", headers: map[string]string{"Authorization": "Basic YWxpY2U6czNjcmV0"}, expectedStatus: http.StatusOK, expectedBody: "alice basic"},
+		{name: "api key", headers: map[string]string{"X-API-Key": "k1"}, expectedStatus: http.StatusOK, expectedBody: "billing api-key"},
+		{name: "bearer", headers: map[string]string{"Authorization": "Bearer t1"}, expectedStatus: http.StatusOK, expectedBody: "carol bearer"},
+		{name: "invalid basic is not skipped", headers: map[string]string{"Authorization": "Basic YWxpY2U6eA==", "X-API-Key": "k1"}, expectedStatus: http.StatusUnauthorized},
+		{name: "no credentials", expectedStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			rec := httptest.NewRecorder()
+			router.ServerHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Fatalf("expected %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if tt.expectedBody != "" && rec.Body.String() != tt.expectedBody {
+				t.Errorf("expected body %q, got %q", tt.expectedBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestBasicRequiresValidate(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected a panic without a Validate function")
+		}
+	}()
+	Basic(BasicConfig{})
+}
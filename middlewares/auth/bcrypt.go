@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// MinBcryptCost is the lowest cost accepted by HashPassword.
+	MinBcryptCost = bcrypt.MinCost
+	// MaxBcryptCost is the highest cost accepted by HashPassword.
+	MaxBcryptCost = bcrypt.MaxCost
+	// DefaultBcryptCost is the cost used by HashPassword when given zero.
+	DefaultBcryptCost = bcrypt.DefaultCost
+)
+
+// dummyHash is a bcrypt hash at DefaultBcryptCost that no password is expected to match. Passwords of
+// unknown users are checked against it when no other hash is at hand, so that they take as long to reject
+// as those of known users.
+const dummyHash = "$2a$10$ZlgL6iwmbm57JlldVTuWqubyzRmCAlr8i6Ufmav7AnASPMSHaXukm"
+
+// HashPassword hashes a password with bcrypt, in the "$2a$" format accepted by htpasswd files.
+//
+// Parameters:
+// - password: The password to hash, at most 72 bytes long.
+// - cost: The base-2 logarithm of the number of iterations, between MinBcryptCost and MaxBcryptCost.
+// Zero selects DefaultBcryptCost.
+//
+// Example:
+//
+//	hash, err := auth.HashPassword("s3cret", 0)
+//	fmt.Fprintf(file, "alice:%s\n", hash)
+func HashPassword(password string, cost int) (string, error) {
+	if cost == 0 {
+		cost = DefaultBcryptCost
+	}
+	if cost < MinBcryptCost || cost > MaxBcryptCost {
+		return "", fmt.Errorf("auth: bcrypt cost %d out of range", cost)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), cost)
+	if err != nil {
+		return "", fmt.Errorf("auth: %w", err)
+	}
+	return string(hash), nil
+}
+
+// CheckPassword reports whether password matches hash, in constant time. Supported hashes are bcrypt
+// ("$2a$", "$2b$" and "$2y$") and SHA-1 ("{SHA}"), the formats of htpasswd files. Bcrypt is recommended:
+// SHA-1 hashes are unsalted and fast to brute-force.
+func CheckPassword(hash, password string) bool {
+	if encoded, ok := strings.CutPrefix(hash, "{SHA}"); ok {
+		sum := sha1.Sum([]byte(password))
+		expected := base64.StdEncoding.EncodeToString(sum[:])
+		return subtle.ConstantTimeCompare([]byte(encoded), []byte(expected)) == 1
+	}
+	if !isBcrypt(hash) {
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// validHash reports whether hash is in a format supported by CheckPassword, without hashing anything.
+func validHash(hash string) bool {
+	if encoded, ok := strings.CutPrefix(hash, "{SHA}"); ok {
+		sum, err := base64.StdEncoding.DecodeString(encoded)
+		return err == nil && len(sum) == sha1.Size
+	}
+	return isBcrypt(hash)
+}
+
+// isBcrypt reports whether hash is a well-formed bcrypt hash of one of the versions written by htpasswd
+// and the bcrypt libraries. The bcrypt package alone would accept any minor version.
+func isBcrypt(hash string) bool {
+	if len(hash) != 60 || !strings.HasPrefix(hash, "$2a$") && !strings.HasPrefix(hash, "$2b$") &&
+		!strings.HasPrefix(hash, "$2y$") {
+		return false
+	}
+	_, err := bcrypt.Cost([]byte(hash))
+	return err == nil
+}
@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestCheckPassword(t *testing.T) {
+	tests := []struct {
+		name     string
+		hash     string
+		password string
+		expected bool
+	}{
+		{name: "bcrypt 2a", hash: "$2a$05$CCCCCCCCCCCCCCCCCCCCC.E5YPO9kmyuRGyh0XouQYb4YMJKvyOeW", password: "U*U", expected: true},
+		{name: "bcrypt empty password", hash: "$2a$05$CCCCCCCCCCCCCCCCCCCCC.7uG0VCzI2bS7j6ymqJi9CdcdxiRTWNy", password: "", expected: true},
+		{name: "bcrypt 2b", hash: "$2b$04$abcdefghijklmnopqrstuuLZYjhNQAOdpbzt4WxWlUHjv1wsyH5DG", password: "s3cret", expected: true},
+		{name: "bcrypt 2y", hash: "$2y$06$0123456789ABCDEFGHIJKuLlY0YHBYpLj0MmMN.GT/6U2lEH4VAuC", password: "correct horse battery staple", expected: true},
+		{name: "bcrypt 72 bytes", hash: "$2b$04$saltsaltsaltsaltsaltsO0ZTUJGRQojW66/E0DG2EfK.7eVZaxxC", password: strings.Repeat("x", 72), expected: true},
+		{name: "bcrypt truncated after 72 bytes", hash: "$2b$04$saltsaltsaltsaltsaltsO0ZTUJGRQojW66/E0DG2EfK.7eVZaxxC", password: strings.Repeat("x", 80), expected: true},
+		{name: "bcrypt wrong password", hash: "$2b$04$abcdefghijklmnopqrstuuLZYjhNQAOdpbzt4WxWlUHjv1wsyH5DG", password: "s3cret!", expected: false},
+		{name: "sha1", hash: "{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=", password: "password", expected: true},
+		{name: "sha1 wrong password", hash: "{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=", password: "Password", expected: false},
+		{name: "plain text", hash: "password", password: "password", expected: false},
+		{name: "malformed bcrypt", hash: "$2b$04$short", password: "s3cret", expected: false},
+		{name: "unsupported bcrypt version", hash: "$2x$04$abcdefghijklmnopqrstuuLZYjhNQAOdpbzt4WxWlUHjv1wsyH5DG", password: "s3cret", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CheckPassword(tt.hash, tt.password); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestHashPassword(t *testing.T) {
+	hash, err := HashPassword("s3cret", MinBcryptCost)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(hash, "$2a$04$") || len(hash) != 60 {
+		t.Errorf("unexpected hash format %q", hash)
+	}
+	if !CheckPassword(hash, "s3cret") || CheckPassword(hash, "other") {
+		t.Errorf("expected the hash to match only its password")
+	}
+
+	other, err := HashPassword("s3cret", MinBcryptCost)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if other == hash {
+		t.Errorf("expected a random salt")
+	}
+
+	for _, cost := range []int{MinBcryptCost - 1, MaxBcryptCost + 1} {
+		if _, err := HashPassword("s3cret", cost); err == nil {
+			t.Errorf("expected an error for cost %d", cost)
+		}
+	}
+	if _, err := HashPassword(strings.Repeat("x", 73), MinBcryptCost); err == nil {
+		t.Errorf("expected an error for a password longer than 72 bytes")
+	}
+}
+
+func TestDummyHash(t *testing.T) {
+	if !validHash(dummyHash) {
+		t.Fatalf("expected the dummy hash to be a valid bcrypt hash")
+	}
+	if cost, _ := bcrypt.Cost([]byte(dummyHash)); cost != DefaultBcryptCost {
+		t.Errorf("expected the dummy hash to use the default cost, got %d", cost)
+	}
+	if CheckPassword(dummyHash, "") {
+		t.Errorf("expected the dummy hash not to match an empty password")
+	}
+}
@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"net/http"
+	"strings"
+
+	goapi "github.com/carlosealves2/go-api"
+)
+
+// BearerConfig configures the Bearer middleware.
+type BearerConfig struct {
+	// Realm is the protection space announced to clients. Defaults to "Restricted".
+	Realm string
+	// Validate returns the principal identified by a token, for example by introspecting it or looking up
+	// an opaque session token. Required.
+	Validate TokenValidator
+	// Optional lets requests without a bearer token through unauthenticated, for another middleware or the
+	// handler to deal with. Invalid tokens are still rejected.
+	Optional bool
+}
+
+// BearerToken extracts the token of an "Authorization: Bearer <token>" header (RFC 6750).
+// The scheme is matched case-insensitively. The second return value is false when there is no such header.
+func BearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// Bearer returns a middleware authenticating requests by a bearer token. Requests without a token are
+// answered with 401 Unauthorized and a "Bearer" challenge; requests with an invalid token also get
+// error="invalid_token" in the challenge, as described by RFC 6750.
+//
+// Example:
+//
+//	api.Use(auth.Bearer(auth.BearerConfig{
+//		Validate: func(r *http.Request, token string) (*goapi.Principal, error) {
+//			session, ok := sessions.Get(token)
+//			if !ok {
+//				return nil, auth.ErrInvalidCredentials
+//			}
+//			return &goapi.Principal{ID: session.UserID, Scheme: "bearer", Roles: session.Roles}, nil
+//		},
+//	}))
+func Bearer(config BearerConfig) goapi.MiddlewareFunc {
+	if config.Validate == nil {
+		panic("auth: Bearer requires a Validate function")
+	}
+	if config.Realm == "" {
+		config.Realm = "Restricted"
+	}
+	challenge := "Bearer realm=" + quote(config.Realm)
+	invalid := challenge + `, error="invalid_token"`
+
+	return func(next goapi.HandlerFunc) goapi.HandlerFunc {
+		return func(w http.ResponseWriter, req *http.Request) {
+			if authenticated(req) {
+				next(w, req)
+				return
+			}
+
+			token, ok := BearerToken(req)
+			if !ok {
+				if config.Optional && !usesScheme(req, "Bearer") {
+					next(w, req)
+					return
+				}
+				unauthorized(w, req, challenge)
+				return
+			}
+
+			principal, err := config.Validate(req, token)
+			authenticate(w, req, next, principal, err, invalid)
+		}
+	}
+}
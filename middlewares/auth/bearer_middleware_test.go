@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	goapi "github.com/carlosealves2/go-api"
+)
+
+func TestBearerToken(t *testing.T) {
+	tests := []struct {
+		name          string
+		authorization string
+		expected      string
+		expectedOK    bool
+	}{
+		{name: "token", authorization: "Bearer abc.def", expected: "abc.def", expectedOK: true},
+		{name: "lower case scheme", authorization: "bearer abc", expected: "abc", expectedOK: true},
+		{name: "surrounding spaces", authorization: "Bearer   abc ", expected: "abc", expectedOK: true},
+		{name: "empty token", authorization: "Bearer ", expectedOK: false},
+		{name: "other scheme", authorization: "Basic YWxpY2U6czNjcmV0", expectedOK: false},
+		{name: "missing header", expectedOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			token, ok := BearerToken(req)
+			if token != tt.expected || ok != tt.expectedOK {
+				t.Errorf("expected (%q, %v), got (%q, %v)", tt.expected, tt.expectedOK, token, ok)
+			}
+		})
+	}
+}
+
+func TestBearer(t *testing.T) {
+	validate := func(r *http.Request, token string) (*goapi.Principal, error) {
+		if token != "t1" {
+			return nil, ErrInvalidCredentials
+		}
+		return &goapi.Principal{ID: "carol", Scheme: "bearer"}, nil
+	}
+
+	tests := []struct {
+		name              string
+		config            BearerConfig
+		authorization     string
+		expectedStatus    int
+		expectedBody      string
+		expectedChallenge string
+	}{
+		{name: "valid token", config: BearerConfig{Validate: validate}, authorization: "Bearer t1", expectedStatus: http.StatusOK, expectedBody: "carol bearer"},
+		{name: "invalid token", config: BearerConfig{Realm: "api", Validate: validate}, authorization: "Bearer t2", expectedStatus: http.StatusUnauthorized, expectedChallenge: `Bearer realm="api", error="invalid_token"`},
+		{name: "missing token", config: BearerConfig{Validate: validate}, expectedStatus: http.StatusUnauthorized, expectedChallenge: `Bearer realm="Restricted"`},
+		{name: "optional missing token", config: BearerConfig{Validate: validate, Optional: true}, expectedStatus: http.StatusOK, expectedBody: "anonymous"},
+		{name: "optional empty token", config: BearerConfig{Validate: validate, Optional: true}, authorization: "Bearer ", expectedStatus: http.StatusUnauthorized},
+		{name: "realm is quoted", config: BearerConfig{Realm: `my "api"`, Validate: validate}, expectedStatus: http.StatusUnauthorized, expectedChallenge: `Bearer realm="my \"api\""`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := authRouter(Bearer(tt.config))
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			router.ServerHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Fatalf("expected %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if tt.expectedBody != "" && rec.Body.String() != tt.expectedBody {
+				t.Errorf("expected body %q, got %q", tt.expectedBody, rec.Body.String())
+			}
+			if tt.expectedChallenge != "" && rec.Header().Get("WWW-Authenticate") != tt.expectedChallenge {
+				t.Errorf("expected challenge %q, got %q", tt.expectedChallenge, rec.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
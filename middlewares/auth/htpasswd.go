@@ -0,0 +1,129 @@
+package auth
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	goapi "github.com/carlosealves2/go-api"
+)
+
+// Htpasswd holds the users of an htpasswd file, as created by Apache's htpasswd tool with the -B (bcrypt)
+// or -s (SHA-1) option. It is safe for concurrent use, and Reload re-reads the file at runtime.
+type Htpasswd struct {
+	path  string
+	mu    sync.RWMutex
+	users map[string]string
+	dummy string
+}
+
+// LoadHtpasswd reads an htpasswd file.
+//
+// Returns:
+// - *Htpasswd: The users of the file. Its Validate method is a BasicValidator.
+// - error: An error if the file cannot be read, or if a line is malformed or uses an unsupported hash
+// (only bcrypt and SHA-1 are supported).
+func LoadHtpasswd(path string) (*Htpasswd, error) {
+	h := &Htpasswd{path: path}
+	if err := h.Reload(); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// ParseHtpasswd reads users in the htpasswd format from r. The result cannot be reloaded.
+func ParseHtpasswd(r io.Reader) (*Htpasswd, error) {
+	users, dummy, err := parseHtpasswd(r)
+	if err != nil {
+		return nil, err
+	}
+	return &Htpasswd{users: users, dummy: dummy}, nil
+}
+
+// Reload re-reads the file the users were loaded from. The users are unchanged if it fails.
+func (h *Htpasswd) Reload() error {
+	if h.path == "" {
+		return fmt.Errorf("auth: htpasswd users were not loaded from a file")
+	}
+	file, err := os.Open(h.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	users, dummy, err := parseHtpasswd(file)
+	if err != nil {
+		return fmt.Errorf("%s: %w", h.path, err)
+	}
+	h.mu.Lock()
+	h.users, h.dummy = users, dummy
+	h.mu.Unlock()
+	return nil
+}
+
+// Authenticate reports whether the password of the user matches.
+func (h *Htpasswd) Authenticate(username, password string) bool {
+	h.mu.RLock()
+	hash, ok := h.users[username]
+	dummy := h.dummy
+	h.mu.RUnlock()
+
+	if !ok {
+		// Hash the password anyway so that unknown users cannot be told apart by timing.
+		CheckPassword(dummy, password)
+		return false
+	}
+	return CheckPassword(hash, password)
+}
+
+// Validate is a BasicValidator authenticating the users of the file.
+func (h *Htpasswd) Validate(r *http.Request, username, password string) (*goapi.Principal, error) {
+	if !h.Authenticate(username, password) {
+		return nil, ErrInvalidCredentials
+	}
+	return &goapi.Principal{ID: username, Scheme: "basic"}, nil
+}
+
+// parseHtpasswd parses "user:hash" lines, ignoring blank lines and comments. It also returns the hash to
+// check passwords of unknown users against, so that they cost as much as those of the users: the first
+// bcrypt hash of the file, else its first hash, or dummyHash for a file without users.
+func parseHtpasswd(r io.Reader) (map[string]string, string, error) {
+	users := make(map[string]string)
+	dummy, first := "", ""
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		username, hash, ok := strings.Cut(text, ":")
+		if !ok || username == "" {
+			return nil, "", fmt.Errorf("auth: htpasswd line %d: missing user name or hash", line)
+		}
+		if !validHash(hash) {
+			return nil, "", fmt.Errorf("auth: htpasswd line %d: unsupported hash for user %q", line, username)
+		}
+		users[username] = hash
+		if first == "" {
+			first = hash
+		}
+		if dummy == "" && isBcrypt(hash) {
+			dummy = hash
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, "", err
+	}
+	switch {
+	case dummy != "":
+	case first != "":
+		dummy = first
+	default:
+		dummy = dummyHash
+	}
+	return users, dummy, nil
+}
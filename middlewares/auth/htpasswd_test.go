@@ -0,0 +1,152 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const htpasswdFile = `# users
+alice:$2b$04$abcdefghijklmnopqrstuuLZYjhNQAOdpbzt4WxWlUHjv1wsyH5DG
+
+legacy:{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=
+`
+
+func TestHtpasswd(t *testing.T) {
+	users, err := ParseHtpasswd(strings.NewReader(htpasswdFile))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		username string
+		password string
+		expected bool
+	}{
+		{name: "bcrypt user", username: "alice", password: "s3cret", expected: true},
+		{name: "sha1 user", username: "legacy", password: "password", expected: true},
+		{name: "wrong password", username: "alice", password: "password", expected: false},
+		{name: "unknown user", username: "mallory", password: "s3cret", expected: false},
+		{name: "comment is not a user", username: "# users", password: "", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := users.Authenticate(tt.username, tt.password); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+
+	if err := users.Reload(); err == nil {
+		t.Errorf("expected an error reloading parsed users")
+	}
+}
+
+func TestHtpasswdDummyHash(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "first bcrypt hash", input: "legacy:{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=\nalice:$2b$04$abcdefghijklmnopqrstuuLZYjhNQAOdpbzt4WxWlUHjv1wsyH5DG\n", expected: "$2b$04$abcdefghijklmnopqrstuuLZYjhNQAOdpbzt4WxWlUHjv1wsyH5DG"},
+		{name: "sha1 only", input: "legacy:{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=\n", expected: "{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g="},
+		{name: "no users", input: "# empty\n", expected: dummyHash},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users, err := ParseHtpasswd(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if users.dummy != tt.expected {
+				t.Errorf("expected dummy hash %q, got %q", tt.expected, users.dummy)
+			}
+			if users.Authenticate("mallory", "") {
+				t.Errorf("expected unknown users to be rejected")
+			}
+		})
+	}
+}
+
+func TestParseHtpasswdErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{name: "missing hash", input: "alice\n"},
+		{name: "missing user", input: ":{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=\n"},
+		{name: "plain text password", input: "alice:s3cret\n"},
+		{name: "md5 hash", input: "alice:$apr1$salt$hash\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseHtpasswd(strings.NewReader(tt.input)); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
+
+func TestLoadHtpasswd(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "htpasswd")
+	if err := os.WriteFile(path, []byte(htpasswdFile), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	users, err := LoadHtpasswd(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	router := authRouter(Basic(BasicConfig{Validate: users.Validate}))
+
+	request := func(username, password string) int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.SetBasicAuth(username, password)
+		rec := httptest.NewRecorder()
+		router.ServerHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := request("alice", "s3cret"); code != http.StatusOK {
+		t.Errorf("expected 200, got %d", code)
+	}
+
+	// alice is removed and bob added.
+	hash, err := HashPassword("hunter2", MinBcryptCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("bob:"+hash+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := users.Reload(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if code := request("alice", "s3cret"); code != http.StatusUnauthorized {
+		t.Errorf("expected 401 for a removed user, got %d", code)
+	}
+	if code := request("bob", "hunter2"); code != http.StatusOK {
+		t.Errorf("expected 200 for an added user, got %d", code)
+	}
+
+	// A broken file keeps the previous users.
+	if err := os.WriteFile(path, []byte("bob\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := users.Reload(); err == nil {
+		t.Errorf("expected an error reloading a malformed file")
+	}
+	if code := request("bob", "hunter2"); code != http.StatusOK {
+		t.Errorf("expected the previous users to be kept, got %d", code)
+	}
+
+	if _, err := LoadHtpasswd(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Errorf("expected an error for a missing file")
+	}
+}
@@ -0,0 +1,344 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	goapi "github.com/carlosealves2/go-api"
+)
+
+// jwksFetchTimeout bounds the fetches triggered by requests, which do not use the request context.
+const jwksFetchTimeout = 10 * time.Second
+
+// JWKSConfig configures a key set fetched from a URL by NewRemoteJWKS.
+type JWKSConfig struct {
+	// URL is the address of the JSON Web Key Set, such as the jwks_uri of an OpenID Connect provider.
+	URL string
+	// Client fetches the key set. Defaults to a client with a 10 second timeout.
+	Client *http.Client
+	// RefreshInterval is how often the key set is fetched again. Defaults to 1 hour.
+	RefreshInterval time.Duration
+	// MinRefreshInterval is the shortest time between two fetch attempts, whether they are due to
+	// RefreshInterval, to a failed fetch or to a token signed with an unknown key, which usually means the
+	// keys were rotated. Defaults to 1 minute.
+	MinRefreshInterval time.Duration
+}
+
+// JWKS is a JSON Web Key Set (RFC 7517) holding the keys that verify JWT signatures, indexed by key ID
+// ("kid"). Key sets loaded from a file or a URL are reloaded periodically, and as soon as a token signed
+// with an unknown key ID is received, so that keys can be rotated without a restart. It is safe for
+// concurrent use.
+//
+// Supported keys are RSA, EC on the P-256 curve and symmetric ("oct") keys. Other keys of the set are
+// ignored.
+type JWKS struct {
+	fetch              func(ctx context.Context) ([]byte, error)
+	refreshInterval    time.Duration
+	minRefreshInterval time.Duration
+
+	// refreshing serializes fetches, mu protects the fields below it. fetched is the time of the last
+	// successful fetch, attempted the time of the last fetch, and err the error of the last fetch.
+	refreshing sync.Mutex
+	mu         sync.RWMutex
+	keys       map[string]*jwk
+	fetched    time.Time
+	attempted  time.Time
+	err        error
+}
+
+// jwk is a parsed key of the set.
+type jwk struct {
+	// alg is the algorithm the key is restricted to, if any.
+	alg string
+	// key is a []byte, an *rsa.PublicKey or an *ecdsa.PublicKey.
+	key any
+}
+
+// rawJWK is the JSON representation of a key.
+type rawJWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	K   string `json:"k"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// ParseJWKS parses a JSON Web Key Set. The result never changes.
+//
+// Example:
+//
+//	keys, err := auth.ParseJWKS([]byte(os.Getenv("JWKS")))
+func ParseJWKS(data []byte) (*JWKS, error) {
+	keys, err := parseJWKS(data)
+	if err != nil {
+		return nil, err
+	}
+	return &JWKS{keys: keys}, nil
+}
+
+// LoadJWKS reads a JSON Web Key Set from a file. The file is read again every hour, and when a token
+// references an unknown key ID (at most once a minute).
+//
+// Returns:
+// - *JWKS: The key set.
+// - error: An error if the file cannot be read or parsed.
+func LoadJWKS(path string) (*JWKS, error) {
+	s := &JWKS{
+		fetch: func(ctx context.Context) ([]byte, error) {
+			return os.ReadFile(path)
+		},
+		refreshInterval:    time.Hour,
+		minRefreshInterval: time.Minute,
+	}
+	if err := s.Refresh(context.Background()); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// NewRemoteJWKS returns a key set fetched from a URL. The keys are fetched on first use, then every
+// RefreshInterval and when a token references an unknown key ID, at most once every MinRefreshInterval.
+// When a fetch fails, the previous keys keep being used until the next attempt; requests arriving before
+// the first successful fetch are answered with 503 Service Unavailable.
+//
+// Example:
+//
+//	keys := auth.NewRemoteJWKS(auth.JWKSConfig{URL: "https://login.example.com/.well-known/jwks.json"})
+//	api.Use(auth.JWT(auth.JWTConfig{Keys: keys, Issuer: "https://login.example.com/", Audience: "orders-api"}))
+func NewRemoteJWKS(config JWKSConfig) *JWKS {
+	if config.URL == "" {
+		panic("auth: NewRemoteJWKS requires a URL")
+	}
+	if config.Client == nil {
+		config.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if config.RefreshInterval <= 0 {
+		config.RefreshInterval = time.Hour
+	}
+	if config.MinRefreshInterval <= 0 {
+		config.MinRefreshInterval = time.Minute
+	}
+
+	return &JWKS{
+		fetch: func(ctx context.Context) ([]byte, error) {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, config.URL, nil)
+			if err != nil {
+				return nil, err
+			}
+			req.Header.Set("Accept", "application/json")
+			resp, err := config.Client.Do(req)
+			if err != nil {
+				return nil, err
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				return nil, fmt.Errorf("auth: fetching %s: unexpected status %s", config.URL, resp.Status)
+			}
+			return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		},
+		refreshInterval:    config.RefreshInterval,
+		minRefreshInterval: config.MinRefreshInterval,
+	}
+}
+
+// Refresh loads the key set again from its file or URL. The keys are unchanged if it fails.
+// It does nothing for key sets created by ParseJWKS.
+func (s *JWKS) Refresh(ctx context.Context) error {
+	if s.fetch == nil {
+		return nil
+	}
+	s.refreshing.Lock()
+	defer s.refreshing.Unlock()
+	return s.refresh(ctx)
+}
+
+func (s *JWKS) refresh(ctx context.Context) error {
+	keys, err := s.load(ctx)
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attempted, s.err = now, err
+	if err == nil {
+		s.keys, s.fetched = keys, now
+	}
+	return err
+}
+
+// load fetches and parses the key set.
+func (s *JWKS) load(ctx context.Context) (map[string]*jwk, error) {
+	data, err := s.fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return parseJWKS(data)
+}
+
+// jwksState is a snapshot of the keys of a set and of its last fetches.
+type jwksState struct {
+	keys      map[string]*jwk
+	fetched   time.Time
+	attempted time.Time
+	err       error
+}
+
+// snapshot returns the current keys and the outcome of the last fetches.
+func (s *JWKS) snapshot() jwksState {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return jwksState{keys: s.keys, fetched: s.fetched, attempted: s.attempted, err: s.err}
+}
+
+// canRefresh reports whether MinRefreshInterval has passed since the last fetch attempt.
+func (s *JWKS) canRefresh(state jwksState) bool {
+	return state.attempted.IsZero() || time.Since(state.attempted) >= s.minRefreshInterval
+}
+
+// refreshIf fetches the keys unless another goroutine already tried after since, in which case it returns
+// the outcome of that attempt. With wait false, it returns immediately if another goroutine is fetching.
+func (s *JWKS) refreshIf(ctx context.Context, since time.Time, wait bool) error {
+	if !wait {
+		if !s.refreshing.TryLock() {
+			return nil
+		}
+	} else {
+		s.refreshing.Lock()
+	}
+	defer s.refreshing.Unlock()
+	if state := s.snapshot(); state.attempted.After(since) {
+		return state.err
+	}
+	// The attempt is shared by every request and limits the next ones, so it must not fail because the
+	// client of the request that triggered it went away.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), jwksFetchTimeout)
+	defer cancel()
+	return s.refresh(ctx)
+}
+
+// key returns the key with the given ID, refreshing the set when it is stale or the ID is unknown.
+// Fetches are attempted at most once every MinRefreshInterval, successful or not; meanwhile the last keys
+// are used. A token without a key ID uses the only key of the set.
+func (s *JWKS) key(ctx context.Context, kid string) (*jwk, error) {
+	state := s.snapshot()
+	if s.fetch != nil && (state.keys == nil || time.Since(state.fetched) >= s.refreshInterval) {
+		if s.canRefresh(state) {
+			// Stale keys are still usable, so requests do not queue behind a fetch in progress.
+			_ = s.refreshIf(ctx, state.attempted, state.keys == nil)
+			state = s.snapshot()
+		}
+		if state.keys == nil {
+			return nil, &goapi.HTTPError{Status: http.StatusServiceUnavailable, Message: "key set unavailable", Err: state.err}
+		}
+	}
+
+	k, ok := lookupKey(state.keys, kid)
+	if !ok && s.fetch != nil && s.canRefresh(state) {
+		// Keys may have been rotated since the last fetch. A failed fetch is not fatal: the token is
+		// rejected for its unknown key.
+		if s.refreshIf(ctx, state.attempted, true) == nil {
+			k, ok = lookupKey(s.snapshot().keys, kid)
+		}
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown key %q", ErrInvalidCredentials, kid)
+	}
+	return k, nil
+}
+
+func lookupKey(keys map[string]*jwk, kid string) (*jwk, bool) {
+	if k, ok := keys[kid]; ok {
+		return k, true
+	}
+	if kid == "" && len(keys) == 1 {
+		for _, k := range keys {
+			return k, true
+		}
+	}
+	return nil, false
+}
+
+// parseJWKS parses the keys of a set, skipping keys that cannot verify signatures.
+func parseJWKS(data []byte) (map[string]*jwk, error) {
+	var set struct {
+		Keys []rawJWK `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("auth: invalid JWKS: %w", err)
+	}
+
+	keys := make(map[string]*jwk, len(set.Keys))
+	for _, raw := range set.Keys {
+		if raw.Use != "" && raw.Use != "sig" {
+			continue
+		}
+		key, err := raw.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("auth: invalid JWKS key %q: %w", raw.Kid, err)
+		}
+		if key != nil {
+			keys[raw.Kid] = &jwk{alg: raw.Alg, key: key}
+		}
+	}
+	return keys, nil
+}
+
+// publicKey returns the key that verifies signatures, or nil for unsupported key types.
+func (raw rawJWK) publicKey() (any, error) {
+	switch raw.Kty {
+	case "oct":
+		k, err := decodeSegment(raw.K)
+		if err != nil || len(k) == 0 {
+			return nil, fmt.Errorf("invalid symmetric key")
+		}
+		return k, nil
+
+	case "RSA":
+		n, errN := decodeSegment(raw.N)
+		e, errE := decodeSegment(raw.E)
+		if errN != nil || errE != nil || len(n) == 0 || len(e) == 0 || len(e) > 4 {
+			return nil, fmt.Errorf("invalid RSA key")
+		}
+		exponent := 0
+		for _, b := range e {
+			exponent = exponent<<8 | int(b)
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: exponent}, nil
+
+	case "EC":
+		if raw.Crv != "P-256" {
+			return nil, nil
+		}
+		x, errX := decodeSegment(raw.X)
+		y, errY := decodeSegment(raw.Y)
+		if errX != nil || errY != nil || len(x) != 32 || len(y) != 32 {
+			return nil, fmt.Errorf("invalid EC key")
+		}
+		// Validate that the point is on the curve.
+		if _, err := ecdh.P256().NewPublicKey(append(append([]byte{4}, x...), y...)); err != nil {
+			return nil, fmt.Errorf("invalid EC key: %w", err)
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	}
+	return nil, nil
+}
+
+// decodeSegment decodes base64url data without padding, as used by JWTs and JWKs.
+func decodeSegment(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(s)
+}
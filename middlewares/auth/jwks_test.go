@@ -0,0 +1,268 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	goapi "github.com/carlosealves2/go-api"
+)
+
+func TestParseJWKS(t *testing.T) {
+	tests := []struct {
+		name         string
+		input        string
+		expectedKeys int
+		expectError  bool
+	}{
+		{name: "symmetric key", input: `{"keys":[{"kty":"oct","kid":"a","k":"c2VjcmV0"}]}`, expectedKeys: 1},
+		{name: "unsupported keys are skipped", input: `{"keys":[{"kty":"OKP","kid":"a","crv":"Ed25519","x":"AA"},{"kty":"EC","kid":"b","crv":"P-384"}]}`, expectedKeys: 0},
+		{name: "encryption keys are skipped", input: `{"keys":[{"kty":"oct","kid":"a","use":"enc","k":"c2VjcmV0"}]}`, expectedKeys: 0},
+		{name: "invalid json", input: `{"keys":`, expectError: true},
+		{name: "invalid symmetric key", input: `{"keys":[{"kty":"oct","kid":"a","k":"!"}]}`, expectError: true},
+		{name: "invalid RSA key", input: `{"keys":[{"kty":"RSA","kid":"a","n":"","e":"AQAB"}]}`, expectError: true},
+		{name: "EC point off the curve", input: `{"keys":[{"kty":"EC","kid":"a","crv":"P-256","x":"AQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQE","y":"AQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQE"}]}`, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := ParseJWKS([]byte(tt.input))
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(keys.keys) != tt.expectedKeys {
+				t.Errorf("expected %d keys, got %d", tt.expectedKeys, len(keys.keys))
+			}
+		})
+	}
+}
+
+func TestRemoteJWKSRotation(t *testing.T) {
+	oldKey, newKey := ecKey(t), ecKey(t)
+	var current atomic.Pointer[[]byte]
+	set := jwksJSON(map[string]any{"old": oldKey})
+	current.Store(&set)
+
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		w.Write(*current.Load())
+	}))
+	defer server.Close()
+
+	keys := NewRemoteJWKS(JWKSConfig{URL: server.URL, Client: server.Client(), MinRefreshInterval: time.Millisecond})
+	verifier, err := NewJWTVerifier(JWTConfig{Keys: keys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	verify := func(kid string, key any) error {
+		_, err := verifier.Verify(context.Background(), signToken(t, "ES256", kid, key, map[string]any{"sub": "alice"}))
+		return err
+	}
+
+	if fetches.Load() != 0 {
+		t.Errorf("expected the key set to be fetched lazily")
+	}
+	if err := verify("old", oldKey); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := verify("old", oldKey); err != nil || fetches.Load() != 1 {
+		t.Fatalf("expected the key set to be cached, got %d fetches and %v", fetches.Load(), err)
+	}
+
+	// The provider rotates its keys: a token with the new key ID triggers a refresh.
+	set = jwksJSON(map[string]any{"new": newKey})
+	current.Store(&set)
+	time.Sleep(2 * time.Millisecond)
+	if err := verify("new", newKey); err != nil {
+		t.Fatalf("expected the rotated key to be fetched, got %v", err)
+	}
+	if fetches.Load() != 2 {
+		t.Errorf("expected 2 fetches, got %d", fetches.Load())
+	}
+	if err := verify("old", oldKey); err == nil {
+		t.Errorf("expected the retired key to be rejected")
+	}
+}
+
+func TestRemoteJWKSUnavailable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	keys := NewRemoteJWKS(JWKSConfig{URL: server.URL, Client: server.Client()})
+	router := authRouter(JWT(JWTConfig{Keys: keys}))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+signToken(t, "ES256", "k", ecKey(t), map[string]any{"sub": "alice"}))
+	rec := httptest.NewRecorder()
+	router.ServerHTTP(rec, req)
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 while the key set is unavailable, got %d", rec.Code)
+	}
+}
+
+// ageJWKS moves the fetches of the key set d into the past.
+func ageJWKS(s *JWKS, d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fetched, s.attempted = s.fetched.Add(-d), s.attempted.Add(-d)
+}
+
+// flakyJWKS returns a key set with one symmetric key "a", whose fetches fail while failing is set.
+func flakyJWKS(fetches *atomic.Int32, failing *atomic.Bool) *JWKS {
+	return &JWKS{
+		fetch: func(ctx context.Context) ([]byte, error) {
+			fetches.Add(1)
+			if failing.Load() {
+				return nil, errors.New("provider down")
+			}
+			return []byte(`{"keys":[{"kty":"oct","kid":"a","k":"c2VjcmV0"}]}`), nil
+		},
+		refreshInterval:    time.Hour,
+		minRefreshInterval: time.Minute,
+	}
+}
+
+func TestRemoteJWKSFailingFetchIsRateLimited(t *testing.T) {
+	var fetches atomic.Int32
+	var failing atomic.Bool
+	failing.Store(true)
+	keys := flakyJWKS(&fetches, &failing)
+
+	for i := 0; i < 3; i++ {
+		_, err := keys.key(context.Background(), "a")
+		var httpErr *goapi.HTTPError
+		if !errors.As(err, &httpErr) || httpErr.Status != http.StatusServiceUnavailable || httpErr.Err == nil {
+			t.Fatalf("expected 503 with the fetch error, got %v", err)
+		}
+	}
+	if fetches.Load() != 1 {
+		t.Errorf("expected a failed fetch not to be retried before MinRefreshInterval, got %d fetches", fetches.Load())
+	}
+
+	failing.Store(false)
+	ageJWKS(keys, 2*time.Minute)
+	if _, err := keys.key(context.Background(), "a"); err != nil || fetches.Load() != 2 {
+		t.Errorf("expected the fetch to be retried after MinRefreshInterval, got %d fetches and %v", fetches.Load(), err)
+	}
+}
+
+func TestRemoteJWKSServesStaleKeys(t *testing.T) {
+	var fetches atomic.Int32
+	var failing atomic.Bool
+	keys := flakyJWKS(&fetches, &failing)
+	lookup := func(kid string) error {
+		_, err := keys.key(context.Background(), kid)
+		return err
+	}
+
+	if err := lookup("a"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The periodic refresh fails: the stale keys are used, and the fetch is not retried on every request.
+	failing.Store(true)
+	ageJWKS(keys, 2*time.Hour)
+	for i := 0; i < 3; i++ {
+		if err := lookup("a"); err != nil {
+			t.Fatalf("expected the stale key to be used, got %v", err)
+		}
+	}
+	if err := lookup("unknown"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("expected an unknown key to be rejected, got %v", err)
+	}
+	if fetches.Load() != 2 {
+		t.Errorf("expected 2 fetches, got %d", fetches.Load())
+	}
+
+	// An unknown key triggers a fetch once MinRefreshInterval has passed since the failed attempt.
+	ageJWKS(keys, 2*time.Minute)
+	if err := lookup("unknown"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("expected an unknown key to be rejected, got %v", err)
+	}
+	if err := lookup("a"); err != nil || fetches.Load() != 3 {
+		t.Errorf("expected the stale key without another fetch, got %d fetches and %v", fetches.Load(), err)
+	}
+
+	failing.Store(false)
+	ageJWKS(keys, 2*time.Minute)
+	if err := lookup("a"); err != nil || fetches.Load() != 4 {
+		t.Errorf("expected the keys to be refreshed, got %d fetches and %v", fetches.Load(), err)
+	}
+	if state := keys.snapshot(); time.Since(state.fetched) > time.Minute || state.err != nil {
+		t.Errorf("expected a successful fetch to be recorded, got %+v", state)
+	}
+}
+
+func TestRemoteJWKSCanceledRequest(t *testing.T) {
+	var fetches atomic.Int32
+	keys := &JWKS{
+		fetch: func(ctx context.Context) ([]byte, error) {
+			fetches.Add(1)
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			return []byte(`{"keys":[{"kty":"oct","kid":"a","k":"c2VjcmV0"}]}`), nil
+		},
+		refreshInterval:    time.Hour,
+		minRefreshInterval: time.Minute,
+	}
+
+	// The client of the request triggering the first fetch went away.
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := keys.key(canceled, "a"); err != nil {
+		t.Errorf("expected the fetch to ignore the canceled request, got %v", err)
+	}
+	if _, err := keys.key(context.Background(), "a"); err != nil || fetches.Load() != 1 {
+		t.Errorf("expected the next request to use the fetched keys, got %d fetches and %v", fetches.Load(), err)
+	}
+}
+
+func TestLoadJWKS(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	key := rsaKey(t)
+	if err := os.WriteFile(path, jwksJSON(map[string]any{"k1": key}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := LoadJWKS(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	verifier, err := NewJWTVerifier(JWTConfig{Keys: keys, Algorithms: []string{"RS256"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verifier.Verify(context.Background(), signToken(t, "RS256", "k1", key, map[string]any{"sub": "alice"})); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// A broken file keeps the previous keys.
+	if err := os.WriteFile(path, []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := keys.Refresh(context.Background()); err == nil {
+		t.Errorf("expected an error refreshing a malformed file")
+	}
+	if _, err := verifier.Verify(context.Background(), signToken(t, "RS256", "k1", key, map[string]any{"sub": "alice"})); err != nil {
+		t.Errorf("expected the previous keys to be kept, got %v", err)
+	}
+
+	if _, err := LoadJWKS(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Errorf("expected an error for a missing file")
+	}
+}
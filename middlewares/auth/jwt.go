@@ -0,0 +1,323 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"math"
+	"math/big"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	goapi "github.com/carlosealves2/go-api"
+)
+
+// Claims are the claims of a verified JWT, decoded from JSON: numbers are float64 and arrays are []any.
+type Claims map[string]any
+
+// String returns a string claim, or "" when it is missing or not a string.
+func (c Claims) String(name string) string {
+	s, _ := c[name].(string)
+	return s
+}
+
+// Strings returns a claim holding a string or an array of strings.
+func (c Claims) Strings(name string) []string {
+	switch v := c[name].(type) {
+	case string:
+		return []string{v}
+	case []any:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// Time returns a NumericDate claim such as "exp", "nbf" or "iat".
+// The second return value is false when the claim is missing or not a number.
+func (c Claims) Time(name string) (time.Time, bool) {
+	seconds, ok := c[name].(float64)
+	if !ok {
+		return time.Time{}, false
+	}
+	whole, frac := math.Modf(seconds)
+	return time.Unix(int64(whole), int64(frac*1e9)), true
+}
+
+// Subject returns the "sub" claim.
+func (c Claims) Subject() string {
+	return c.String("sub")
+}
+
+// Scopes returns the OAuth scopes of the token, from the space-separated "scope" claim or the "scp" array.
+func (c Claims) Scopes() []string {
+	if scope := c.String("scope"); scope != "" {
+		return strings.Fields(scope)
+	}
+	return c.Strings("scp")
+}
+
+// JWTConfig configures JWT verification.
+type JWTConfig struct {
+	// Keys verifies tokens by their key ID ("kid" header). Exactly one of Keys and Secret is required.
+	Keys *JWKS
+	// Secret is the HMAC key of tokens signed with HS256, HS384 or HS512.
+	Secret []byte
+	// Algorithms lists the accepted signature algorithms, among HS256, HS384, HS512, RS256 and ES256.
+	// Defaults to the HMAC algorithms with Secret, and to all of them with Keys. Keys must also be of the
+	// type of the algorithm, so a public RSA key can never be used as an HMAC secret.
+	Algorithms []string
+	// Issuer is the required "iss" claim. Empty accepts any issuer.
+	Issuer string
+	// Audience must be one of the "aud" claim values. Empty accepts any audience.
+	Audience string
+	// Leeway is the clock skew tolerated when checking the "exp" and "nbf" claims.
+	Leeway time.Duration
+	// RolesClaim is the claim listing the roles of the principal. Defaults to "roles".
+	RolesClaim string
+	// Realm is the protection space announced to clients. Defaults to "Restricted".
+	Realm string
+	// Optional lets requests without a bearer token through unauthenticated. Invalid tokens are still
+	// rejected.
+	Optional bool
+}
+
+// JWTVerifier verifies JSON Web Tokens (RFC 7519) signed with a secret or with the keys of a JWKS.
+type JWTVerifier struct {
+	config     JWTConfig
+	algorithms []string
+}
+
+// jwtAlgorithm describes a supported signature algorithm.
+type jwtAlgorithm struct {
+	hash crypto.Hash
+	new  func() hash.Hash
+	// kind is the key type: "oct", "RSA" or "EC".
+	kind string
+}
+
+var jwtAlgorithms = map[string]jwtAlgorithm{
+	"HS256": {hash: crypto.SHA256, new: sha256.New, kind: "oct"},
+	"HS384": {hash: crypto.SHA384, new: sha512.New384, kind: "oct"},
+	"HS512": {hash: crypto.SHA512, new: sha512.New, kind: "oct"},
+	"RS256": {hash: crypto.SHA256, new: sha256.New, kind: "RSA"},
+	"ES256": {hash: crypto.SHA256, new: sha256.New, kind: "EC"},
+}
+
+// NewJWTVerifier creates a verifier.
+//
+// Returns:
+// - *JWTVerifier: The verifier. Its Validate method is a TokenValidator for Bearer.
+// - error: An error if neither or both of Keys and Secret are set, or if an algorithm is not supported.
+func NewJWTVerifier(config JWTConfig) (*JWTVerifier, error) {
+	if (config.Keys == nil) == (len(config.Secret) == 0) {
+		return nil, fmt.Errorf("auth: JWT verification requires either Keys or Secret")
+	}
+	algorithms := config.Algorithms
+	if len(algorithms) == 0 {
+		algorithms = []string{"HS256", "HS384", "HS512"}
+		if config.Keys != nil {
+			algorithms = append(algorithms, "RS256", "ES256")
+		}
+	}
+	for _, alg := range algorithms {
+		spec, ok := jwtAlgorithms[alg]
+		if !ok {
+			return nil, fmt.Errorf("auth: unsupported JWT algorithm %q", alg)
+		}
+		if config.Keys == nil && spec.kind != "oct" {
+			return nil, fmt.Errorf("auth: JWT algorithm %s requires Keys", alg)
+		}
+	}
+	if config.RolesClaim == "" {
+		config.RolesClaim = "roles"
+	}
+	return &JWTVerifier{config: config, algorithms: algorithms}, nil
+}
+
+// JWT returns a middleware authenticating requests by a JWT bearer token. The principal stored in the
+// context has the "sub" claim as ID, the "jwt" scheme, the roles of the RolesClaim claim, the scopes of
+// the "scope" or "scp" claim, and all the claims as Attributes; handlers read the claims with
+// ClaimsFromContext. Invalid tokens are answered with 401 Unauthorized. It panics if the configuration is
+// invalid.
+//
+// Example:
+//
+//	api.Use(auth.JWT(auth.JWTConfig{
+//		Secret:   []byte(os.Getenv("JWT_SECRET")),
+//		Issuer:   "https://login.example.com/",
+//		Audience: "orders-api",
+//		Leeway:   30 * time.Second,
+//	}))
+func JWT(config JWTConfig) goapi.MiddlewareFunc {
+	verifier, err := NewJWTVerifier(config)
+	if err != nil {
+		panic(err)
+	}
+	return Bearer(BearerConfig{Realm: config.Realm, Validate: verifier.Validate, Optional: config.Optional})
+}
+
+// ClaimsFromContext returns the claims of the JWT that authenticated the request.
+// The second return value is false when the request was not authenticated by the JWT middleware.
+//
+// Example:
+//
+//	claims, _ := auth.ClaimsFromContext(r)
+//	tenant := claims.String("tenant")
+func ClaimsFromContext(r *http.Request) (Claims, bool) {
+	principal, ok := goapi.PrincipalFromContext(r)
+	if !ok || principal.Scheme != "jwt" {
+		return nil, false
+	}
+	return Claims(principal.Attributes), true
+}
+
+// Validate is a TokenValidator verifying JWTs.
+func (v *JWTVerifier) Validate(r *http.Request, token string) (*goapi.Principal, error) {
+	claims, err := v.Verify(r.Context(), token)
+	if err != nil {
+		return nil, err
+	}
+	return &goapi.Principal{
+		ID:         claims.Subject(),
+		Scheme:     "jwt",
+		Roles:      claims.Strings(v.config.RolesClaim),
+		Scopes:     claims.Scopes(),
+		Attributes: claims,
+	}, nil
+}
+
+// Verify checks the signature and the registered claims of a token, and returns its claims.
+// Invalid tokens are reported with errors wrapping ErrInvalidCredentials.
+func (v *JWTVerifier) Verify(ctx context.Context, token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, invalidToken("malformed token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if data, err := decodeSegment(parts[0]); err != nil || json.Unmarshal(data, &header) != nil {
+		return nil, invalidToken("malformed header")
+	}
+	if !slices.Contains(v.algorithms, header.Alg) {
+		return nil, invalidToken("algorithm %q not accepted", header.Alg)
+	}
+	signature, err := decodeSegment(parts[2])
+	if err != nil {
+		return nil, invalidToken("malformed signature")
+	}
+
+	key, err := v.key(ctx, header.Alg, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if !verifySignature(jwtAlgorithms[header.Alg], key, []byte(parts[0]+"."+parts[1]), signature) {
+		return nil, invalidToken("invalid signature")
+	}
+
+	var claims Claims
+	if data, err := decodeSegment(parts[1]); err != nil || json.Unmarshal(data, &claims) != nil || claims == nil {
+		return nil, invalidToken("malformed claims")
+	}
+	if err := v.checkClaims(claims, time.Now()); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// key returns the key verifying a token, checking that it suits the algorithm.
+func (v *JWTVerifier) key(ctx context.Context, alg, kid string) (any, error) {
+	if v.config.Keys == nil {
+		return v.config.Secret, nil
+	}
+	k, err := v.config.Keys.key(ctx, kid)
+	if err != nil {
+		return nil, err
+	}
+	if k.alg != "" && k.alg != alg {
+		return nil, invalidToken("key %q is not for %s", kid, alg)
+	}
+	return k.key, nil
+}
+
+// checkClaims checks the registered claims of a token.
+func (v *JWTVerifier) checkClaims(claims Claims, now time.Time) error {
+	expires, ok := claims.Time("exp")
+	if _, present := claims["exp"]; present && !ok {
+		return invalidToken("malformed exp claim")
+	}
+	if ok && !now.Before(expires.Add(v.config.Leeway)) {
+		return invalidToken("token expired")
+	}
+
+	notBefore, ok := claims.Time("nbf")
+	if _, present := claims["nbf"]; present && !ok {
+		return invalidToken("malformed nbf claim")
+	}
+	if ok && now.Add(v.config.Leeway).Before(notBefore) {
+		return invalidToken("token not valid yet")
+	}
+
+	if v.config.Issuer != "" && claims.String("iss") != v.config.Issuer {
+		return invalidToken("unexpected issuer")
+	}
+	if v.config.Audience != "" && !slices.Contains(claims.Strings("aud"), v.config.Audience) {
+		return invalidToken("unexpected audience")
+	}
+	return nil
+}
+
+// verifySignature checks the signature of the signed part of a token with a key of the algorithm's type.
+func verifySignature(alg jwtAlgorithm, key any, signed, signature []byte) bool {
+	switch key := key.(type) {
+	case []byte:
+		if alg.kind != "oct" {
+			return false
+		}
+		mac := hmac.New(alg.new, key)
+		mac.Write(signed)
+		return hmac.Equal(mac.Sum(nil), signature)
+
+	case *rsa.PublicKey:
+		if alg.kind != "RSA" {
+			return false
+		}
+		digest := alg.new()
+		digest.Write(signed)
+		return rsa.VerifyPKCS1v15(key, alg.hash, digest.Sum(nil), signature) == nil
+
+	case *ecdsa.PublicKey:
+		// JWS encodes ECDSA signatures as the fixed-size concatenation of r and s (RFC 7518).
+		if alg.kind != "EC" || len(signature) != 64 {
+			return false
+		}
+		digest := alg.new()
+		digest.Write(signed)
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		return ecdsa.Verify(key, digest.Sum(nil), r, s)
+	}
+	return false
+}
+
+// invalidToken returns an error wrapping ErrInvalidCredentials.
+func invalidToken(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrInvalidCredentials, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,348 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	goapi "github.com/carlosealves2/go-api"
+)
+
+var (
+	testRSAKeyOnce sync.Once
+	testRSAKey     *rsa.PrivateKey
+)
+
+// rsaKey returns an RSA key shared by the tests, since generating one is slow.
+func rsaKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	testRSAKeyOnce.Do(func() {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			panic(err)
+		}
+		testRSAKey = key
+	})
+	return testRSAKey
+}
+
+func ecKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func segment(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// signToken creates a JWT signed with key: a []byte, an *rsa.PrivateKey or an *ecdsa.PrivateKey.
+func signToken(t *testing.T, alg, kid string, key any, claims map[string]any) string {
+	t.Helper()
+	header := map[string]any{"alg": alg, "typ": "JWT"}
+	if kid != "" {
+		header["kid"] = kid
+	}
+	headerJSON, _ := json.Marshal(header)
+	claimsJSON, _ := json.Marshal(claims)
+	signed := segment(headerJSON) + "." + segment(claimsJSON)
+
+	spec := jwtAlgorithms[alg]
+	digest := spec.new()
+	digest.Write([]byte(signed))
+
+	var signature []byte
+	switch key := key.(type) {
+	case []byte:
+		mac := hmac.New(spec.new, key)
+		mac.Write([]byte(signed))
+		signature = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		var err error
+		signature, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest.Sum(nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, key, digest.Sum(nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		signature = make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+	}
+	return signed + "." + segment(signature)
+}
+
+// jwksJSON returns a key set holding the public keys, indexed by key ID.
+func jwksJSON(keys map[string]any) []byte {
+	var set []map[string]string
+	for kid, key := range keys {
+		switch key := key.(type) {
+		case []byte:
+			set = append(set, map[string]string{"kty": "oct", "kid": kid, "k": segment(key)})
+		case *rsa.PrivateKey:
+			set = append(set, map[string]string{
+				"kty": "RSA", "kid": kid, "use": "sig",
+				"n": segment(key.N.Bytes()), "e": segment([]byte{1, 0, 1}),
+			})
+		case *ecdsa.PrivateKey:
+			x, y := make([]byte, 32), make([]byte, 32)
+			key.X.FillBytes(x)
+			key.Y.FillBytes(y)
+			set = append(set, map[string]string{"kty": "EC", "kid": kid, "crv": "P-256", "x": segment(x), "y": segment(y)})
+		}
+	}
+	data, _ := json.Marshal(map[string]any{"keys": set})
+	return data
+}
+
+func TestJWTVerify(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	rsaPrivate := rsaKey(t)
+	ecPrivate := ecKey(t)
+	keys, err := ParseJWKS(jwksJSON(map[string]any{"hmac": secret, "rsa": rsaPrivate, "ec": ecPrivate}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	verifier, err := NewJWTVerifier(JWTConfig{
+		Keys:     keys,
+		Issuer:   "https://login.example.com/",
+		Audience: "orders-api",
+		Leeway:   30 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	now := time.Now().Unix()
+	claims := func(overrides map[string]any) map[string]any {
+		c := map[string]any{
+			"sub": "alice",
+			"iss": "https://login.example.com/",
+			"aud": []string{"billing-api", "orders-api"},
+			"exp": now + 60,
+		}
+		for name, value := range overrides {
+			if value == nil {
+				delete(c, name)
+			} else {
+				c[name] = value
+			}
+		}
+		return c
+	}
+	otherRSA, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		token         string
+		expectedError string
+	}{
+		{name: "HS256", token: signToken(t, "HS256", "hmac", secret, claims(nil))},
+		{name: "HS384", token: signToken(t, "HS384", "hmac", secret, claims(nil))},
+		{name: "HS512", token: signToken(t, "HS512", "hmac", secret, claims(nil))},
+		{name: "RS256", token: signToken(t, "RS256", "rsa", rsaPrivate, claims(nil))},
+		{name: "ES256", token: signToken(t, "ES256", "ec", ecPrivate, claims(nil))},
+		{name: "audience string", token: signToken(t, "HS256", "hmac", secret, claims(map[string]any{"aud": "orders-api"}))},
+		{name: "no expiration", token: signToken(t, "HS256", "hmac", secret, claims(map[string]any{"exp": nil}))},
+		{name: "expired within leeway", token: signToken(t, "HS256", "hmac", secret, claims(map[string]any{"exp": now - 10}))},
+		{name: "not before within leeway", token: signToken(t, "HS256", "hmac", secret, claims(map[string]any{"nbf": now + 10}))},
+		{name: "expired", token: signToken(t, "HS256", "hmac", secret, claims(map[string]any{"exp": now - 60})), expectedError: "token expired"},
+		{name: "not valid yet", token: signToken(t, "HS256", "hmac", secret, claims(map[string]any{"nbf": now + 60})), expectedError: "not valid yet"},
+		{name: "malformed exp", token: signToken(t, "HS256", "hmac", secret, claims(map[string]any{"exp": "tomorrow"})), expectedError: "malformed exp"},
+		{name: "wrong issuer", token: signToken(t, "HS256", "hmac", secret, claims(map[string]any{"iss": "https://evil.example.com/"})), expectedError: "issuer"},
+		{name: "wrong audience", token: signToken(t, "HS256", "hmac", secret, claims(map[string]any{"aud": "billing-api"})), expectedError: "audience"},
+		{name: "missing audience", token: signToken(t, "HS256", "hmac", secret, claims(map[string]any{"aud": nil})), expectedError: "audience"},
+		{name: "wrong key", token: signToken(t, "RS256", "rsa", otherRSA, claims(nil)), expectedError: "invalid signature"},
+		{name: "unknown key", token: signToken(t, "HS256", "other", secret, claims(nil)), expectedError: "unknown key"},
+		{name: "algorithm of another key type", token: signToken(t, "HS256", "rsa", secret, claims(nil)), expectedError: "invalid signature"},
+		{name: "none algorithm", token: segment([]byte(`{"alg":"none"}`)) + "." + segment([]byte(`{"sub":"alice"}`)) + ".", expectedError: "not accepted"},
+		{name: "tampered claims", token: tamper(signToken(t, "HS256", "hmac", secret, claims(nil))), expectedError: "invalid signature"},
+		{name: "malformed", token: "abc.def", expectedError: "malformed token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := verifier.Verify(context.Background(), tt.token)
+			if tt.expectedError == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if claims.Subject() != "alice" {
+					t.Errorf("expected subject alice, got %q", claims.Subject())
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.expectedError) {
+				t.Fatalf("expected error containing %q, got %v", tt.expectedError, err)
+			}
+			if !errors.Is(err, ErrInvalidCredentials) {
+				t.Errorf("expected the error to wrap ErrInvalidCredentials")
+			}
+		})
+	}
+}
+
+// tamper replaces the claims of a token, keeping its signature.
+func tamper(token string) string {
+	parts := strings.Split(token, ".")
+	parts[1] = segment([]byte(`{"sub":"alice","aud":"orders-api","iss":"https://login.example.com/","admin":true}`))
+	return strings.Join(parts, ".")
+}
+
+func TestNewJWTVerifierErrors(t *testing.T) {
+	keys, err := ParseJWKS([]byte(`{"keys":[]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		config JWTConfig
+	}{
+		{name: "no key", config: JWTConfig{}},
+		{name: "secret and keys", config: JWTConfig{Secret: []byte("s"), Keys: keys}},
+		{name: "unsupported algorithm", config: JWTConfig{Keys: keys, Algorithms: []string{"PS256"}}},
+		{name: "asymmetric algorithm with secret", config: JWTConfig{Secret: []byte("s"), Algorithms: []string{"RS256"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewJWTVerifier(tt.config); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
+
+func TestJWTAlgorithmRestriction(t *testing.T) {
+	secret := []byte("secret")
+	verifier, err := NewJWTVerifier(JWTConfig{Secret: secret, Algorithms: []string{"HS512"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verifier.Verify(context.Background(), signToken(t, "HS512", "", secret, map[string]any{"sub": "a"})); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := verifier.Verify(context.Background(), signToken(t, "HS256", "", secret, map[string]any{"sub": "a"})); err == nil {
+		t.Errorf("expected HS256 to be rejected")
+	}
+
+	// A key restricted to an algorithm cannot verify another one.
+	keys, err := ParseJWKS([]byte(fmt.Sprintf(`{"keys":[{"kty":"oct","kid":"k","alg":"HS256","k":%q}]}`, segment(secret))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier, err = NewJWTVerifier(JWTConfig{Keys: keys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verifier.Verify(context.Background(), signToken(t, "HS384", "k", secret, map[string]any{"sub": "a"})); err == nil {
+		t.Errorf("expected a key restricted to HS256 to reject HS384")
+	}
+}
+
+func TestJWTMiddleware(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	router := goapi.NewRouter()
+	router.Use(JWT(JWTConfig{Secret: secret, RolesClaim: "groups"}))
+	router.GET("/me", func(w http.ResponseWriter, r *http.Request) {
+		principal, _ := goapi.PrincipalFromContext(r)
+		claims, ok := ClaimsFromContext(r)
+		if !ok {
+			t.Errorf("expected claims in the context")
+		}
+		fmt.Fprintf(w, "%s %s %v %v %s", principal.ID, principal.Scheme, principal.Roles, principal.Scopes, claims.String("tenant"))
+	})
+
+	tests := []struct {
+		name           string
+		token          string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "valid token",
+			token:          signToken(t, "HS256", "", secret, map[string]any{"sub": "alice", "groups": []string{"admin"}, "scope": "orders:read orders:write", "tenant": "acme"}),
+			expectedStatus: http.StatusOK,
+			expectedBody:   "alice jwt [admin] [orders:read orders:write] acme",
+		},
+		{
+			name:           "scp claim",
+			token:          signToken(t, "HS256", "", secret, map[string]any{"sub": "bob", "scp": []string{"orders:read"}}),
+			expectedStatus: http.StatusOK,
+			expectedBody:   "bob jwt [] [orders:read] ",
+		},
+		{
+			name:           "invalid token",
+			token:          signToken(t, "HS256", "", []byte("other"), map[string]any{"sub": "alice"}),
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/me", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			rec := httptest.NewRecorder()
+			router.ServerHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Fatalf("expected %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if tt.expectedBody != "" && rec.Body.String() != tt.expectedBody {
+				t.Errorf("expected body %q, got %q", tt.expectedBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestClaimsFromContextOtherScheme(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req = goapi.WithPrincipal(req, &goapi.Principal{ID: "alice", Scheme: "basic", Attributes: map[string]any{"a": 1}})
+	if _, ok := ClaimsFromContext(req); ok {
+		t.Errorf("expected no claims for a principal authenticated without a JWT")
+	}
+}
+
+func TestClaims(t *testing.T) {
+	claims := Claims{"aud": []any{"a", 1, "b"}, "iss": "x", "exp": 1.7e9, "n": 1.0}
+	if got := claims.Strings("aud"); !slices.Equal(got, []string{"a", "b"}) {
+		t.Errorf("expected [a b], got %v", got)
+	}
+	if got := claims.Strings("iss"); !slices.Equal(got, []string{"x"}) {
+		t.Errorf("expected [x], got %v", got)
+	}
+	if got := claims.String("n"); got != "" {
+		t.Errorf("expected an empty string for a number, got %q", got)
+	}
+	if exp, ok := claims.Time("exp"); !ok || exp.Unix() != 1.7e9 {
+		t.Errorf("expected exp 1.7e9, got %v", exp)
+	}
+	if _, ok := claims.Time("iss"); ok {
+		t.Errorf("expected a string claim not to be a time")
+	}
+}
@@ -0,0 +1,597 @@
+package middlewares
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	goapi "github.com/carlosealves2/go-api"
+)
+
+// CacheKeyFunc computes the key under which the response to a request is cached.
+type CacheKeyFunc func(r *http.Request) string
+
+// CacheConfig configures a Cache.
+type CacheConfig struct {
+	// Store holds the cached responses. Defaults to an LRUCacheStore of 10,000 entries and 64 MiB.
+	Store CacheStore
+	// Key computes the cache key of a request. Defaults to DefaultCacheKey.
+	Key CacheKeyFunc
+	// RouteKeys overrides Key for the routes with the given names, typically with CacheKeyFromParams.
+	RouteKeys map[string]CacheKeyFunc
+	// DefaultTTL is the freshness lifetime of cacheable responses that do not set one with Cache-Control
+	// or Expires. Zero leaves such responses uncached.
+	DefaultTTL time.Duration
+	// MaxEntrySize is the largest response body, in bytes, that is cached. Defaults to 1 MiB.
+	MaxEntrySize int
+}
+
+// Cache is an in-process HTTP cache for GET and HEAD responses that follows RFC 9111. Register its
+// Middleware on the groups whose responses should be cached.
+type Cache struct {
+	config       CacheConfig
+	store        CacheStore
+	mu           sync.Mutex
+	revalidating map[string]bool
+}
+
+// NewCache creates a Cache with the given configuration.
+//
+// Handlers control caching with the standard response headers: Cache-Control (max-age, s-maxage,
+// no-store, no-cache, private, must-revalidate, stale-while-revalidate and stale-if-error), Expires and
+// Vary. They can tag a response with a space- or comma-separated Cache-Tag header, which is removed before
+// the response is sent, and responses of named routes are tagged with the route name, so that they can be
+// purged with PurgeTag and PurgeRoute.
+//
+// Example:
+//
+//	cache := middlewares.NewCache(middlewares.CacheConfig{
+//		RouteKeys: map[string]middlewares.CacheKeyFunc{
+//			"orders.show": middlewares.CacheKeyFromParams("id"),
+//		},
+//	})
+//	orders := api.Group("/orders")
+//	orders.Use(cache.Middleware)
+//	orders.GET("/:id", func(w http.ResponseWriter, r *http.Request) {
+//		w.Header().Set("Cache-Control", "max-age=60, stale-while-revalidate=30")
+//		// ...
+//	}).Name("orders.show")
+//
+//	// After an order changes:
+//	cache.PurgeRoute("orders.show")
+func NewCache(config CacheConfig) *Cache {
+	if config.Store == nil {
+		config.Store = NewLRUCacheStore(10000, 64<<20)
+	}
+	if config.Key == nil {
+		config.Key = DefaultCacheKey
+	}
+	if config.MaxEntrySize <= 0 {
+		config.MaxEntrySize = 1 << 20
+	}
+	return &Cache{
+		config:       config,
+		store:        config.Store,
+		revalidating: make(map[string]bool),
+	}
+}
+
+// DefaultCacheKey returns the host, path and query of the request, with the query parameters sorted.
+func DefaultCacheKey(r *http.Request) string {
+	key := r.Host + r.URL.Path
+	if r.URL.RawQuery != "" {
+		if query, err := url.ParseQuery(r.URL.RawQuery); err == nil {
+			key += "?" + query.Encode()
+		} else {
+			key += "?" + r.URL.RawQuery
+		}
+	}
+	return key
+}
+
+// CacheKeyFromParams returns a CacheKeyFunc that identifies a response by the matched route pattern and
+// the values of the given route parameters only, ignoring the query string. Use it for routes whose
+// response depends on nothing else, so that irrelevant query parameters do not fragment the cache.
+func CacheKeyFromParams(names ...string) CacheKeyFunc {
+	return func(r *http.Request) string {
+		route, ok := goapi.RouteFromContext(r)
+		if !ok {
+			return DefaultCacheKey(r)
+		}
+		params := goapi.ParamsFromContext(r)
+		var b strings.Builder
+		b.WriteString(r.Host + route.Pattern)
+		for _, name := range names {
+			b.WriteString("|" + name + "=" + params[name])
+		}
+		return b.String()
+	}
+}
+
+// PurgeRoute removes every cached response of the route with the given name and returns how many were removed.
+func (c *Cache) PurgeRoute(name string) int {
+	return c.store.PurgeTag("route:" + name)
+}
+
+// PurgeTag removes every cached response tagged with tag through the Cache-Tag header and returns how
+// many were removed.
+func (c *Cache) PurgeTag(tag string) int {
+	return c.store.PurgeTag(tag)
+}
+
+// Middleware serves GET and HEAD requests from the cache when possible and caches the responses of the
+// handler. Successful unsafe requests (POST, PUT, PATCH, DELETE) invalidate the cached responses of their
+// URL path, whatever their query string and the key function that stored them.
+func (c *Cache) Middleware(next goapi.HandlerFunc) goapi.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			ww := goapi.WrapResponseWriter(w)
+			next(ww, r)
+			if status := ww.Status(); status < 400 {
+				c.store.PurgeTag(cacheURLTag(r))
+			}
+			return
+		}
+
+		requestDirectives := parseCacheControl(r.Header.Values("Cache-Control"))
+		if _, ok := requestDirectives["no-store"]; ok || r.Header.Get("Upgrade") != "" {
+			next(w, r)
+			return
+		}
+		if len(requestDirectives) == 0 && strings.Contains(strings.ToLower(r.Header.Get("Pragma")), "no-cache") {
+			requestDirectives["no-cache"] = ""
+		}
+
+		key := c.key(r)
+		now := time.Now()
+		entry, entryKey := c.lookup(key, r)
+
+		var fallback *CacheEntry
+		if entry != nil {
+			if _, noCache := requestDirectives["no-cache"]; !noCache {
+				switch c.usability(entry, requestDirectives, now) {
+				case cacheFresh:
+					c.serve(w, r, entry, now, "HIT")
+					return
+				case cacheStale:
+					c.serve(w, r, entry, now, "STALE")
+					return
+				case cacheRevalidate:
+					c.serve(w, r, entry, now, "STALE")
+					c.revalidate(next, r, key, entryKey)
+					return
+				case cacheStaleIfError:
+					fallback = entry
+				}
+			}
+		}
+
+		if _, ok := requestDirectives["only-if-cached"]; ok {
+			goapi.WriteError(w, r, goapi.NewHTTPError(http.StatusGatewayTimeout, "response is not cached"))
+			return
+		}
+
+		rec := newCacheRecorder(w, c.config.MaxEntrySize)
+		next(rec, r)
+		if rec.passthrough {
+			return
+		}
+		if fallback != nil && rec.status >= 500 {
+			c.serve(w, r, fallback, now, "STALE")
+			return
+		}
+
+		tags := rec.takeTags()
+		w.Header().Set("X-Cache", "MISS")
+		rec.send()
+		if r.Method == http.MethodGet {
+			c.save(key, rec, tags, r)
+		}
+	}
+}
+
+// Freshness states of a cached entry for a request.
+const (
+	cacheUnusable = iota
+	cacheFresh
+	cacheStale
+	cacheRevalidate
+	cacheStaleIfError
+)
+
+// usability decides how a cached entry may be used for a request, according to its age and to the
+// request's max-age, min-fresh and max-stale directives.
+func (c *Cache) usability(entry *CacheEntry, directives map[string]string, now time.Time) int {
+	age := entry.Age(now)
+	remaining := entry.Lifetime - age
+
+	acceptable := true
+	if maxAge, ok := directiveSeconds(directives, "max-age"); ok && age > maxAge {
+		acceptable = false
+	}
+	if minFresh, ok := directiveSeconds(directives, "min-fresh"); ok && remaining < minFresh {
+		acceptable = false
+	}
+	if remaining > 0 && acceptable {
+		return cacheFresh
+	}
+
+	stale := -remaining
+	if value, ok := directives["max-stale"]; ok && !entry.MustRevalidate && remaining <= 0 {
+		if value == "" {
+			return cacheStale
+		}
+		if maxStale, ok := directiveSeconds(directives, "max-stale"); ok && stale <= maxStale {
+			return cacheStale
+		}
+	}
+	if remaining <= 0 && stale <= entry.StaleWhileRevalidate {
+		return cacheRevalidate
+	}
+	if remaining <= 0 && stale <= entry.StaleIfError {
+		return cacheStaleIfError
+	}
+	return cacheUnusable
+}
+
+// key returns the cache key of the request, using the key function of its route if one is configured.
+func (c *Cache) key(r *http.Request) string {
+	if route, ok := goapi.RouteFromContext(r); ok && route.Name != "" {
+		if keyFunc, ok := c.config.RouteKeys[route.Name]; ok {
+			return keyFunc(r)
+		}
+	}
+	return c.config.Key(r)
+}
+
+// lookup returns the entry for the request, following the variant index of responses with a Vary header.
+func (c *Cache) lookup(key string, r *http.Request) (*CacheEntry, string) {
+	entry, ok := c.store.Get(key)
+	if !ok {
+		return nil, ""
+	}
+	if entry.Status == 0 && len(entry.Vary) > 0 {
+		variantKey := cacheVariantKey(key, entry.Vary, r)
+		entry, ok = c.store.Get(variantKey)
+		if !ok {
+			return nil, ""
+		}
+		return entry, variantKey
+	}
+	return entry, key
+}
+
+// serve writes a cached response, answering conditional requests with 304 Not Modified.
+func (c *Cache) serve(w http.ResponseWriter, r *http.Request, entry *CacheEntry, now time.Time, state string) {
+	header := w.Header()
+	for name, values := range entry.Header {
+		if name == "Vary" {
+			for _, value := range values {
+				header.Add(name, value)
+			}
+			continue
+		}
+		header[name] = append([]string(nil), values...)
+	}
+	header.Set("Age", strconv.FormatInt(int64(entry.Age(now)/time.Second), 10))
+	header.Set("X-Cache", state)
+
+	var modified time.Time
+	if value := header.Get("Last-Modified"); value != "" {
+		modified, _ = http.ParseTime(value)
+	}
+	if entry.Status == http.StatusOK && goapi.EvaluatePreconditions(r, header.Get("ETag"), modified) == http.StatusNotModified {
+		goapi.WriteNotModified(w)
+		return
+	}
+
+	if bodyAllowed(entry.Status) {
+		header.Set("Content-Length", strconv.Itoa(len(entry.Body)))
+	}
+	w.WriteHeader(entry.Status)
+	if r.Method != http.MethodHead {
+		_, _ = w.Write(entry.Body)
+	}
+}
+
+// revalidate refreshes a stale entry in the background, at most once at a time per key.
+func (c *Cache) revalidate(next goapi.HandlerFunc, r *http.Request, key, entryKey string) {
+	c.mu.Lock()
+	if c.revalidating[entryKey] {
+		c.mu.Unlock()
+		return
+	}
+	c.revalidating[entryKey] = true
+	c.mu.Unlock()
+
+	req := r.Clone(context.WithoutCancel(r.Context()))
+	req.Method = http.MethodGet
+	for _, name := range []string{"If-None-Match", "If-Modified-Since", "If-Match", "If-Unmodified-Since", "Cache-Control", "Pragma"} {
+		req.Header.Del(name)
+	}
+
+	go func() {
+		defer func() {
+			if value := recover(); value != nil {
+				slog.Default().Error("cache revalidation panicked", slog.String("key", key), slog.Any("panic", value))
+			}
+			c.mu.Lock()
+			delete(c.revalidating, entryKey)
+			c.mu.Unlock()
+		}()
+
+		rec := newCacheRecorder(&discardResponseWriter{header: http.Header{}}, c.config.MaxEntrySize)
+		next(rec, req)
+		if !rec.passthrough && rec.status < 500 {
+			c.save(key, rec, rec.takeTags(), req)
+		}
+	}()
+}
+
+// cacheURLTag returns the tag of the responses cached for the URL path of the request, which unsafe
+// requests to the same path purge.
+func cacheURLTag(r *http.Request) string {
+	return "url:" + r.Host + r.URL.Path
+}
+
+// cacheableStatus lists the status codes that are cacheable by default (RFC 9110, section 15.1).
+var cacheableStatus = map[int]bool{
+	http.StatusOK: true, http.StatusNonAuthoritativeInfo: true, http.StatusNoContent: true,
+	http.StatusMultipleChoices: true, http.StatusMovedPermanently: true, http.StatusPermanentRedirect: true,
+	http.StatusNotFound: true, http.StatusMethodNotAllowed: true, http.StatusGone: true,
+	http.StatusRequestURITooLong: true, http.StatusNotImplemented: true,
+}
+
+// save stores a recorded response if it is cacheable. Responses with a Vary header are stored under a
+// variant key, and an index listing the Vary headers is stored under the primary key.
+func (c *Cache) save(key string, rec *cacheRecorder, tags []string, r *http.Request) {
+	entry := c.newEntry(rec, r, time.Now())
+	if entry == nil {
+		return
+	}
+
+	if route, ok := goapi.RouteFromContext(r); ok && route.Name != "" {
+		tags = append(tags, "route:"+route.Name)
+	}
+	entry.Tags = append(tags, cacheURLTag(r))
+
+	var vary []string
+	for _, value := range entry.Header.Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name == "*" {
+				return
+			} else if name != "" {
+				vary = append(vary, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+	if len(vary) == 0 {
+		c.store.Set(key, entry)
+		return
+	}
+
+	sort.Strings(vary)
+	index := *entry
+	index.Status, index.Header, index.Body, index.Vary = 0, nil, nil, vary
+	c.store.Set(key, &index)
+	c.store.Set(cacheVariantKey(key, vary, r), entry)
+}
+
+// newEntry builds the cache entry of a recorded response, or returns nil if the response must not be
+// stored: its status is not cacheable, Cache-Control forbids it, it sets cookies, it answers an
+// authenticated request without explicitly allowing shared caching, or it has no freshness lifetime.
+func (c *Cache) newEntry(rec *cacheRecorder, r *http.Request, now time.Time) *CacheEntry {
+	status := rec.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	if !cacheableStatus[status] || rec.header.Get("Set-Cookie") != "" {
+		return nil
+	}
+
+	directives := parseCacheControl(rec.header.Values("Cache-Control"))
+	for _, name := range []string{"no-store", "no-cache", "private"} {
+		if _, ok := directives[name]; ok {
+			return nil
+		}
+	}
+	_, public := directives["public"]
+	_, sharedMaxAge := directives["s-maxage"]
+	_, mustRevalidate := directives["must-revalidate"]
+	_, proxyRevalidate := directives["proxy-revalidate"]
+	if r.Header.Get("Authorization") != "" && !public && !sharedMaxAge && !mustRevalidate {
+		return nil
+	}
+
+	lifetime, explicit := directiveSeconds(directives, "s-maxage")
+	if !explicit {
+		lifetime, explicit = directiveSeconds(directives, "max-age")
+	}
+	if !explicit && rec.header.Get("Expires") != "" {
+		explicit = true
+		if expires, err := http.ParseTime(rec.header.Get("Expires")); err == nil {
+			date, err := http.ParseTime(rec.header.Get("Date"))
+			if err != nil {
+				date = now
+			}
+			lifetime = expires.Sub(date)
+		}
+	}
+	if !explicit {
+		lifetime = c.config.DefaultTTL
+	}
+
+	entry := &CacheEntry{
+		Status:         status,
+		Header:         rec.header.Clone(),
+		Body:           append([]byte(nil), rec.body...),
+		Stored:         now,
+		Lifetime:       max(lifetime, 0),
+		MustRevalidate: mustRevalidate || proxyRevalidate || sharedMaxAge,
+	}
+	if !entry.MustRevalidate {
+		entry.StaleWhileRevalidate, _ = directiveSeconds(directives, "stale-while-revalidate")
+		entry.StaleIfError, _ = directiveSeconds(directives, "stale-if-error")
+	}
+	if entry.Lifetime == 0 && entry.StaleWhileRevalidate == 0 && entry.StaleIfError == 0 {
+		return nil
+	}
+	return entry
+}
+
+// cacheVariantKey derives the key of a response variant from the request headers it varies on.
+func cacheVariantKey(key string, vary []string, r *http.Request) string {
+	var b strings.Builder
+	b.WriteString(key)
+	for _, name := range vary {
+		b.WriteString("\x00" + name + "=" + strings.Join(r.Header.Values(name), ","))
+	}
+	return b.String()
+}
+
+// parseCacheControl parses Cache-Control header values into lowercased directives and unquoted values.
+func parseCacheControl(values []string) map[string]string {
+	directives := make(map[string]string)
+	for _, value := range values {
+		for _, part := range strings.Split(value, ",") {
+			name, arg, _ := strings.Cut(strings.TrimSpace(part), "=")
+			if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+				directives[name] = strings.Trim(strings.TrimSpace(arg), `"`)
+			}
+		}
+	}
+	return directives
+}
+
+// directiveSeconds returns the duration of a directive expressed in seconds.
+func directiveSeconds(directives map[string]string, name string) (time.Duration, bool) {
+	value, ok := directives[name]
+	if !ok {
+		return 0, false
+	}
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || seconds < 0 {
+		return 0, false
+	}
+	return time.Duration(seconds) * time.Second, true
+}
+
+// discardResponseWriter is the destination of background revalidations.
+type discardResponseWriter struct {
+	header http.Header
+}
+
+func (d *discardResponseWriter) Header() http.Header         { return d.header }
+func (d *discardResponseWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (d *discardResponseWriter) WriteHeader(int)             {}
+
+// cacheRecorder buffers the response of the handler so that it can be stored. Responses larger than the
+// maximum entry size, and flushed ones, are streamed to the client and not stored.
+type cacheRecorder struct {
+	http.ResponseWriter
+	header      http.Header
+	status      int
+	wroteHeader bool
+	body        []byte
+	maxSize     int
+	passthrough bool
+}
+
+func newCacheRecorder(w http.ResponseWriter, maxSize int) *cacheRecorder {
+	return &cacheRecorder{ResponseWriter: w, header: http.Header{}, maxSize: maxSize}
+}
+
+func (rec *cacheRecorder) Header() http.Header {
+	return rec.header
+}
+
+func (rec *cacheRecorder) WriteHeader(code int) {
+	if rec.wroteHeader {
+		return
+	}
+	if code >= 100 && code < 200 && code != http.StatusSwitchingProtocols {
+		rec.ResponseWriter.WriteHeader(code)
+		return
+	}
+	rec.wroteHeader = true
+	rec.status = code
+}
+
+func (rec *cacheRecorder) Write(b []byte) (int, error) {
+	if !rec.wroteHeader {
+		rec.WriteHeader(http.StatusOK)
+	}
+	if !rec.passthrough && len(rec.body)+len(b) > rec.maxSize {
+		rec.startPassthrough()
+	}
+	if rec.passthrough {
+		return rec.ResponseWriter.Write(b)
+	}
+	rec.body = append(rec.body, b...)
+	return len(b), nil
+}
+
+// Flush streams the response to the client, which keeps it out of the cache.
+func (rec *cacheRecorder) Flush() {
+	if !rec.wroteHeader {
+		rec.WriteHeader(http.StatusOK)
+	}
+	if !rec.passthrough {
+		rec.startPassthrough()
+	}
+	_ = http.NewResponseController(rec.ResponseWriter).Flush()
+}
+
+// Unwrap returns the wrapped writer, so http.ResponseController reaches its other features.
+func (rec *cacheRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// startPassthrough sends what was recorded so far and passes further writes through to the client.
+func (rec *cacheRecorder) startPassthrough() {
+	rec.passthrough = true
+	rec.send()
+	rec.body = nil
+}
+
+// takeTags removes the Cache-Tag header and returns the tags it listed.
+func (rec *cacheRecorder) takeTags() []string {
+	var tags []string
+	for _, value := range rec.header.Values("Cache-Tag") {
+		tags = append(tags, strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' })...)
+	}
+	rec.header.Del("Cache-Tag")
+	return tags
+}
+
+// send writes the recorded header and body to the client.
+func (rec *cacheRecorder) send() {
+	header := rec.ResponseWriter.Header()
+	for name, values := range rec.header {
+		if name == "Cache-Tag" {
+			continue
+		}
+		if name == "Vary" {
+			for _, value := range values {
+				header.Add(name, value)
+			}
+			continue
+		}
+		header[name] = values
+	}
+
+	status := rec.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	rec.ResponseWriter.WriteHeader(status)
+	if len(rec.body) > 0 {
+		_, _ = rec.ResponseWriter.Write(rec.body)
+	}
+}
@@ -0,0 +1,377 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	goapi "github.com/carlosealves2/go-api"
+)
+
+// ageCache makes every entry of the store older by d.
+func ageCache(store *LRUCacheStore, d time.Duration) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	for _, element := range store.entries {
+		entry := element.Value.(*lruItem).entry
+		entry.Stored = entry.Stored.Add(-d)
+	}
+}
+
+type cacheFixture struct {
+	cache  *Cache
+	store  *LRUCacheStore
+	router *goapi.Router
+	calls  atomic.Int32
+}
+
+func newCacheFixture(config CacheConfig, cacheControl string) *cacheFixture {
+	f := &cacheFixture{store: NewLRUCacheStore(100, 0)}
+	config.Store = f.store
+	f.cache = NewCache(config)
+	f.router = goapi.NewRouter()
+	f.router.Use(f.cache.Middleware)
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		n := f.calls.Add(1)
+		if cacheControl != "" {
+			w.Header().Set("Cache-Control", cacheControl)
+		}
+		w.Header().Set("Cache-Tag", "docs doc:"+goapi.ParamsFromContext(r)["id"])
+		w.Write([]byte("version " + strconv.Itoa(int(n))))
+	}
+	f.router.GET("/docs/:id", handler).Name("docs.show")
+	f.router.HEAD("/docs/:id", handler)
+	f.router.PUT("/docs/:id", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	return f
+}
+
+func (f *cacheFixture) get(path string, headers map[string]string) *httptest.ResponseRecorder {
+	return f.do(http.MethodGet, path, headers)
+}
+
+func (f *cacheFixture) do(method, path string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	rec := httptest.NewRecorder()
+	f.router.ServerHTTP(rec, req)
+	return rec
+}
+
+func TestCacheHit(t *testing.T) {
+	f := newCacheFixture(CacheConfig{}, "max-age=60")
+
+	first := f.get("/docs/1", nil)
+	if first.Header().Get("X-Cache") != "MISS" || first.Body.String() != "version 1" {
+		t.Fatalf("expected a miss, got %q %q", first.Header().Get("X-Cache"), first.Body.String())
+	}
+	if first.Header().Get("Cache-Tag") != "" {
+		t.Errorf("expected Cache-Tag to be removed from the response")
+	}
+
+	ageCache(f.store, 5*time.Second)
+	second := f.get("/docs/1", nil)
+	if second.Header().Get("X-Cache") != "HIT" || second.Body.String() != "version 1" {
+		t.Errorf("expected a hit, got %q %q", second.Header().Get("X-Cache"), second.Body.String())
+	}
+	if second.Header().Get("Age") != "5" {
+		t.Errorf("expected Age 5, got %q", second.Header().Get("Age"))
+	}
+
+	head := f.do(http.MethodHead, "/docs/1", nil)
+	if head.Header().Get("X-Cache") != "HIT" || head.Body.Len() != 0 {
+		t.Errorf("expected HEAD to be answered from the cache without a body")
+	}
+	if f.calls.Load() != 1 {
+		t.Errorf("expected the handler to run once, ran %d times", f.calls.Load())
+	}
+}
+
+func TestCacheResponseDirectives(t *testing.T) {
+	tests := []struct {
+		name         string
+		cacheControl string
+		defaultTTL   time.Duration
+		cached       bool
+	}{
+		{name: "max-age", cacheControl: "public, max-age=60", cached: true},
+		{name: "s-maxage", cacheControl: "s-maxage=60", cached: true},
+		{name: "no-store", cacheControl: "no-store", cached: false},
+		{name: "no-cache", cacheControl: "no-cache, max-age=60", cached: false},
+		{name: "private", cacheControl: "private, max-age=60", cached: false},
+		{name: "max-age zero", cacheControl: "max-age=0", cached: false},
+		{name: "no freshness", cacheControl: "", cached: false},
+		{name: "default ttl", cacheControl: "", defaultTTL: time.Minute, cached: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newCacheFixture(CacheConfig{DefaultTTL: tt.defaultTTL}, tt.cacheControl)
+			f.get("/docs/1", nil)
+			rec := f.get("/docs/1", nil)
+
+			if cached := rec.Header().Get("X-Cache") == "HIT"; cached != tt.cached {
+				t.Errorf("expected cached=%v, got X-Cache %q", tt.cached, rec.Header().Get("X-Cache"))
+			}
+		})
+	}
+}
+
+func TestCacheRequestDirectives(t *testing.T) {
+	f := newCacheFixture(CacheConfig{}, "max-age=60")
+
+	if rec := f.get("/docs/1", map[string]string{"Cache-Control": "only-if-cached"}); rec.Code != http.StatusGatewayTimeout {
+		t.Errorf("expected 504 for only-if-cached on a miss, got %d", rec.Code)
+	}
+
+	f.get("/docs/1", nil)
+	ageCache(f.store, 30*time.Second)
+
+	tests := []struct {
+		name         string
+		cacheControl string
+		expected     string
+	}{
+		{name: "fresh enough", cacheControl: "max-age=40", expected: "HIT"},
+		{name: "too old", cacheControl: "max-age=10", expected: "MISS"},
+		{name: "not fresh long enough", cacheControl: "min-fresh=45", expected: "MISS"},
+		{name: "no-cache", cacheControl: "no-cache", expected: "MISS"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := f.get("/docs/1", map[string]string{"Cache-Control": tt.cacheControl})
+			if got := rec.Header().Get("X-Cache"); got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+			// Refreshing stores a new entry; make it 30 seconds old again.
+			ageCache(f.store, 30*time.Second)
+		})
+	}
+
+	if rec := f.get("/docs/1", map[string]string{"Cache-Control": "no-store"}); rec.Header().Get("X-Cache") != "" {
+		t.Errorf("expected no-store to bypass the cache, got %q", rec.Header().Get("X-Cache"))
+	}
+}
+
+func TestCacheMaxStale(t *testing.T) {
+	f := newCacheFixture(CacheConfig{}, "max-age=10")
+	f.get("/docs/1", nil)
+	ageCache(f.store, 15*time.Second)
+
+	// The store drops entries past their lifetime, so keep it around with a stale window.
+	f.store.mu.Lock()
+	for _, element := range f.store.entries {
+		element.Value.(*lruItem).entry.StaleIfError = time.Minute
+	}
+	f.store.mu.Unlock()
+
+	if rec := f.get("/docs/1", map[string]string{"Cache-Control": "max-stale=10"}); rec.Header().Get("X-Cache") != "STALE" {
+		t.Errorf("expected a stale response within max-stale, got %q", rec.Header().Get("X-Cache"))
+	}
+	if rec := f.get("/docs/1", map[string]string{"Cache-Control": "max-stale=1"}); rec.Header().Get("X-Cache") != "MISS" {
+		t.Errorf("expected a miss beyond max-stale, got %q", rec.Header().Get("X-Cache"))
+	}
+}
+
+func TestCacheStaleWhileRevalidate(t *testing.T) {
+	f := newCacheFixture(CacheConfig{}, "max-age=10, stale-while-revalidate=30")
+	f.get("/docs/1", nil)
+	ageCache(f.store, 20*time.Second)
+
+	rec := f.get("/docs/1", nil)
+	if rec.Header().Get("X-Cache") != "STALE" || rec.Body.String() != "version 1" {
+		t.Fatalf("expected the stale response, got %q %q", rec.Header().Get("X-Cache"), rec.Body.String())
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		rec = f.get("/docs/1", nil)
+		if rec.Body.String() == "version 2" && rec.Header().Get("X-Cache") == "HIT" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the entry to be refreshed in the background, got %q", rec.Body.String())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestCacheStaleIfError(t *testing.T) {
+	store := NewLRUCacheStore(100, 0)
+	cache := NewCache(CacheConfig{Store: store})
+	var failing atomic.Bool
+
+	router := goapi.NewRouter()
+	router.Use(cache.Middleware)
+	router.GET("/report", func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			http.Error(w, "database down", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Cache-Control", "max-age=10, stale-if-error=60")
+		w.Write([]byte("report"))
+	})
+
+	get := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServerHTTP(rec, httptest.NewRequest(http.MethodGet, "/report", nil))
+		return rec
+	}
+
+	get()
+	ageCache(store, 20*time.Second)
+	failing.Store(true)
+
+	rec := get()
+	if rec.Code != http.StatusOK || rec.Body.String() != "report" || rec.Header().Get("X-Cache") != "STALE" {
+		t.Errorf("expected the stale response instead of the error, got %d %q", rec.Code, rec.Body.String())
+	}
+
+	ageCache(store, time.Minute)
+	if rec := get(); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected the error once stale-if-error has passed, got %d", rec.Code)
+	}
+}
+
+func TestCacheVary(t *testing.T) {
+	cache := NewCache(CacheConfig{})
+	router := goapi.NewRouter()
+	router.Use(cache.Middleware)
+	router.GET("/greeting", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set("Vary", "Accept-Language")
+		if r.Header.Get("Accept-Language") == "pt" {
+			w.Write([]byte("olá"))
+			return
+		}
+		w.Write([]byte("hello"))
+	})
+
+	get := func(language string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/greeting", nil)
+		req.Header.Set("Accept-Language", language)
+		rec := httptest.NewRecorder()
+		router.ServerHTTP(rec, req)
+		return rec
+	}
+
+	get("en")
+	get("pt")
+	for language, expected := range map[string]string{"en": "hello", "pt": "olá"} {
+		rec := get(language)
+		if rec.Header().Get("X-Cache") != "HIT" || rec.Body.String() != expected {
+			t.Errorf("expected cached %q for %s, got %q %q", expected, language, rec.Header().Get("X-Cache"), rec.Body.String())
+		}
+	}
+}
+
+func TestCacheConditionalRequest(t *testing.T) {
+	f := newCacheFixture(CacheConfig{}, "max-age=60")
+	f.router.GET("/etag", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		goapi.SetETag(w, "v1")
+		w.Write([]byte("tagged"))
+	})
+
+	f.get("/etag", nil)
+	rec := f.get("/etag", map[string]string{"If-None-Match": `"v1"`})
+	if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Errorf("expected 304 from the cache, got %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestCachePurge(t *testing.T) {
+	f := newCacheFixture(CacheConfig{}, "max-age=60")
+	f.get("/docs/1", nil)
+	f.get("/docs/2", nil)
+
+	if purged := f.cache.PurgeTag("doc:1"); purged != 1 {
+		t.Errorf("expected 1 entry purged by tag, got %d", purged)
+	}
+	if rec := f.get("/docs/1", nil); rec.Header().Get("X-Cache") != "MISS" {
+		t.Errorf("expected a miss after purging the tag")
+	}
+
+	if purged := f.cache.PurgeRoute("docs.show"); purged != 2 {
+		t.Errorf("expected 2 entries purged by route, got %d", purged)
+	}
+	if rec := f.get("/docs/2", nil); rec.Header().Get("X-Cache") != "MISS" {
+		t.Errorf("expected a miss after purging the route")
+	}
+}
+
+func TestCacheInvalidatedByUnsafeRequest(t *testing.T) {
+	f := newCacheFixture(CacheConfig{}, "max-age=60")
+	f.get("/docs/1", nil)
+
+	f.do(http.MethodPut, "/docs/1", nil)
+
+	if rec := f.get("/docs/1", nil); rec.Header().Get("X-Cache") != "MISS" {
+		t.Errorf("expected the PUT to invalidate the cached response")
+	}
+}
+
+func TestCacheInvalidatedWithRouteKey(t *testing.T) {
+	f := newCacheFixture(CacheConfig{
+		RouteKeys: map[string]CacheKeyFunc{"docs.show": CacheKeyFromParams("id")},
+	}, "max-age=60")
+	f.get("/docs/1?utm_source=mail", nil)
+	f.get("/docs/2", nil)
+
+	f.do(http.MethodPut, "/docs/1", nil)
+
+	if rec := f.get("/docs/1?utm_source=mail", nil); rec.Header().Get("X-Cache") != "MISS" || rec.Body.String() != "version 3" {
+		t.Errorf("expected the PUT to invalidate the response stored under the route key, got %s %q",
+			rec.Header().Get("X-Cache"), rec.Body.String())
+	}
+	if rec := f.get("/docs/2", nil); rec.Header().Get("X-Cache") != "HIT" {
+		t.Errorf("expected other documents to stay cached")
+	}
+}
+
+func TestCacheKeyFromParams(t *testing.T) {
+	f := newCacheFixture(CacheConfig{
+		RouteKeys: map[string]CacheKeyFunc{"docs.show": CacheKeyFromParams("id")},
+	}, "max-age=60")
+
+	f.get("/docs/1?utm_source=mail", nil)
+	if rec := f.get("/docs/1?utm_source=ads", nil); rec.Header().Get("X-Cache") != "HIT" {
+		t.Errorf("expected the query string to be ignored by the route key")
+	}
+	if rec := f.get("/docs/2", nil); rec.Header().Get("X-Cache") != "MISS" {
+		t.Errorf("expected different params to use different keys")
+	}
+}
+
+func TestDefaultCacheKey(t *testing.T) {
+	a := httptest.NewRequest(http.MethodGet, "http://example.com/items?b=2&a=1", nil)
+	b := httptest.NewRequest(http.MethodGet, "http://example.com/items?a=1&b=2", nil)
+	c := httptest.NewRequest(http.MethodGet, "http://other.com/items?a=1&b=2", nil)
+
+	if DefaultCacheKey(a) != DefaultCacheKey(b) {
+		t.Errorf("expected the query order not to matter")
+	}
+	if DefaultCacheKey(b) == DefaultCacheKey(c) {
+		t.Errorf("expected the host to be part of the key")
+	}
+}
+
+func TestCacheSkipsLargeResponses(t *testing.T) {
+	f := newCacheFixture(CacheConfig{MaxEntrySize: 5}, "max-age=60")
+
+	first := f.get("/docs/1", nil)
+	if first.Body.String() != "version 1" {
+		t.Fatalf("expected the full response to be streamed, got %q", first.Body.String())
+	}
+	if rec := f.get("/docs/1", nil); rec.Body.String() != "version 2" {
+		t.Errorf("expected a response larger than MaxEntrySize not to be cached, got %q", rec.Body.String())
+	}
+}
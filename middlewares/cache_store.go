@@ -0,0 +1,199 @@
+package middlewares
+
+import (
+	"container/list"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// CacheEntry is a response stored by the Cache middleware.
+type CacheEntry struct {
+	// Status, Header and Body are the stored response.
+	Status int
+	Header http.Header
+	Body   []byte
+	// Vary lists the request headers the response varies on. An entry with Vary set and a zero Status is
+	// an index pointing to the variants of a resource, which are stored under keys derived from it.
+	Vary []string
+	// Stored is when the response was generated.
+	Stored time.Time
+	// Lifetime is how long the response is fresh after Stored.
+	Lifetime time.Duration
+	// StaleWhileRevalidate is how long after expiring the response may still be served while it is
+	// refreshed in the background.
+	StaleWhileRevalidate time.Duration
+	// StaleIfError is how long after expiring the response may still be served when refreshing it fails.
+	StaleIfError time.Duration
+	// MustRevalidate forbids serving the response once it is stale.
+	MustRevalidate bool
+	// Tags are the purge tags of the response, including "route:" followed by the route name and "url:"
+	// followed by the host and path of the request.
+	Tags []string
+}
+
+// Age returns how long ago the response was generated.
+func (e *CacheEntry) Age(now time.Time) time.Duration {
+	if age := now.Sub(e.Stored); age > 0 {
+		return age
+	}
+	return 0
+}
+
+// Expires returns when the entry can no longer be used in any way, not even as a stale response.
+// Stores may discard it after that time.
+func (e *CacheEntry) Expires() time.Time {
+	return e.Stored.Add(e.Lifetime + max(e.StaleWhileRevalidate, e.StaleIfError))
+}
+
+// size estimates the memory used by the entry.
+func (e *CacheEntry) size() int64 {
+	size := int64(len(e.Body)) + 64
+	for name, values := range e.Header {
+		size += int64(len(name))
+		for _, value := range values {
+			size += int64(len(value))
+		}
+	}
+	return size
+}
+
+// CacheStore stores the responses of the Cache middleware. Implementations must be safe for concurrent use,
+// which allows plugging a shared backend in place of the in-memory LRUCacheStore.
+type CacheStore interface {
+	// Get returns the entry stored under key, if it has not expired.
+	Get(key string) (*CacheEntry, bool)
+	// Set stores an entry under key, replacing any previous one.
+	Set(key string, entry *CacheEntry)
+	// Delete removes the entry stored under key.
+	Delete(key string)
+	// PurgeTag removes every entry carrying the tag and returns how many were removed.
+	PurgeTag(tag string) int
+}
+
+// LRUCacheStore is an in-memory CacheStore that evicts the least recently used entries when it is full.
+type LRUCacheStore struct {
+	mu         sync.Mutex
+	maxEntries int
+	maxBytes   int64
+	bytes      int64
+	order      *list.List
+	entries    map[string]*list.Element
+	tags       map[string]map[string]struct{}
+}
+
+type lruItem struct {
+	key   string
+	entry *CacheEntry
+	size  int64
+}
+
+// NewLRUCacheStore creates an LRUCacheStore holding at most maxEntries entries and about maxBytes bytes of
+// responses. Zero disables the corresponding limit.
+//
+// Example:
+//
+//	store := middlewares.NewLRUCacheStore(10000, 64<<20)
+func NewLRUCacheStore(maxEntries int, maxBytes int64) *LRUCacheStore {
+	return &LRUCacheStore{
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		order:      list.New(),
+		entries:    make(map[string]*list.Element),
+		tags:       make(map[string]map[string]struct{}),
+	}
+}
+
+// Get returns the entry stored under key and marks it as recently used.
+func (s *LRUCacheStore) Get(key string) (*CacheEntry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	element, ok := s.entries[key]
+	if !ok {
+		return nil, false
+	}
+	item := element.Value.(*lruItem)
+	if time.Now().After(item.entry.Expires()) {
+		s.removeLocked(element)
+		return nil, false
+	}
+	s.order.MoveToFront(element)
+	return item.entry, true
+}
+
+// Set stores an entry, evicting the least recently used entries as needed.
+func (s *LRUCacheStore) Set(key string, entry *CacheEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if element, ok := s.entries[key]; ok {
+		s.removeLocked(element)
+	}
+
+	item := &lruItem{key: key, entry: entry, size: entry.size()}
+	if s.maxBytes > 0 && item.size > s.maxBytes {
+		return
+	}
+	s.entries[key] = s.order.PushFront(item)
+	s.bytes += item.size
+	for _, tag := range entry.Tags {
+		if s.tags[tag] == nil {
+			s.tags[tag] = make(map[string]struct{})
+		}
+		s.tags[tag][key] = struct{}{}
+	}
+
+	for (s.maxEntries > 0 && s.order.Len() > s.maxEntries) || (s.maxBytes > 0 && s.bytes > s.maxBytes) {
+		s.removeLocked(s.order.Back())
+	}
+}
+
+// Delete removes the entry stored under key.
+func (s *LRUCacheStore) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if element, ok := s.entries[key]; ok {
+		s.removeLocked(element)
+	}
+}
+
+// PurgeTag removes every entry carrying the tag.
+func (s *LRUCacheStore) PurgeTag(tag string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := s.tags[tag]
+	purged := 0
+	for key := range keys {
+		if element, ok := s.entries[key]; ok {
+			s.removeLocked(element)
+			purged++
+		}
+	}
+	delete(s.tags, tag)
+	return purged
+}
+
+// Len returns the number of stored entries.
+func (s *LRUCacheStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.order.Len()
+}
+
+func (s *LRUCacheStore) removeLocked(element *list.Element) {
+	item := element.Value.(*lruItem)
+	s.order.Remove(element)
+	delete(s.entries, item.key)
+	s.bytes -= item.size
+	for _, tag := range item.entry.Tags {
+		if keys, ok := s.tags[tag]; ok {
+			delete(keys, item.key)
+			if len(keys) == 0 {
+				delete(s.tags, tag)
+			}
+		}
+	}
+}
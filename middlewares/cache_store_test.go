@@ -0,0 +1,78 @@
+package middlewares
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestEntry(body string, tags ...string) *CacheEntry {
+	return &CacheEntry{Status: 200, Body: []byte(body), Stored: time.Now(), Lifetime: time.Minute, Tags: tags}
+}
+
+func TestLRUCacheStoreEvictsLeastRecentlyUsed(t *testing.T) {
+	store := NewLRUCacheStore(2, 0)
+	store.Set("a", newTestEntry("a"))
+	store.Set("b", newTestEntry("b"))
+	store.Get("a")
+	store.Set("c", newTestEntry("c"))
+
+	if _, ok := store.Get("b"); ok {
+		t.Errorf("expected the least recently used entry to be evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := store.Get(key); !ok {
+			t.Errorf("expected %q to be kept", key)
+		}
+	}
+}
+
+func TestLRUCacheStoreMaxBytes(t *testing.T) {
+	store := NewLRUCacheStore(0, 400)
+	store.Set("a", newTestEntry(strings.Repeat("a", 100)))
+	store.Set("b", newTestEntry(strings.Repeat("b", 100)))
+	store.Set("huge", newTestEntry(strings.Repeat("h", 1000)))
+
+	if _, ok := store.Get("huge"); ok {
+		t.Errorf("expected an entry larger than the store to be rejected")
+	}
+	store.Set("c", newTestEntry(strings.Repeat("c", 100)))
+	if store.Len() != 2 {
+		t.Errorf("expected the byte limit to keep 2 entries, got %d", store.Len())
+	}
+	if _, ok := store.Get("a"); ok {
+		t.Errorf("expected the oldest entry to be evicted")
+	}
+}
+
+func TestLRUCacheStorePurgeTag(t *testing.T) {
+	store := NewLRUCacheStore(0, 0)
+	store.Set("order-1", newTestEntry("1", "orders", "route:orders.show"))
+	store.Set("order-2", newTestEntry("2", "orders"))
+	store.Set("user-1", newTestEntry("u", "users"))
+
+	if purged := store.PurgeTag("orders"); purged != 2 {
+		t.Errorf("expected 2 entries to be purged, got %d", purged)
+	}
+	if store.Len() != 1 {
+		t.Errorf("expected 1 entry to remain, got %d", store.Len())
+	}
+	if purged := store.PurgeTag("route:orders.show"); purged != 0 {
+		t.Errorf("expected the tag index to be cleaned up, got %d", purged)
+	}
+}
+
+func TestLRUCacheStoreExpiry(t *testing.T) {
+	store := NewLRUCacheStore(0, 0)
+	entry := newTestEntry("old")
+	entry.Stored = time.Now().Add(-2 * time.Minute)
+	entry.StaleIfError = 30 * time.Second
+	store.Set("old", entry)
+
+	if _, ok := store.Get("old"); ok {
+		t.Errorf("expected an entry past its stale windows to be dropped")
+	}
+	if store.Len() != 0 {
+		t.Errorf("expected the expired entry to be removed")
+	}
+}
@@ -0,0 +1,263 @@
+package middlewares
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	goapi "github.com/carlosealves2/go-api"
+)
+
+// CircuitState is the state of a CircuitBreaker.
+type CircuitState int
+
+const (
+	// CircuitClosed lets requests through while counting failures.
+	CircuitClosed CircuitState = iota
+	// CircuitOpen rejects requests without calling the handler.
+	CircuitOpen
+	// CircuitHalfOpen lets a few trial requests through to find out whether the handler recovered.
+	CircuitHalfOpen
+)
+
+// String returns the name of the state.
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitBreakerConfig configures a CircuitBreaker. At least one of ConsecutiveFailures and FailureRatio must
+// be set.
+type CircuitBreakerConfig struct {
+	// ConsecutiveFailures opens the circuit after this many failures in a row. Zero disables the trigger.
+	ConsecutiveFailures int
+	// FailureRatio opens the circuit when the ratio of failed requests in the current Window reaches it,
+	// between 0 and 1. Zero disables the trigger.
+	FailureRatio float64
+	// MinRequests is the number of requests a Window must have before FailureRatio is applied.
+	// Defaults to 10.
+	MinRequests int
+	// Window is the period over which FailureRatio is computed; the counts restart with each window.
+	// Defaults to 10 seconds.
+	Window time.Duration
+	// OpenTimeout is how long the circuit stays open before letting trial requests through.
+	// Defaults to 30 seconds.
+	OpenTimeout time.Duration
+	// HalfOpenRequests is the number of trial requests let through in the half-open state. The circuit closes
+	// when they all succeed and opens again on the first failure. Defaults to 1.
+	HalfOpenRequests int
+	// IsFailure reports whether a response status is a failure. Panics are always failures.
+	// Defaults to statuses of 500 and above.
+	IsFailure func(status int) bool
+	// OnStateChange is called after each state change, for example to update metrics. It must not block.
+	OnStateChange func(from, to CircuitState)
+}
+
+// CircuitBreaker stops calling a failing handler for a while so that it, and the dependencies it waits for,
+// can recover, and requests fail fast instead of hanging.
+type CircuitBreaker struct {
+	config CircuitBreakerConfig
+
+	mu                  sync.Mutex
+	state               CircuitState
+	generation          uint64
+	requests            int
+	failures            int
+	consecutiveFailures int
+	windowStart         time.Time
+	openedAt            time.Time
+	trials              int
+	trialSuccesses      int
+}
+
+// NewCircuitBreaker creates a CircuitBreaker with the given configuration.
+//
+// While the circuit is open, requests are rejected with 503 Service Unavailable and a Retry-After header,
+// through the router's error handler. After OpenTimeout, HalfOpenRequests trial requests are let through;
+// other requests are still rejected until the trials end.
+//
+// Register one breaker per group whose handlers share a dependency; all the routes of the group and its
+// subgroups then share the circuit.
+//
+// Example:
+//
+//	breaker := middlewares.NewCircuitBreaker(middlewares.CircuitBreakerConfig{
+//		ConsecutiveFailures: 5,
+//		FailureRatio:        0.5,
+//		OpenTimeout:         10 * time.Second,
+//		OnStateChange: func(from, to middlewares.CircuitState) {
+//			circuitState.Set(float64(to))
+//		},
+//	})
+//	payments := api.Group("/payments")
+//	payments.Use(breaker.Middleware)
+func NewCircuitBreaker(config CircuitBreakerConfig) *CircuitBreaker {
+	if config.ConsecutiveFailures <= 0 && config.FailureRatio <= 0 {
+		panic("middlewares: CircuitBreaker requires ConsecutiveFailures or FailureRatio")
+	}
+	if config.MinRequests <= 0 {
+		config.MinRequests = 10
+	}
+	if config.Window <= 0 {
+		config.Window = 10 * time.Second
+	}
+	if config.OpenTimeout <= 0 {
+		config.OpenTimeout = 30 * time.Second
+	}
+	if config.HalfOpenRequests <= 0 {
+		config.HalfOpenRequests = 1
+	}
+	if config.IsFailure == nil {
+		config.IsFailure = func(status int) bool { return status >= http.StatusInternalServerError }
+	}
+	return &CircuitBreaker{config: config, windowStart: time.Now()}
+}
+
+// State returns the current state of the circuit.
+func (b *CircuitBreaker) State() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	state := b.state
+	if state == CircuitOpen && time.Since(b.openedAt) >= b.config.OpenTimeout {
+		state = CircuitHalfOpen
+	}
+	return state
+}
+
+// Middleware calls the handler while the circuit allows it and records the outcome.
+func (b *CircuitBreaker) Middleware(next goapi.HandlerFunc) goapi.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		generation, retryAfter, ok := b.allow()
+		if !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(max(1, ceilSeconds(retryAfter))))
+			goapi.WriteError(w, req, goapi.NewHTTPError(http.StatusServiceUnavailable, "circuit open"))
+			return
+		}
+
+		ww := goapi.WrapResponseWriter(w)
+		failed := true
+		defer func() {
+			b.record(generation, failed)
+		}()
+		next(ww, req)
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		failed = b.config.IsFailure(status)
+	}
+}
+
+// allow reports whether a request may go through. It returns the generation the outcome must be recorded
+// in, or how long the circuit remains open.
+func (b *CircuitBreaker) allow() (uint64, time.Duration, bool) {
+	b.mu.Lock()
+	now := time.Now()
+	var changed func()
+
+	switch b.state {
+	case CircuitClosed:
+		if now.Sub(b.windowStart) >= b.config.Window {
+			b.windowStart = now
+			b.requests, b.failures = 0, 0
+		}
+	case CircuitOpen:
+		if wait := b.config.OpenTimeout - now.Sub(b.openedAt); wait > 0 {
+			b.mu.Unlock()
+			return 0, wait, false
+		}
+		changed = b.setStateLocked(CircuitHalfOpen, now)
+	}
+
+	if b.state == CircuitHalfOpen {
+		if b.trials >= b.config.HalfOpenRequests {
+			b.mu.Unlock()
+			if changed != nil {
+				changed()
+			}
+			return 0, time.Second, false
+		}
+		b.trials++
+	}
+	b.requests++
+	generation := b.generation
+	b.mu.Unlock()
+
+	if changed != nil {
+		changed()
+	}
+	return generation, 0, true
+}
+
+// record counts the outcome of a request and changes the state when a trigger fires.
+func (b *CircuitBreaker) record(generation uint64, failed bool) {
+	b.mu.Lock()
+	if generation != b.generation {
+		// The request started in a previous state.
+		b.mu.Unlock()
+		return
+	}
+
+	now := time.Now()
+	var changed func()
+	switch b.state {
+	case CircuitClosed:
+		if !failed {
+			b.consecutiveFailures = 0
+			break
+		}
+		b.failures++
+		b.consecutiveFailures++
+		if (b.config.ConsecutiveFailures > 0 && b.consecutiveFailures >= b.config.ConsecutiveFailures) ||
+			(b.config.FailureRatio > 0 && b.requests >= b.config.MinRequests &&
+				float64(b.failures)/float64(b.requests) >= b.config.FailureRatio) {
+			changed = b.setStateLocked(CircuitOpen, now)
+		}
+	case CircuitHalfOpen:
+		if failed {
+			changed = b.setStateLocked(CircuitOpen, now)
+			break
+		}
+		b.trialSuccesses++
+		if b.trialSuccesses >= b.config.HalfOpenRequests {
+			changed = b.setStateLocked(CircuitClosed, now)
+		}
+	}
+	b.mu.Unlock()
+
+	if changed != nil {
+		changed()
+	}
+}
+
+// setStateLocked switches to a new state, resetting the counts, and returns the function notifying the
+// change, to be called once the lock is released.
+func (b *CircuitBreaker) setStateLocked(state CircuitState, now time.Time) func() {
+	from := b.state
+	b.state = state
+	b.generation++
+	b.requests, b.failures, b.consecutiveFailures = 0, 0, 0
+	b.trials, b.trialSuccesses = 0, 0
+	b.windowStart = now
+	if state == CircuitOpen {
+		b.openedAt = now
+	}
+
+	if b.config.OnStateChange == nil {
+		return nil
+	}
+	return func() {
+		b.config.OnStateChange(from, state)
+	}
+}
@@ -0,0 +1,191 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	goapi "github.com/carlosealves2/go-api"
+)
+
+// breakerRouter serves /status/:code, which answers with the given status code.
+func breakerRouter(breaker *CircuitBreaker) *goapi.Router {
+	router := goapi.NewRouter()
+	router.Use(breaker.Middleware)
+	router.GET("/status/:code", func(w http.ResponseWriter, r *http.Request) {
+		var code int
+		fmt.Sscan(goapi.ParamsFromContext(r)["code"], &code)
+		w.WriteHeader(code)
+	})
+	return router
+}
+
+func breakerRequest(router *goapi.Router, code int) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	router.ServerHTTP(rec, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/status/%d", code), nil))
+	return rec
+}
+
+type stateRecorder struct {
+	mu      sync.Mutex
+	changes []string
+}
+
+func (s *stateRecorder) record(from, to CircuitState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.changes = append(s.changes, from.String()+"->"+to.String())
+}
+
+func (s *stateRecorder) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return strings.Join(s.changes, ",")
+}
+
+func TestCircuitBreakerConsecutiveFailures(t *testing.T) {
+	changes := &stateRecorder{}
+	breaker := NewCircuitBreaker(CircuitBreakerConfig{
+		ConsecutiveFailures: 3,
+		OpenTimeout:         50 * time.Millisecond,
+		OnStateChange:       changes.record,
+	})
+	router := breakerRouter(breaker)
+
+	for _, code := range []int{500, 502, 200, 500, 404, 500, 500} {
+		breakerRequest(router, code)
+	}
+	if breaker.State() != CircuitClosed {
+		t.Fatalf("expected successes and client errors to reset the count, got %v", breaker.State())
+	}
+
+	breakerRequest(router, 503)
+	if breaker.State() != CircuitOpen {
+		t.Fatalf("expected the circuit to open, got %v", breaker.State())
+	}
+
+	rec := breakerRequest(router, 200)
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") != "1" {
+		t.Errorf("expected 503 with Retry-After while open, got %d %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if breaker.State() != CircuitHalfOpen {
+		t.Fatalf("expected the circuit to be half-open after the timeout, got %v", breaker.State())
+	}
+	breakerRequest(router, 500)
+	if breaker.State() != CircuitOpen {
+		t.Fatalf("expected a failed trial to open the circuit again, got %v", breaker.State())
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if rec := breakerRequest(router, 200); rec.Code != http.StatusOK {
+		t.Fatalf("expected the trial request to go through, got %d", rec.Code)
+	}
+	if breaker.State() != CircuitClosed {
+		t.Fatalf("expected a successful trial to close the circuit, got %v", breaker.State())
+	}
+
+	expected := "closed->open,open->half-open,half-open->open,open->half-open,half-open->closed"
+	if changes.String() != expected {
+		t.Errorf("expected state changes %q, got %q", expected, changes.String())
+	}
+}
+
+func TestCircuitBreakerFailureRatio(t *testing.T) {
+	breaker := NewCircuitBreaker(CircuitBreakerConfig{FailureRatio: 0.5, MinRequests: 4, Window: time.Minute})
+	router := breakerRouter(breaker)
+
+	for _, code := range []int{500, 200, 500} {
+		breakerRequest(router, code)
+	}
+	if breaker.State() != CircuitClosed {
+		t.Fatalf("expected MinRequests to keep the circuit closed, got %v", breaker.State())
+	}
+	breakerRequest(router, 200)
+	breakerRequest(router, 500)
+	if breaker.State() != CircuitOpen {
+		t.Fatalf("expected a failure ratio of 3/5 to open the circuit, got %v", breaker.State())
+	}
+}
+
+func TestCircuitBreakerHalfOpenLimit(t *testing.T) {
+	breaker := NewCircuitBreaker(CircuitBreakerConfig{
+		ConsecutiveFailures: 1,
+		OpenTimeout:         10 * time.Millisecond,
+		HalfOpenRequests:    2,
+	})
+	release := make(chan struct{})
+	router := goapi.NewRouter()
+	router.Use(breaker.Middleware)
+	router.GET("/fail", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	router.GET("/slow", func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	})
+
+	router.ServerHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/fail", nil))
+	time.Sleep(20 * time.Millisecond)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			router.ServerHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/slow", nil))
+		}()
+	}
+	waitFor(t, func() bool {
+		breaker.mu.Lock()
+		defer breaker.mu.Unlock()
+		return breaker.trials == 2
+	})
+
+	rec := httptest.NewRecorder()
+	router.ServerHTTP(rec, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected requests beyond the trials to be rejected, got %d", rec.Code)
+	}
+
+	close(release)
+	wg.Wait()
+	if breaker.State() != CircuitClosed {
+		t.Errorf("expected the successful trials to close the circuit, got %v", breaker.State())
+	}
+}
+
+func TestCircuitBreakerFailureStatusesAndPanics(t *testing.T) {
+	breaker := NewCircuitBreaker(CircuitBreakerConfig{
+		ConsecutiveFailures: 2,
+		IsFailure: func(status int) bool {
+			return status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+		},
+	})
+	router := goapi.NewRouter()
+	router.Use(breaker.Middleware)
+	router.GET("/limited", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	router.GET("/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+
+	router.ServerHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/limited", nil))
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("expected the panic to be propagated")
+			}
+		}()
+		router.ServerHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/panic", nil))
+	}()
+
+	if breaker.State() != CircuitOpen {
+		t.Errorf("expected 429 and the panic to count as failures, got %v", breaker.State())
+	}
+}
@@ -0,0 +1,329 @@
+package middlewares
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	goapi "github.com/carlosealves2/go-api"
+)
+
+// DefaultCompressExcludedTypes lists the content types that are already compressed, and which
+// CompressWithConfig therefore never compresses unless CompressConfig.ExcludedContentTypes is set.
+var DefaultCompressExcludedTypes = []string{
+	"image/png", "image/jpeg", "image/gif", "image/webp", "image/avif",
+	"video/*", "audio/*",
+	"font/woff", "font/woff2",
+	"application/zip", "application/gzip", "application/x-gzip", "application/zstd",
+	"application/x-bzip2", "application/x-7z-compressed", "application/x-rar-compressed",
+	"application/pdf",
+}
+
+// CompressConfig configures the CompressWithConfig middleware.
+type CompressConfig struct {
+	// Level is the compression level, from flate.BestSpeed to flate.BestCompression, or flate.HuffmanOnly.
+	// Zero selects flate.DefaultCompression.
+	Level int
+	// MinSize is the minimum response size, in bytes, worth compressing. Defaults to 1024.
+	// Streamed responses that are flushed before reaching it are compressed regardless.
+	MinSize int
+	// ExcludedContentTypes lists the media types that are never compressed. An entry ending in "/*" matches
+	// a whole type. Defaults to DefaultCompressExcludedTypes.
+	ExcludedContentTypes []string
+}
+
+// Compress is a middleware that compresses responses with gzip or deflate, according to the client's
+// Accept-Encoding header. See CompressWithConfig for the details.
+func Compress(next goapi.HandlerFunc) goapi.HandlerFunc {
+	return CompressWithConfig(CompressConfig{})(next)
+}
+
+// CompressWithConfig returns a middleware that compresses responses with gzip or deflate.
+//
+// The encoding is negotiated from the Accept-Encoding header, honoring q-values and preferring gzip on
+// ties. The response is buffered until MinSize bytes have been written, so small responses are sent
+// uncompressed; responses that already have a Content-Encoding, excluded content types, HEAD requests,
+// WebSocket upgrades and responses without a body are never compressed. Compressed responses lose their
+// Content-Length, and every response gets "Vary: Accept-Encoding". Flushing, either directly or through
+// http.ResponseController, flushes the compressed stream, so Server-Sent Events keep working.
+// Compressors are pooled to limit allocations.
+//
+// Example:
+//
+//	api := goapi.NewRouter()
+//	api.Use(middlewares.CompressWithConfig(middlewares.CompressConfig{
+//		Level:   flate.BestSpeed,
+//		MinSize: 512,
+//	}))
+func CompressWithConfig(config CompressConfig) goapi.MiddlewareFunc {
+	c := newCompressor(config)
+
+	return func(next goapi.HandlerFunc) goapi.HandlerFunc {
+		return func(w http.ResponseWriter, req *http.Request) {
+			if req.Header.Get("Upgrade") != "" {
+				next(w, req)
+				return
+			}
+
+			w.Header().Add("Vary", "Accept-Encoding")
+			encoding := negotiateEncoding(req.Header.Get("Accept-Encoding"))
+			if encoding == "" || req.Method == http.MethodHead {
+				next(w, req)
+				return
+			}
+
+			cw := &compressWriter{ResponseWriter: w, compressor: c, encoding: encoding}
+			next(cw, req)
+			cw.close()
+		}
+	}
+}
+
+// compressEncoder is implemented by the gzip and zlib writers.
+type compressEncoder interface {
+	io.Writer
+	Flush() error
+	Close() error
+	Reset(w io.Writer)
+}
+
+// compressor holds the settings and the encoder pools of one middleware instance.
+type compressor struct {
+	minSize  int
+	exact    map[string]bool
+	prefixes []string
+	pools    map[string]*sync.Pool
+}
+
+func newCompressor(config CompressConfig) *compressor {
+	level := config.Level
+	if level == 0 {
+		level = flate.DefaultCompression
+	}
+	if level < flate.HuffmanOnly || level > flate.BestCompression {
+		panic(fmt.Sprintf("middlewares: invalid compression level %d", level))
+	}
+
+	c := &compressor{
+		minSize: config.MinSize,
+		exact:   make(map[string]bool),
+		pools: map[string]*sync.Pool{
+			"gzip": {New: func() any {
+				w, _ := gzip.NewWriterLevel(io.Discard, level)
+				return w
+			}},
+			"deflate": {New: func() any {
+				w, _ := zlib.NewWriterLevel(io.Discard, level)
+				return w
+			}},
+		},
+	}
+	if c.minSize <= 0 {
+		c.minSize = 1024
+	}
+
+	excluded := config.ExcludedContentTypes
+	if excluded == nil {
+		excluded = DefaultCompressExcludedTypes
+	}
+	for _, mediaType := range excluded {
+		mediaType = strings.ToLower(mediaType)
+		if prefix, ok := strings.CutSuffix(mediaType, "*"); ok {
+			c.prefixes = append(c.prefixes, prefix)
+		} else {
+			c.exact[mediaType] = true
+		}
+	}
+	return c
+}
+
+// compressible reports whether responses of the given content type should be compressed.
+func (c *compressor) compressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = strings.ToLower(strings.TrimSpace(contentType))
+	}
+	if c.exact[mediaType] {
+		return false
+	}
+	for _, prefix := range c.prefixes {
+		if strings.HasPrefix(mediaType, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// negotiateEncoding returns the supported encoding with the highest q-value in an Accept-Encoding header,
+// or an empty string when the client accepts neither gzip nor deflate.
+func negotiateEncoding(acceptEncoding string) string {
+	if acceptEncoding == "" {
+		return ""
+	}
+
+	qualities := make(map[string]float64)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		coding, params, _ := strings.Cut(part, ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(name, "q") {
+				if parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		qualities[coding] = q
+	}
+
+	best, bestQ := "", 0.0
+	for _, coding := range []string{"gzip", "deflate"} {
+		q, ok := qualities[coding]
+		if !ok {
+			if coding == "gzip" {
+				q, ok = qualities["x-gzip"]
+			}
+			if !ok {
+				q = qualities["*"]
+			}
+		}
+		if q > bestQ {
+			best, bestQ = coding, q
+		}
+	}
+	return best
+}
+
+// compressWriter buffers the start of the response until it can decide whether to compress it.
+type compressWriter struct {
+	http.ResponseWriter
+	compressor  *compressor
+	encoding    string
+	status      int
+	wroteHeader bool
+	decided     bool
+	compressing bool
+	buf         []byte
+	encoder     compressEncoder
+}
+
+func (cw *compressWriter) WriteHeader(code int) {
+	if cw.wroteHeader {
+		return
+	}
+	if code >= 100 && code < 200 && code != http.StatusSwitchingProtocols {
+		cw.ResponseWriter.WriteHeader(code)
+		return
+	}
+
+	cw.wroteHeader = true
+	cw.status = code
+	if !bodyAllowed(code) || code == http.StatusPartialContent {
+		cw.decided = true
+		cw.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.decided {
+		if cw.compressing {
+			return cw.encoder.Write(b)
+		}
+		return cw.ResponseWriter.Write(b)
+	}
+
+	cw.buf = append(cw.buf, b...)
+	if len(cw.buf) >= cw.compressor.minSize {
+		if err := cw.decide(false); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// Flush sends the buffered data, compressing it if the content type allows, and flushes the encoder and
+// the underlying writer.
+func (cw *compressWriter) Flush() {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if !cw.decided {
+		_ = cw.decide(true)
+	}
+	if cw.compressing {
+		_ = cw.encoder.Flush()
+	}
+	_ = http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+// Unwrap returns the wrapped writer, so http.ResponseController reaches its other features.
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// decide chooses between compressing and sending the response as is, writes the header and the buffered data.
+func (cw *compressWriter) decide(streaming bool) error {
+	cw.decided = true
+	header := cw.Header()
+
+	if header.Get("Content-Type") == "" && len(cw.buf) > 0 {
+		header.Set("Content-Type", http.DetectContentType(cw.buf))
+	}
+	large := streaming || len(cw.buf) >= cw.compressor.minSize
+	if large && header.Get("Content-Encoding") == "" && cw.compressor.compressible(header.Get("Content-Type")) {
+		cw.compressing = true
+		header.Set("Content-Encoding", cw.encoding)
+		header.Del("Content-Length")
+		cw.encoder = cw.compressor.pools[cw.encoding].Get().(compressEncoder)
+		cw.encoder.Reset(cw.ResponseWriter)
+	}
+
+	cw.ResponseWriter.WriteHeader(cw.status)
+	buf := cw.buf
+	cw.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	var err error
+	if cw.compressing {
+		_, err = cw.encoder.Write(buf)
+	} else {
+		_, err = cw.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+// close sends what is still buffered and terminates the compressed stream.
+func (cw *compressWriter) close() {
+	if !cw.decided {
+		if !cw.wroteHeader && len(cw.buf) == 0 {
+			return
+		}
+		_ = cw.decide(false)
+	}
+	if cw.compressing {
+		_ = cw.encoder.Close()
+		cw.encoder.Reset(io.Discard)
+		cw.compressor.pools[cw.encoding].Put(cw.encoder)
+		cw.encoder = nil
+	}
+}
+
+// bodyAllowed reports whether a response with the given status may have a body.
+func bodyAllowed(status int) bool {
+	return status >= 200 && status != http.StatusNoContent && status != http.StatusNotModified
+}
@@ -0,0 +1,236 @@
+package middlewares
+
+import (
+	"bufio"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	goapi "github.com/carlosealves2/go-api"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		acceptEncoding string
+		expected       string
+	}{
+		{acceptEncoding: "", expected: ""},
+		{acceptEncoding: "gzip", expected: "gzip"},
+		{acceptEncoding: "deflate", expected: "deflate"},
+		{acceptEncoding: "gzip, deflate, br", expected: "gzip"},
+		{acceptEncoding: "gzip;q=0.5, deflate", expected: "deflate"},
+		{acceptEncoding: "GZIP; Q=0.8, deflate;q=0.9", expected: "deflate"},
+		{acceptEncoding: "gzip;q=0, deflate;q=0", expected: ""},
+		{acceptEncoding: "*", expected: "gzip"},
+		{acceptEncoding: "*;q=0.5, gzip;q=0", expected: "deflate"},
+		{acceptEncoding: "x-gzip", expected: "gzip"},
+		{acceptEncoding: "identity, br", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.acceptEncoding, func(t *testing.T) {
+			if got := negotiateEncoding(tt.acceptEncoding); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func newCompressRouter(handler goapi.HandlerFunc) *goapi.Router {
+	router := goapi.NewRouter()
+	router.Use(CompressWithConfig(CompressConfig{MinSize: 100}))
+	router.GET("/", handler)
+	router.HEAD("/", handler)
+	return router
+}
+
+func compressRequest(router *goapi.Router, method, acceptEncoding string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/", nil)
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	rec := httptest.NewRecorder()
+	router.ServerHTTP(rec, req)
+	return rec
+}
+
+func decompress(t *testing.T, encoding string, body io.Reader) string {
+	t.Helper()
+
+	var reader io.Reader
+	var err error
+	switch encoding {
+	case "gzip":
+		reader, err = gzip.NewReader(body)
+	case "deflate":
+		reader, err = zlib.NewReader(body)
+	default:
+		reader = body
+	}
+	if err != nil {
+		t.Fatalf("failed to open %s stream: %v", encoding, err)
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("failed to decompress %s body: %v", encoding, err)
+	}
+	return string(data)
+}
+
+func TestCompress(t *testing.T) {
+	large := strings.Repeat("compressible text ", 100)
+
+	tests := []struct {
+		name             string
+		acceptEncoding   string
+		contentType      string
+		contentEncoding  string
+		body             string
+		expectedEncoding string
+	}{
+		{name: "gzip", acceptEncoding: "gzip", body: large, expectedEncoding: "gzip"},
+		{name: "deflate", acceptEncoding: "deflate", body: large, expectedEncoding: "deflate"},
+		{name: "not accepted", acceptEncoding: "", body: large, expectedEncoding: ""},
+		{name: "small body", acceptEncoding: "gzip", body: "tiny", expectedEncoding: ""},
+		{name: "excluded type", acceptEncoding: "gzip", contentType: "image/png", body: large, expectedEncoding: ""},
+		{name: "svg compressed", acceptEncoding: "gzip", contentType: "image/svg+xml", body: large, expectedEncoding: "gzip"},
+		{name: "already encoded", acceptEncoding: "gzip", contentEncoding: "br", body: large, expectedEncoding: "br"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newCompressRouter(func(w http.ResponseWriter, r *http.Request) {
+				if tt.contentType != "" {
+					w.Header().Set("Content-Type", tt.contentType)
+				}
+				if tt.contentEncoding != "" {
+					w.Header().Set("Content-Encoding", tt.contentEncoding)
+				}
+				w.Header().Set("Content-Length", strconv.Itoa(len(tt.body)))
+				// Write in chunks to exercise buffering across writes.
+				for i := 0; i < len(tt.body); i += 50 {
+					end := min(i+50, len(tt.body))
+					io.WriteString(w, tt.body[i:end])
+				}
+			})
+
+			rec := compressRequest(router, http.MethodGet, tt.acceptEncoding)
+
+			if got := rec.Header().Get("Content-Encoding"); got != tt.expectedEncoding {
+				t.Fatalf("expected Content-Encoding %q, got %q", tt.expectedEncoding, got)
+			}
+			if rec.Header().Get("Vary") != "Accept-Encoding" {
+				t.Errorf("expected Vary: Accept-Encoding, got %q", rec.Header().Get("Vary"))
+			}
+			if tt.expectedEncoding == "gzip" || tt.expectedEncoding == "deflate" {
+				if rec.Header().Get("Content-Length") != "" {
+					t.Errorf("expected Content-Length to be removed")
+				}
+				if rec.Body.Len() >= len(tt.body) {
+					t.Errorf("expected the body to shrink, got %d bytes", rec.Body.Len())
+				}
+			}
+			if got := decompress(t, tt.expectedEncoding, rec.Body); got != tt.body {
+				t.Errorf("unexpected body %q", got)
+			}
+		})
+	}
+}
+
+func TestCompressDetectsContentType(t *testing.T) {
+	router := newCompressRouter(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "<html><body>"+strings.Repeat("<p>hello</p>", 50)+"</body></html>")
+	})
+
+	rec := compressRequest(router, http.MethodGet, "gzip")
+
+	if got := rec.Header().Get("Content-Type"); got != "text/html; charset=utf-8" {
+		t.Errorf("expected the content type to be sniffed before compressing, got %q", got)
+	}
+	if rec.Header().Get("Content-Encoding") != "gzip" {
+		t.Errorf("expected a gzip response")
+	}
+}
+
+func TestCompressWithoutBody(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		status int
+	}{
+		{name: "no content", method: http.MethodGet, status: http.StatusNoContent},
+		{name: "not modified", method: http.MethodGet, status: http.StatusNotModified},
+		{name: "head", method: http.MethodHead, status: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newCompressRouter(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			})
+
+			rec := compressRequest(router, tt.method, "gzip")
+
+			if rec.Code != tt.status {
+				t.Errorf("expected status %d, got %d", tt.status, rec.Code)
+			}
+			if rec.Header().Get("Content-Encoding") != "" || rec.Body.Len() != 0 {
+				t.Errorf("expected an uncompressed empty response, got %q", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestCompressFlush(t *testing.T) {
+	release := make(chan struct{})
+	router := goapi.NewRouter()
+	router.Use(Compress)
+	router.GET("/events", func(w http.ResponseWriter, r *http.Request) {
+		stream, err := goapi.SSE(w, r)
+		if err != nil {
+			t.Errorf("expected SSE to work behind Compress: %v", err)
+			return
+		}
+		stream.Send(goapi.Event{Data: "first"})
+		<-release
+	})
+
+	server := httptest.NewServer(http.HandlerFunc(router.ServerHTTP))
+	defer server.Close()
+	defer close(release)
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/events", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.Header.Get("Content-Encoding") != "gzip" {
+		t.Fatalf("expected a gzip stream, got %q", resp.Header.Get("Content-Encoding"))
+	}
+	reader, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to open gzip stream: %v", err)
+	}
+	// The event must arrive while the handler is still running.
+	line, err := bufio.NewReader(reader).ReadString('\n')
+	if err != nil || line != "data: first\n" {
+		t.Errorf("expected the flushed event, got %q (%v)", line, err)
+	}
+}
+
+func TestCompressInvalidLevel(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected an invalid level to panic")
+		}
+	}()
+	CompressWithConfig(CompressConfig{Level: 42})
+}
@@ -0,0 +1,288 @@
+package middlewares
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	goapi "github.com/carlosealves2/go-api"
+)
+
+// Priority is the class of a request for the ConcurrencyLimiter. Queued requests of a higher priority are
+// admitted first and may take the place of lower priority ones.
+type Priority int
+
+const (
+	// PriorityLow is for requests that can be shed first, such as batch or prefetch traffic.
+	PriorityLow Priority = iota
+	// PriorityNormal is the default priority.
+	PriorityNormal
+	// PriorityHigh is for requests admitted before the others.
+	PriorityHigh
+	// PriorityCritical requests, such as health checks and admin routes, bypass the limiter: they are never
+	// queued nor shed and do not count against the limit.
+	PriorityCritical
+)
+
+// AdaptiveLimitConfig enables adaptive concurrency limits: the limit grows additively while requests
+// complete within TargetLatency and shrinks multiplicatively when they take longer (AIMD).
+type AdaptiveLimitConfig struct {
+	// TargetLatency is the request latency above which the limit is decreased. Required:
+	// NewConcurrencyLimiter panics if it is not positive.
+	TargetLatency time.Duration
+	// MinLimit is the lowest limit. Defaults to 1.
+	MinLimit int
+	// MaxLimit is the highest limit. Defaults to ConcurrencyLimitConfig.MaxConcurrent.
+	MaxLimit int
+	// Backoff is the factor the limit is multiplied by when a request is too slow, at most once per
+	// TargetLatency. Defaults to 0.9.
+	Backoff float64
+}
+
+// ConcurrencyLimitConfig configures a ConcurrencyLimiter.
+type ConcurrencyLimitConfig struct {
+	// MaxConcurrent is the number of requests handled at the same time, and the initial limit when Adaptive
+	// is set. Required.
+	MaxConcurrent int
+	// MaxQueue is the number of requests that may wait for a slot. Zero sheds every request over the limit.
+	MaxQueue int
+	// MaxWait is the longest a request waits in the queue before being shed. Defaults to one second.
+	MaxWait time.Duration
+	// RetryAfter is sent in the Retry-After header of shed requests. Defaults to one second.
+	RetryAfter time.Duration
+	// Priority returns the priority of a request. Defaults to PriorityNormal for every request.
+	Priority func(r *http.Request) Priority
+	// Adaptive enables adaptive limits when set.
+	Adaptive *AdaptiveLimitConfig
+}
+
+// ConcurrencyLimiter caps the number of requests handled at the same time and sheds the excess.
+type ConcurrencyLimiter struct {
+	config       ConcurrencyLimitConfig
+	mu           sync.Mutex
+	inFlight     int
+	limit        float64
+	queue        [PriorityCritical][]*concurrencyWaiter
+	queued       int
+	lastDecrease time.Time
+}
+
+type concurrencyWaiter struct {
+	// admitted receives true when the request gets a slot and false when it is shed.
+	admitted chan bool
+}
+
+// NewConcurrencyLimiter creates a ConcurrencyLimiter with the given configuration.
+//
+// Requests over the limit wait in a queue, ordered by priority, for at most MaxWait. When the queue is full,
+// a request takes the place of the most recent waiter of a lower priority, if any. Requests that cannot be
+// queued or that wait too long are shed with 503 Service Unavailable and a Retry-After header, through the
+// router's error handler.
+//
+// The limiter counts the requests of the router or group it is registered on, including subgroups; use one
+// limiter per group for separate caps, and one on the router for a global cap.
+//
+// Example:
+//
+//	limiter := middlewares.NewConcurrencyLimiter(middlewares.ConcurrencyLimitConfig{
+//		MaxConcurrent: 100,
+//		MaxQueue:      200,
+//		MaxWait:       500 * time.Millisecond,
+//		Priority: middlewares.PriorityByRoute(map[string]middlewares.Priority{
+//			"/health":      middlewares.PriorityCritical,
+//			"admin.orders": middlewares.PriorityCritical,
+//		}),
+//		Adaptive: &middlewares.AdaptiveLimitConfig{TargetLatency: 200 * time.Millisecond},
+//	})
+//	router.Use(limiter.Middleware)
+func NewConcurrencyLimiter(config ConcurrencyLimitConfig) *ConcurrencyLimiter {
+	if config.MaxConcurrent <= 0 {
+		panic("middlewares: ConcurrencyLimiter requires a positive MaxConcurrent")
+	}
+	if config.MaxWait <= 0 {
+		config.MaxWait = time.Second
+	}
+	if config.RetryAfter <= 0 {
+		config.RetryAfter = time.Second
+	}
+	if config.Adaptive != nil {
+		// The caller's configuration may be shared by several limiters, so the defaults go into a copy.
+		adaptive := *config.Adaptive
+		config.Adaptive = &adaptive
+		if adaptive.TargetLatency <= 0 {
+			panic("middlewares: ConcurrencyLimiter requires a positive Adaptive.TargetLatency")
+		}
+		if adaptive.MinLimit <= 0 {
+			adaptive.MinLimit = 1
+		}
+		if adaptive.MaxLimit <= 0 {
+			adaptive.MaxLimit = config.MaxConcurrent
+		}
+		if adaptive.Backoff <= 0 || adaptive.Backoff >= 1 {
+			adaptive.Backoff = 0.9
+		}
+	}
+	return &ConcurrencyLimiter{config: config, limit: float64(config.MaxConcurrent)}
+}
+
+// Limit returns the current concurrency limit.
+func (l *ConcurrencyLimiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return int(l.limit)
+}
+
+// InFlight returns the number of requests being handled, excluding critical ones.
+func (l *ConcurrencyLimiter) InFlight() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.inFlight
+}
+
+// Queued returns the number of requests waiting for a slot.
+func (l *ConcurrencyLimiter) Queued() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.queued
+}
+
+// Middleware admits, queues or sheds requests.
+func (l *ConcurrencyLimiter) Middleware(next goapi.HandlerFunc) goapi.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		priority := PriorityNormal
+		if l.config.Priority != nil {
+			priority = min(max(l.config.Priority(req), PriorityLow), PriorityCritical)
+		}
+		if priority == PriorityCritical {
+			next(w, req)
+			return
+		}
+
+		if !l.acquire(req, priority) {
+			w.Header().Set("Retry-After", strconv.Itoa(max(1, ceilSeconds(l.config.RetryAfter))))
+			goapi.WriteError(w, req, goapi.NewHTTPError(http.StatusServiceUnavailable, "server overloaded"))
+			return
+		}
+
+		start := time.Now()
+		defer func() {
+			l.release(time.Since(start))
+		}()
+		next(w, req)
+	}
+}
+
+// acquire waits for a slot and reports whether the request was admitted.
+func (l *ConcurrencyLimiter) acquire(req *http.Request, priority Priority) bool {
+	l.mu.Lock()
+	if l.queued == 0 && l.inFlight < int(l.limit) {
+		l.inFlight++
+		l.mu.Unlock()
+		return true
+	}
+	if l.queued >= l.config.MaxQueue && !l.evictLocked(priority) {
+		l.mu.Unlock()
+		return false
+	}
+	waiter := &concurrencyWaiter{admitted: make(chan bool, 1)}
+	l.queue[priority] = append(l.queue[priority], waiter)
+	l.queued++
+	l.mu.Unlock()
+
+	timer := time.NewTimer(l.config.MaxWait)
+	defer timer.Stop()
+	select {
+	case admitted := <-waiter.admitted:
+		return admitted
+	case <-timer.C:
+	case <-req.Context().Done():
+	}
+
+	l.mu.Lock()
+	removed := l.removeLocked(priority, waiter)
+	l.mu.Unlock()
+	if removed {
+		return false
+	}
+	// The request was admitted or shed while giving up.
+	return <-waiter.admitted
+}
+
+// evictLocked sheds the most recent waiter with a priority lower than the given one, to make room for it.
+func (l *ConcurrencyLimiter) evictLocked(priority Priority) bool {
+	for p := PriorityLow; p < priority; p++ {
+		if n := len(l.queue[p]); n > 0 {
+			waiter := l.queue[p][n-1]
+			l.queue[p] = l.queue[p][:n-1]
+			l.queued--
+			waiter.admitted <- false
+			return true
+		}
+	}
+	return false
+}
+
+// removeLocked removes a waiter from the queue and reports whether it was still there.
+func (l *ConcurrencyLimiter) removeLocked(priority Priority, waiter *concurrencyWaiter) bool {
+	for i, queued := range l.queue[priority] {
+		if queued == waiter {
+			l.queue[priority] = append(l.queue[priority][:i], l.queue[priority][i+1:]...)
+			l.queued--
+			return true
+		}
+	}
+	return false
+}
+
+// release frees the slot of a finished request, adapts the limit and admits waiting requests.
+func (l *ConcurrencyLimiter) release(latency time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.inFlight--
+	if adaptive := l.config.Adaptive; adaptive != nil {
+		now := time.Now()
+		if latency > adaptive.TargetLatency {
+			if now.Sub(l.lastDecrease) >= adaptive.TargetLatency {
+				l.limit = max(float64(adaptive.MinLimit), l.limit*adaptive.Backoff)
+				l.lastDecrease = now
+			}
+		} else {
+			// Grow by about one slot each time a full limit of requests completes in time.
+			l.limit = min(float64(adaptive.MaxLimit), l.limit+1/l.limit)
+		}
+	}
+
+	for l.queued > 0 && l.inFlight < int(l.limit) {
+		for p := PriorityCritical - 1; p >= PriorityLow; p-- {
+			if len(l.queue[p]) == 0 {
+				continue
+			}
+			waiter := l.queue[p][0]
+			l.queue[p] = l.queue[p][1:]
+			l.queued--
+			l.inFlight++
+			waiter.admitted <- true
+			break
+		}
+	}
+}
+
+// PriorityByRoute returns a Priority function looking up the name, then the pattern, of the matched route
+// (see goapi.RouteInfo) in the map. Other routes have PriorityNormal.
+func PriorityByRoute(priorities map[string]Priority) func(r *http.Request) Priority {
+	return func(r *http.Request) Priority {
+		route, ok := goapi.RouteFromContext(r)
+		if !ok {
+			return PriorityNormal
+		}
+		if priority, ok := priorities[route.Name]; ok && route.Name != "" {
+			return priority
+		}
+		if priority, ok := priorities[route.Pattern]; ok {
+			return priority
+		}
+		return PriorityNormal
+	}
+}
@@ -0,0 +1,206 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	goapi "github.com/carlosealves2/go-api"
+)
+
+// concurrencyRouter serves /block, which waits for release, /fast and the critical /health.
+func concurrencyRouter(limiter *ConcurrencyLimiter, release <-chan struct{}) *goapi.Router {
+	router := goapi.NewRouter()
+	router.Use(limiter.Middleware)
+	router.GET("/block", func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	})
+	router.GET("/fast", func(w http.ResponseWriter, r *http.Request) {})
+	router.GET("/low", func(w http.ResponseWriter, r *http.Request) {})
+	router.GET("/high", func(w http.ResponseWriter, r *http.Request) {})
+	router.GET("/health", func(w http.ResponseWriter, r *http.Request) {}).Name("health")
+	return router
+}
+
+// startRequest serves a request in the background and returns the channel receiving its status.
+func startRequest(router *goapi.Router, path string) <-chan int {
+	status := make(chan int, 1)
+	go func() {
+		rec := httptest.NewRecorder()
+		router.ServerHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		status <- rec.Code
+	}()
+	return status
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestConcurrencyLimiterSheds(t *testing.T) {
+	limiter := NewConcurrencyLimiter(ConcurrencyLimitConfig{MaxConcurrent: 1, RetryAfter: 3 * time.Second})
+	release := make(chan struct{})
+	router := concurrencyRouter(limiter, release)
+
+	blocked := startRequest(router, "/block")
+	waitFor(t, func() bool { return limiter.InFlight() == 1 })
+
+	rec := httptest.NewRecorder()
+	router.ServerHTTP(rec, httptest.NewRequest(http.MethodGet, "/fast", nil))
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") != "3" {
+		t.Errorf("expected 503 with Retry-After 3, got %d %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+
+	close(release)
+	if status := <-blocked; status != http.StatusOK {
+		t.Errorf("expected the admitted request to succeed, got %d", status)
+	}
+	if limiter.InFlight() != 0 {
+		t.Errorf("expected no request in flight, got %d", limiter.InFlight())
+	}
+}
+
+func TestConcurrencyLimiterQueue(t *testing.T) {
+	limiter := NewConcurrencyLimiter(ConcurrencyLimitConfig{MaxConcurrent: 1, MaxQueue: 1, MaxWait: time.Second})
+	release := make(chan struct{})
+	router := concurrencyRouter(limiter, release)
+
+	blocked := startRequest(router, "/block")
+	waitFor(t, func() bool { return limiter.InFlight() == 1 })
+	queued := startRequest(router, "/fast")
+	waitFor(t, func() bool { return limiter.Queued() == 1 })
+
+	rec := httptest.NewRecorder()
+	router.ServerHTTP(rec, httptest.NewRequest(http.MethodGet, "/fast", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected a request over the full queue to be shed, got %d", rec.Code)
+	}
+
+	close(release)
+	if status := <-blocked; status != http.StatusOK {
+		t.Errorf("expected 200, got %d", status)
+	}
+	if status := <-queued; status != http.StatusOK {
+		t.Errorf("expected the queued request to be admitted, got %d", status)
+	}
+}
+
+func TestConcurrencyLimiterMaxWait(t *testing.T) {
+	limiter := NewConcurrencyLimiter(ConcurrencyLimitConfig{MaxConcurrent: 1, MaxQueue: 1, MaxWait: 20 * time.Millisecond})
+	release := make(chan struct{})
+	defer close(release)
+	router := concurrencyRouter(limiter, release)
+
+	startRequest(router, "/block")
+	waitFor(t, func() bool { return limiter.InFlight() == 1 })
+
+	rec := httptest.NewRecorder()
+	router.ServerHTTP(rec, httptest.NewRequest(http.MethodGet, "/fast", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected a request waiting too long to be shed, got %d", rec.Code)
+	}
+	if limiter.Queued() != 0 {
+		t.Errorf("expected the queue to be empty, got %d", limiter.Queued())
+	}
+}
+
+func TestConcurrencyLimiterPriorities(t *testing.T) {
+	limiter := NewConcurrencyLimiter(ConcurrencyLimitConfig{
+		MaxConcurrent: 1,
+		MaxQueue:      1,
+		MaxWait:       time.Second,
+		Priority: PriorityByRoute(map[string]Priority{
+			"health": PriorityCritical,
+			"/low":   PriorityLow,
+			"/high":  PriorityHigh,
+		}),
+	})
+	release := make(chan struct{})
+	router := concurrencyRouter(limiter, release)
+
+	blocked := startRequest(router, "/block")
+	waitFor(t, func() bool { return limiter.InFlight() == 1 })
+	low := startRequest(router, "/low")
+	waitFor(t, func() bool { return limiter.Queued() == 1 })
+
+	high := startRequest(router, "/high")
+	if status := <-low; status != http.StatusServiceUnavailable {
+		t.Errorf("expected the low priority request to be shed, got %d", status)
+	}
+	waitFor(t, func() bool { return limiter.Queued() == 1 })
+
+	rec := httptest.NewRecorder()
+	router.ServerHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected critical requests to bypass the limiter, got %d", rec.Code)
+	}
+
+	close(release)
+	<-blocked
+	if status := <-high; status != http.StatusOK {
+		t.Errorf("expected the high priority request to be admitted, got %d", status)
+	}
+}
+
+func TestConcurrencyLimiterAdaptive(t *testing.T) {
+	limiter := NewConcurrencyLimiter(ConcurrencyLimitConfig{
+		MaxConcurrent: 10,
+		Adaptive:      &AdaptiveLimitConfig{TargetLatency: 10 * time.Millisecond, MinLimit: 2, MaxLimit: 11},
+	})
+	delay := 20 * time.Millisecond
+	router := goapi.NewRouter()
+	router.Use(limiter.Middleware)
+	router.GET("/", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(delay)
+	})
+
+	router.ServerHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if limiter.Limit() != 9 {
+		t.Errorf("expected a slow request to decrease the limit to 9, got %d", limiter.Limit())
+	}
+	for i := 0; i < 20; i++ {
+		router.ServerHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+	if limiter.Limit() != 2 {
+		t.Errorf("expected the limit to stop at MinLimit, got %d", limiter.Limit())
+	}
+
+	delay = 0
+	for i := 0; i < 100; i++ {
+		router.ServerHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+	if limit := limiter.Limit(); limit <= 2 || limit > 11 {
+		t.Errorf("expected fast requests to increase the limit up to MaxLimit, got %d", limit)
+	}
+}
+
+func TestConcurrencyLimiterSharedAdaptiveConfig(t *testing.T) {
+	adaptive := &AdaptiveLimitConfig{TargetLatency: 10 * time.Millisecond}
+	small := NewConcurrencyLimiter(ConcurrencyLimitConfig{MaxConcurrent: 5, Adaptive: adaptive})
+	large := NewConcurrencyLimiter(ConcurrencyLimitConfig{MaxConcurrent: 50, Adaptive: adaptive})
+
+	if *adaptive != (AdaptiveLimitConfig{TargetLatency: 10 * time.Millisecond}) {
+		t.Errorf("expected the caller's configuration to be left unchanged, got %+v", *adaptive)
+	}
+	if small.config.Adaptive.MaxLimit != 5 || large.config.Adaptive.MaxLimit != 50 {
+		t.Errorf("expected each limiter to default MaxLimit to its own MaxConcurrent, got %d and %d",
+			small.config.Adaptive.MaxLimit, large.config.Adaptive.MaxLimit)
+	}
+}
+
+func TestConcurrencyLimiterRequiresTargetLatency(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected a panic for an adaptive limit without TargetLatency")
+		}
+	}()
+	NewConcurrencyLimiter(ConcurrencyLimitConfig{MaxConcurrent: 10, Adaptive: &AdaptiveLimitConfig{}})
+}
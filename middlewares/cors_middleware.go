@@ -0,0 +1,202 @@
+package middlewares
+
+import (
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	goapi "github.com/carlosealves2/go-api"
+)
+
+// CORSConfig configures the CORS middleware.
+type CORSConfig struct {
+	// AllowOrigins lists the allowed origins. An entry is either an exact origin such as
+	// "https://app.example.com", an origin with a wildcard such as "https://*.example.com", or "*" to allow
+	// any origin. Origins are compared case-insensitively.
+	AllowOrigins []string
+	// AllowOriginPatterns lists regular expressions matched against the whole origin.
+	// The middleware panics if a pattern does not compile.
+	AllowOriginPatterns []string
+	// AllowOriginFunc decides whether an origin is allowed when it matches neither AllowOrigins nor
+	// AllowOriginPatterns.
+	AllowOriginFunc func(origin string) bool
+	// AllowMethods lists the methods allowed in cross-origin requests. When empty, preflight requests are
+	// answered with the methods registered for the requested path.
+	AllowMethods []string
+	// AllowHeaders lists the request headers allowed in cross-origin requests. When empty, the headers
+	// requested in the preflight request are allowed.
+	AllowHeaders []string
+	// ExposeHeaders lists the response headers that browsers make available to scripts.
+	ExposeHeaders []string
+	// AllowCredentials allows cookies and HTTP authentication in cross-origin requests. The request origin
+	// is then echoed instead of "*".
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache a preflight response. Zero leaves it to the browser.
+	MaxAge time.Duration
+}
+
+// defaultCORSMethods are the methods allowed when neither the configuration nor the router provides them.
+var defaultCORSMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete,
+}
+
+// CORS returns a middleware that implements Cross-Origin Resource Sharing.
+//
+// Preflight requests are answered directly with 204 No Content. The router answers OPTIONS requests for
+// every path with registered routes, so preflight works without registering OPTIONS routes, and the
+// allowed methods default to the ones registered for the path. Preflight requests from origins or for
+// methods that are not allowed are rejected with 403 Forbidden. Other requests from allowed origins get
+// the Access-Control-Allow-* headers and continue to the handler; requests from other origins are served
+// without them, so browsers block the response.
+//
+// Example:
+//
+//	api := goapi.NewRouter()
+//	api.Use(middlewares.CORS(middlewares.CORSConfig{
+//		AllowOrigins:     []string{"https://app.example.com", "https://*.example.org"},
+//		AllowCredentials: true,
+//		ExposeHeaders:    []string{"X-Request-ID"},
+//		MaxAge:           10 * time.Minute,
+//	}))
+func CORS(config CORSConfig) goapi.MiddlewareFunc {
+	policy := newCORSPolicy(config)
+
+	return func(next goapi.HandlerFunc) goapi.HandlerFunc {
+		return func(w http.ResponseWriter, req *http.Request) {
+			origin := req.Header.Get("Origin")
+			header := w.Header()
+			if !policy.anyOrigin || config.AllowCredentials {
+				header.Add("Vary", "Origin")
+			}
+
+			if req.Method == http.MethodOptions && req.Header.Get("Access-Control-Request-Method") != "" {
+				header.Add("Vary", "Access-Control-Request-Method")
+				header.Add("Vary", "Access-Control-Request-Headers")
+				policy.preflight(w, req, origin)
+				return
+			}
+
+			if origin != "" && policy.allowOrigin(origin) {
+				policy.setOriginHeaders(header, origin)
+				if len(config.ExposeHeaders) > 0 {
+					header.Set("Access-Control-Expose-Headers", strings.Join(config.ExposeHeaders, ", "))
+				}
+			}
+			next(w, req)
+		}
+	}
+}
+
+// corsPolicy is the compiled form of a CORSConfig.
+type corsPolicy struct {
+	config    CORSConfig
+	anyOrigin bool
+	exact     map[string]bool
+	wildcards [][2]string
+	patterns  []*regexp.Regexp
+	methods   []string
+	maxAge    string
+}
+
+func newCORSPolicy(config CORSConfig) *corsPolicy {
+	policy := &corsPolicy{config: config, exact: make(map[string]bool)}
+	for _, origin := range config.AllowOrigins {
+		origin = strings.ToLower(origin)
+		switch {
+		case origin == "*":
+			policy.anyOrigin = true
+		case strings.Contains(origin, "*"):
+			prefix, suffix, _ := strings.Cut(origin, "*")
+			policy.wildcards = append(policy.wildcards, [2]string{prefix, suffix})
+		default:
+			policy.exact[origin] = true
+		}
+	}
+	for _, pattern := range config.AllowOriginPatterns {
+		policy.patterns = append(policy.patterns, regexp.MustCompile("^(?:"+pattern+")$"))
+	}
+	for _, method := range config.AllowMethods {
+		policy.methods = append(policy.methods, strings.ToUpper(method))
+	}
+	if config.MaxAge > 0 {
+		policy.maxAge = strconv.FormatInt(int64(config.MaxAge/time.Second), 10)
+	}
+	return policy
+}
+
+// allowOrigin reports whether requests from origin are allowed.
+func (p *corsPolicy) allowOrigin(origin string) bool {
+	if p.anyOrigin {
+		return true
+	}
+	lower := strings.ToLower(origin)
+	if p.exact[lower] {
+		return true
+	}
+	for _, wildcard := range p.wildcards {
+		prefix, suffix := wildcard[0], wildcard[1]
+		if len(lower) > len(prefix)+len(suffix) && strings.HasPrefix(lower, prefix) && strings.HasSuffix(lower, suffix) {
+			return true
+		}
+	}
+	for _, pattern := range p.patterns {
+		if pattern.MatchString(origin) {
+			return true
+		}
+	}
+	return p.config.AllowOriginFunc != nil && p.config.AllowOriginFunc(origin)
+}
+
+// setOriginHeaders sets the headers shared by preflight and actual responses.
+func (p *corsPolicy) setOriginHeaders(header http.Header, origin string) {
+	if p.anyOrigin && !p.config.AllowCredentials {
+		header.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		header.Set("Access-Control-Allow-Origin", origin)
+	}
+	if p.config.AllowCredentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+// allowedMethods returns the methods allowed for the request.
+func (p *corsPolicy) allowedMethods(req *http.Request) []string {
+	if len(p.methods) > 0 {
+		return p.methods
+	}
+	if methods := goapi.AllowedMethodsFromContext(req); len(methods) > 0 {
+		return methods
+	}
+	return defaultCORSMethods
+}
+
+// preflight answers a preflight request.
+func (p *corsPolicy) preflight(w http.ResponseWriter, req *http.Request, origin string) {
+	if origin == "" || !p.allowOrigin(origin) {
+		goapi.WriteError(w, req, goapi.NewHTTPError(http.StatusForbidden, "origin not allowed"))
+		return
+	}
+
+	requested := strings.ToUpper(req.Header.Get("Access-Control-Request-Method"))
+	methods := p.allowedMethods(req)
+	if !slices.Contains(methods, requested) {
+		goapi.WriteError(w, req, goapi.NewHTTPError(http.StatusForbidden, "method not allowed by CORS policy"))
+		return
+	}
+
+	header := w.Header()
+	p.setOriginHeaders(header, origin)
+	header.Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+	if len(p.config.AllowHeaders) > 0 {
+		header.Set("Access-Control-Allow-Headers", strings.Join(p.config.AllowHeaders, ", "))
+	} else if requestedHeaders := req.Header.Get("Access-Control-Request-Headers"); requestedHeaders != "" {
+		header.Set("Access-Control-Allow-Headers", requestedHeaders)
+	}
+	if p.maxAge != "" {
+		header.Set("Access-Control-Max-Age", p.maxAge)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
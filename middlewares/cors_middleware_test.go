@@ -0,0 +1,215 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	goapi "github.com/carlosealves2/go-api"
+)
+
+func newCORSRouter(config CORSConfig) *goapi.Router {
+	router := goapi.NewRouter()
+	router.Use(CORS(config))
+	router.GET("/items/:id", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("item"))
+	})
+	router.DELETE("/items/:id", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	return router
+}
+
+func corsRequest(router *goapi.Router, method, origin string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/items/7", nil)
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	rec := httptest.NewRecorder()
+	router.ServerHTTP(rec, req)
+	return rec
+}
+
+func TestCORSOrigins(t *testing.T) {
+	router := newCORSRouter(CORSConfig{
+		AllowOrigins:        []string{"https://app.example.com", "https://*.example.org"},
+		AllowOriginPatterns: []string{`https://preview-\d+\.example\.net`},
+		AllowOriginFunc:     func(origin string) bool { return origin == "http://localhost:3000" },
+	})
+
+	tests := []struct {
+		origin  string
+		allowed bool
+	}{
+		{origin: "https://app.example.com", allowed: true},
+		{origin: "https://APP.example.com", allowed: true},
+		{origin: "https://admin.example.org", allowed: true},
+		{origin: "https://example.org", allowed: false},
+		{origin: "https://preview-42.example.net", allowed: true},
+		{origin: "https://preview-x.example.net", allowed: false},
+		{origin: "http://localhost:3000", allowed: true},
+		{origin: "https://evil.com", allowed: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.origin, func(t *testing.T) {
+			rec := corsRequest(router, http.MethodGet, tt.origin, nil)
+
+			if rec.Code != http.StatusOK || rec.Body.String() != "item" {
+				t.Errorf("expected the handler to run, got %d %q", rec.Code, rec.Body.String())
+			}
+			got := rec.Header().Get("Access-Control-Allow-Origin")
+			if tt.allowed && got != tt.origin {
+				t.Errorf("expected Access-Control-Allow-Origin %q, got %q", tt.origin, got)
+			}
+			if !tt.allowed && got != "" {
+				t.Errorf("expected no Access-Control-Allow-Origin, got %q", got)
+			}
+			if rec.Header().Get("Vary") != "Origin" {
+				t.Errorf("expected Vary: Origin, got %q", rec.Header().Get("Vary"))
+			}
+		})
+	}
+}
+
+func TestCORSAnyOrigin(t *testing.T) {
+	t.Run("without credentials", func(t *testing.T) {
+		router := newCORSRouter(CORSConfig{AllowOrigins: []string{"*"}, ExposeHeaders: []string{"X-Request-ID"}})
+		rec := corsRequest(router, http.MethodGet, "https://any.example", nil)
+
+		if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+			t.Errorf("expected Access-Control-Allow-Origin *, got %q", got)
+		}
+		if got := rec.Header().Get("Access-Control-Expose-Headers"); got != "X-Request-ID" {
+			t.Errorf("expected exposed headers, got %q", got)
+		}
+		if rec.Header().Get("Vary") != "" {
+			t.Errorf("expected no Vary header, got %q", rec.Header().Get("Vary"))
+		}
+	})
+
+	t.Run("with credentials", func(t *testing.T) {
+		router := newCORSRouter(CORSConfig{AllowOrigins: []string{"*"}, AllowCredentials: true})
+		rec := corsRequest(router, http.MethodGet, "https://any.example", nil)
+
+		if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://any.example" {
+			t.Errorf("expected the origin to be echoed, got %q", got)
+		}
+		if got := rec.Header().Get("Access-Control-Allow-Credentials"); got != "true" {
+			t.Errorf("expected Access-Control-Allow-Credentials true, got %q", got)
+		}
+	})
+}
+
+func TestCORSPreflight(t *testing.T) {
+	router := newCORSRouter(CORSConfig{
+		AllowOrigins: []string{"https://app.example.com"},
+		MaxAge:       10 * time.Minute,
+	})
+
+	tests := []struct {
+		name           string
+		origin         string
+		method         string
+		expectedStatus int
+	}{
+		{name: "registered method", origin: "https://app.example.com", method: "DELETE", expectedStatus: http.StatusNoContent},
+		{name: "unregistered method", origin: "https://app.example.com", method: "PUT", expectedStatus: http.StatusForbidden},
+		{name: "disallowed origin", origin: "https://evil.com", method: "GET", expectedStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := corsRequest(router, http.MethodOptions, tt.origin, map[string]string{
+				"Access-Control-Request-Method":  tt.method,
+				"Access-Control-Request-Headers": "Content-Type, Authorization",
+			})
+
+			if rec.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if tt.expectedStatus != http.StatusNoContent {
+				if rec.Header().Get("Access-Control-Allow-Origin") != "" {
+					t.Errorf("expected no CORS headers on a rejected preflight")
+				}
+				return
+			}
+
+			expected := map[string]string{
+				"Access-Control-Allow-Origin":  "https://app.example.com",
+				"Access-Control-Allow-Methods": "DELETE, GET, OPTIONS",
+				"Access-Control-Allow-Headers": "Content-Type, Authorization",
+				"Access-Control-Max-Age":       "600",
+			}
+			for name, value := range expected {
+				if got := rec.Header().Get(name); got != value {
+					t.Errorf("expected %s %q, got %q", name, value, got)
+				}
+			}
+			if vary := strings.Join(rec.Header().Values("Vary"), ", "); !strings.Contains(vary, "Access-Control-Request-Method") {
+				t.Errorf("expected Vary to include Access-Control-Request-Method, got %q", vary)
+			}
+		})
+	}
+}
+
+func TestCORSPreflightConfiguredMethodsAndHeaders(t *testing.T) {
+	router := newCORSRouter(CORSConfig{
+		AllowOrigins: []string{"https://app.example.com"},
+		AllowMethods: []string{"get", "put"},
+		AllowHeaders: []string{"Content-Type"},
+	})
+
+	rec := corsRequest(router, http.MethodOptions, "https://app.example.com", map[string]string{
+		"Access-Control-Request-Method":  "PUT",
+		"Access-Control-Request-Headers": "X-Custom",
+	})
+
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected status %d, got %d", http.StatusNoContent, rec.Code)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Methods"); got != "GET, PUT" {
+		t.Errorf("expected the configured methods, got %q", got)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Headers"); got != "Content-Type" {
+		t.Errorf("expected the configured headers, got %q", got)
+	}
+}
+
+func TestCORSPlainOptionsRequest(t *testing.T) {
+	router := newCORSRouter(CORSConfig{AllowOrigins: []string{"*"}})
+
+	rec := corsRequest(router, http.MethodOptions, "", nil)
+
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected status %d, got %d", http.StatusNoContent, rec.Code)
+	}
+	if got := rec.Header().Get("Allow"); got != "DELETE, GET, OPTIONS" {
+		t.Errorf("expected the router to answer with Allow, got %q", got)
+	}
+}
+
+func TestCORSPreflightSiblingGroup(t *testing.T) {
+	router := goapi.NewRouter()
+	public := router.Group.Group("/items")
+	public.GET("/:id", func(w http.ResponseWriter, r *http.Request) {})
+	admin := router.Group.Group("/items")
+	admin.Use(CORS(CORSConfig{AllowOrigins: []string{"https://admin.example.com"}}))
+	admin.DELETE("/:id", func(w http.ResponseWriter, r *http.Request) {})
+
+	rec := corsRequest(router, http.MethodOptions, "https://admin.example.com", map[string]string{
+		"Access-Control-Request-Method": "DELETE",
+	})
+
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected status %d, got %d", http.StatusNoContent, rec.Code)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://admin.example.com" {
+		t.Errorf("expected the preflight to run the CORS middleware of the DELETE route, got %q", got)
+	}
+}
@@ -0,0 +1,109 @@
+package middlewares
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strings"
+
+	goapi "github.com/carlosealves2/go-api"
+)
+
+// DecompressConfig configures the DecompressWithConfig middleware.
+type DecompressConfig struct {
+	// MaxSize is the maximum size, in bytes, of a decompressed request body. Defaults to 10 MiB.
+	MaxSize int64
+}
+
+// Decompress is a middleware that transparently decompresses gzip and deflate request bodies.
+// See DecompressWithConfig for the details.
+func Decompress(next goapi.HandlerFunc) goapi.HandlerFunc {
+	return DecompressWithConfig(DecompressConfig{})(next)
+}
+
+// DecompressWithConfig returns a middleware that decompresses request bodies sent with a Content-Encoding
+// of gzip or deflate, including several encodings applied in sequence. The handler reads the decompressed
+// body, and the Content-Encoding and Content-Length headers are removed.
+//
+// The decompressed body is limited to MaxSize bytes to defend against decompression bombs: reading past
+// the limit fails with an *http.MaxBytesError, which DecodeJSON and Bind report as 413 Request Entity Too
+// Large. The size limit set with Group.MaxBodySize still applies to the compressed body. Requests with an
+// unsupported encoding are rejected with 415 Unsupported Media Type and bodies whose compression header is
+// malformed with 400 Bad Request.
+//
+// Example:
+//
+//	ingest := api.Group("/ingest")
+//	ingest.Use(middlewares.DecompressWithConfig(middlewares.DecompressConfig{MaxSize: 50 << 20}))
+func DecompressWithConfig(config DecompressConfig) goapi.MiddlewareFunc {
+	if config.MaxSize <= 0 {
+		config.MaxSize = 10 << 20
+	}
+
+	return func(next goapi.HandlerFunc) goapi.HandlerFunc {
+		return func(w http.ResponseWriter, req *http.Request) {
+			encodings := contentEncodings(req.Header)
+			if len(encodings) == 0 {
+				next(w, req)
+				return
+			}
+
+			for _, encoding := range encodings {
+				if encoding != "gzip" && encoding != "x-gzip" && encoding != "deflate" && encoding != "identity" {
+					w.Header().Set("Accept-Encoding", "gzip, deflate")
+					goapi.WriteError(w, req, goapi.NewHTTPError(http.StatusUnsupportedMediaType,
+						"unsupported content encoding "+encoding))
+					return
+				}
+			}
+
+			body := req.Body
+			// Encodings are listed in the order they were applied, so they are removed in reverse.
+			var reader io.Reader = body
+			for i := len(encodings) - 1; i >= 0; i-- {
+				var err error
+				switch encodings[i] {
+				case "gzip", "x-gzip":
+					reader, err = gzip.NewReader(reader)
+				case "deflate":
+					reader, err = zlib.NewReader(reader)
+				}
+				if err != nil {
+					goapi.WriteError(w, req, &goapi.HTTPError{
+						Status:  http.StatusBadRequest,
+						Message: "malformed " + encodings[i] + " request body",
+						Err:     err,
+					})
+					return
+				}
+			}
+
+			req = req.Clone(req.Context())
+			req.Body = http.MaxBytesReader(w, readCloser{Reader: reader, Closer: body}, config.MaxSize)
+			req.ContentLength = -1
+			req.Header.Del("Content-Encoding")
+			req.Header.Del("Content-Length")
+			next(w, req)
+		}
+	}
+}
+
+// contentEncodings returns the codings listed in the Content-Encoding headers, lowercased and in order.
+func contentEncodings(header http.Header) []string {
+	var encodings []string
+	for _, value := range header.Values("Content-Encoding") {
+		for _, coding := range strings.Split(value, ",") {
+			if coding = strings.ToLower(strings.TrimSpace(coding)); coding != "" {
+				encodings = append(encodings, coding)
+			}
+		}
+	}
+	return encodings
+}
+
+// readCloser reads from a decompressor and closes the original request body.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,131 @@
+package middlewares
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	goapi "github.com/carlosealves2/go-api"
+)
+
+func gzipBytes(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	w.Write(data)
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to gzip: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func zlibBytes(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	w.Write(data)
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to deflate: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func newDecompressRouter(config DecompressConfig) *goapi.Router {
+	router := goapi.NewRouter()
+	router.Use(DecompressWithConfig(config))
+	router.POST("/ingest", func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]any
+		if err := goapi.DecodeJSON(r, &payload); err != nil {
+			goapi.WriteError(w, r, err)
+			return
+		}
+		w.Header().Set("X-Content-Encoding", r.Header.Get("Content-Encoding"))
+		json.NewEncoder(w).Encode(payload)
+	})
+	return router
+}
+
+func TestDecompress(t *testing.T) {
+	payload := []byte(`{"event":"signup","count":3}`)
+
+	tests := []struct {
+		name           string
+		encoding       string
+		body           []byte
+		expectedStatus int
+	}{
+		{name: "plain", encoding: "", body: payload, expectedStatus: http.StatusOK},
+		{name: "identity", encoding: "identity", body: payload, expectedStatus: http.StatusOK},
+		{name: "gzip", encoding: "gzip", body: gzipBytes(t, payload), expectedStatus: http.StatusOK},
+		{name: "x-gzip", encoding: "x-gzip", body: gzipBytes(t, payload), expectedStatus: http.StatusOK},
+		{name: "deflate", encoding: "deflate", body: zlibBytes(t, payload), expectedStatus: http.StatusOK},
+		{name: "stacked", encoding: "deflate, gzip", body: gzipBytes(t, zlibBytes(t, payload)), expectedStatus: http.StatusOK},
+		{name: "unsupported", encoding: "br", body: payload, expectedStatus: http.StatusUnsupportedMediaType},
+		{name: "malformed", encoding: "gzip", body: payload, expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newDecompressRouter(DecompressConfig{})
+			req := httptest.NewRequest(http.MethodPost, "/ingest", bytes.NewReader(tt.body))
+			if tt.encoding != "" {
+				req.Header.Set("Content-Encoding", tt.encoding)
+			}
+			rec := httptest.NewRecorder()
+			router.ServerHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, rec.Code, rec.Body.String())
+			}
+			if tt.expectedStatus == http.StatusUnsupportedMediaType && rec.Header().Get("Accept-Encoding") != "gzip, deflate" {
+				t.Errorf("expected Accept-Encoding to list the supported encodings, got %q", rec.Header().Get("Accept-Encoding"))
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+			if rec.Header().Get("X-Content-Encoding") != "" {
+				t.Errorf("expected Content-Encoding to be removed from the request")
+			}
+			if strings.TrimSpace(rec.Body.String()) != `{"count":3,"event":"signup"}` {
+				t.Errorf("unexpected body %q", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestDecompressMaxSize(t *testing.T) {
+	// A small gzip body expanding to far more than the limit.
+	bomb := gzipBytes(t, []byte(`{"data":"`+strings.Repeat("a", 1<<20)+`"}`))
+
+	router := newDecompressRouter(DecompressConfig{MaxSize: 1024})
+	req := httptest.NewRequest(http.MethodPost, "/ingest", bytes.NewReader(bomb))
+	req.Header.Set("Content-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	router.ServerHTTP(rec, req)
+
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected status %d, got %d", http.StatusRequestEntityTooLarge, rec.Code)
+	}
+}
+
+func TestDecompressDefaultConfig(t *testing.T) {
+	handler := Decompress(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength != -1 {
+			t.Errorf("expected an unknown content length, got %d", r.ContentLength)
+		}
+		data, err := io.ReadAll(r.Body)
+		if err != nil || string(data) != "hello" {
+			t.Errorf("expected the decompressed body, got %q (%v)", data, err)
+		}
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(gzipBytes(t, []byte("hello"))))
+	req.Header.Set("Content-Encoding", "GZIP")
+	handler(httptest.NewRecorder(), req)
+}
@@ -0,0 +1,215 @@
+package middlewares
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"time"
+
+	goapi "github.com/carlosealves2/go-api"
+)
+
+// ETagConfig configures the ETagWithConfig middleware.
+type ETagConfig struct {
+	// Weak generates weak ETags (W/"..."), for representations that are equivalent rather than
+	// byte-for-byte identical, such as responses compressed afterwards.
+	Weak bool
+	// MaxBufferSize is the largest response, in bytes, that is buffered to compute its ETag. Larger and
+	// flushed responses are streamed without one unless the handler set it. Defaults to 1 MiB.
+	MaxBufferSize int
+	// Validators returns the current ETag and modification time of the resource addressed by an unsafe
+	// request (POST, PUT, PATCH, DELETE), so that If-Match and If-Unmodified-Since are evaluated before the
+	// handler runs. ok is false when the resource does not exist. When nil, unsafe requests are left to
+	// the handler, which can use goapi.CheckPreconditions.
+	Validators func(r *http.Request) (etag string, modified time.Time, ok bool)
+}
+
+// ETag is a middleware that adds strong ETags to GET and HEAD responses and answers conditional requests.
+// See ETagWithConfig for the details.
+func ETag(next goapi.HandlerFunc) goapi.HandlerFunc {
+	return ETagWithConfig(ETagConfig{})(next)
+}
+
+// ETagWithConfig returns a middleware that handles entity tags and conditional requests.
+//
+// Successful GET and HEAD responses are buffered up to MaxBufferSize bytes. Unless the handler set an ETag,
+// one is computed by hashing the body. The request's conditional headers are then evaluated against the
+// ETag and the Last-Modified header: a client that already has the representation gets 304 Not Modified
+// without a body, and a failed If-Match or If-Unmodified-Since gets 412 Precondition Failed.
+//
+// Requests with an Upgrade header, such as WebSocket handshakes, are passed through untouched.
+//
+// Unsafe requests are checked before the handler runs when Validators is set, so that an update based on
+// a stale representation is rejected with 412 without reaching the handler.
+//
+// Example:
+//
+//	api.Use(middlewares.ETagWithConfig(middlewares.ETagConfig{
+//		Validators: func(r *http.Request) (string, time.Time, bool) {
+//			order, err := orders.Find(goapi.ParamsFromContext(r)["id"])
+//			if err != nil {
+//				return "", time.Time{}, false
+//			}
+//			return strconv.Itoa(order.Version), order.UpdatedAt, true
+//		},
+//	}))
+func ETagWithConfig(config ETagConfig) goapi.MiddlewareFunc {
+	if config.MaxBufferSize <= 0 {
+		config.MaxBufferSize = 1 << 20
+	}
+
+	return func(next goapi.HandlerFunc) goapi.HandlerFunc {
+		return func(w http.ResponseWriter, req *http.Request) {
+			if req.Method != http.MethodGet && req.Method != http.MethodHead {
+				if config.Validators != nil && hasPreconditions(req) {
+					etag, modified, ok := config.Validators(req)
+					if !ok {
+						etag, modified = "", time.Time{}
+					} else if etag != "" {
+						etag = goapi.FormatETag(etag)
+					}
+					if status := goapi.EvaluatePreconditions(req, etag, modified); status != 0 {
+						goapi.WriteError(w, req, goapi.NewHTTPError(status, ""))
+						return
+					}
+				}
+				next(w, req)
+				return
+			}
+
+			// Protocol upgrades such as WebSocket take over the connection, which must not be buffered.
+			if req.Header.Get("Upgrade") != "" {
+				next(w, req)
+				return
+			}
+
+			ew := &etagWriter{ResponseWriter: w, config: &config, req: req}
+			next(ew, req)
+			ew.finish()
+		}
+	}
+}
+
+// hasPreconditions reports whether the request carries a header evaluated for unsafe methods.
+func hasPreconditions(req *http.Request) bool {
+	return req.Header.Get("If-Match") != "" || req.Header.Get("If-Unmodified-Since") != "" ||
+		req.Header.Get("If-None-Match") != ""
+}
+
+// etagWriter buffers a successful response until its ETag is known.
+type etagWriter struct {
+	http.ResponseWriter
+	config      *ETagConfig
+	req         *http.Request
+	status      int
+	wroteHeader bool
+	streaming   bool
+	buf         []byte
+}
+
+func (ew *etagWriter) WriteHeader(code int) {
+	if ew.wroteHeader {
+		return
+	}
+	if code >= 100 && code < 200 && code != http.StatusSwitchingProtocols {
+		ew.ResponseWriter.WriteHeader(code)
+		return
+	}
+
+	ew.wroteHeader = true
+	ew.status = code
+	if code != http.StatusOK {
+		_ = ew.stream()
+	}
+}
+
+func (ew *etagWriter) Write(b []byte) (int, error) {
+	if !ew.wroteHeader {
+		ew.WriteHeader(http.StatusOK)
+	}
+	if ew.streaming {
+		return ew.ResponseWriter.Write(b)
+	}
+
+	if len(ew.buf)+len(b) > ew.config.MaxBufferSize {
+		if err := ew.stream(); err != nil {
+			return 0, err
+		}
+		return ew.ResponseWriter.Write(b)
+	}
+	ew.buf = append(ew.buf, b...)
+	return len(b), nil
+}
+
+// Flush gives up on buffering and streams the response.
+func (ew *etagWriter) Flush() {
+	if !ew.wroteHeader {
+		ew.WriteHeader(http.StatusOK)
+	}
+	_ = ew.stream()
+	_ = http.NewResponseController(ew.ResponseWriter).Flush()
+}
+
+// Unwrap returns the wrapped writer, so http.ResponseController reaches its other features.
+func (ew *etagWriter) Unwrap() http.ResponseWriter {
+	return ew.ResponseWriter
+}
+
+// stream writes the header and the buffered body, and passes further writes through.
+func (ew *etagWriter) stream() error {
+	if ew.streaming {
+		return nil
+	}
+	ew.streaming = true
+	ew.ResponseWriter.WriteHeader(ew.status)
+
+	buf := ew.buf
+	ew.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	_, err := ew.ResponseWriter.Write(buf)
+	return err
+}
+
+// finish sets the ETag of a buffered response, answers the conditional request and sends the body.
+func (ew *etagWriter) finish() {
+	if ew.streaming {
+		return
+	}
+	if !ew.wroteHeader {
+		ew.status = http.StatusOK
+	}
+
+	header := ew.Header()
+	if header.Get("ETag") == "" && (len(ew.buf) > 0 || ew.req.Method == http.MethodGet) {
+		sum := sha256.Sum256(ew.buf)
+		etag := `"` + base64.RawURLEncoding.EncodeToString(sum[:16]) + `"`
+		if ew.config.Weak {
+			etag = "W/" + etag
+		}
+		header.Set("ETag", etag)
+	}
+
+	var modified time.Time
+	if value := header.Get("Last-Modified"); value != "" {
+		modified, _ = http.ParseTime(value)
+	}
+	switch goapi.EvaluatePreconditions(ew.req, header.Get("ETag"), modified) {
+	case http.StatusNotModified:
+		goapi.WriteNotModified(ew.ResponseWriter)
+		return
+	case http.StatusPreconditionFailed:
+		header.Del("ETag")
+		header.Del("Last-Modified")
+		header.Del("Content-Length")
+		goapi.WriteError(ew.ResponseWriter, ew.req, goapi.NewHTTPError(http.StatusPreconditionFailed, ""))
+		return
+	}
+
+	if len(ew.buf) > 0 && header.Get("Content-Length") == "" && header.Get("Content-Encoding") == "" {
+		header.Set("Content-Length", strconv.Itoa(len(ew.buf)))
+	}
+	_ = ew.stream()
+}
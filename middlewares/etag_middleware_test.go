@@ -0,0 +1,191 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	goapi "github.com/carlosealves2/go-api"
+)
+
+func etagRequest(router *goapi.Router, method, path string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	rec := httptest.NewRecorder()
+	router.ServerHTTP(rec, req)
+	return rec
+}
+
+func TestETag(t *testing.T) {
+	router := goapi.NewRouter()
+	router.Use(ETag)
+	router.GET("/doc", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("hello "))
+		w.Write([]byte("world"))
+	})
+
+	first := etagRequest(router, http.MethodGet, "/doc", nil)
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || first.Body.String() != "hello world" {
+		t.Fatalf("unexpected response %d %q", first.Code, first.Body.String())
+	}
+	if !strings.HasPrefix(etag, `"`) || len(etag) < 10 {
+		t.Fatalf("expected a strong ETag, got %q", etag)
+	}
+	if first.Header().Get("Content-Length") != "11" {
+		t.Errorf("expected Content-Length 11, got %q", first.Header().Get("Content-Length"))
+	}
+
+	tests := []struct {
+		name           string
+		headers        map[string]string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{name: "matching if-none-match", headers: map[string]string{"If-None-Match": etag}, expectedStatus: http.StatusNotModified},
+		{name: "weak if-none-match", headers: map[string]string{"If-None-Match": "W/" + etag}, expectedStatus: http.StatusNotModified},
+		{name: "stale if-none-match", headers: map[string]string{"If-None-Match": `"stale"`}, expectedStatus: http.StatusOK, expectedBody: "hello world"},
+		{name: "failed if-match", headers: map[string]string{"If-Match": `"stale"`}, expectedStatus: http.StatusPreconditionFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := etagRequest(router, http.MethodGet, "/doc", tt.headers)
+
+			if rec.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if tt.expectedStatus == http.StatusNotModified {
+				if rec.Body.Len() != 0 || rec.Header().Get("Content-Type") != "" {
+					t.Errorf("expected a 304 without body headers, got %q", rec.Body.String())
+				}
+				if rec.Header().Get("ETag") != etag {
+					t.Errorf("expected the ETag on the 304, got %q", rec.Header().Get("ETag"))
+				}
+			}
+			if tt.expectedBody != "" && rec.Body.String() != tt.expectedBody {
+				t.Errorf("expected body %q, got %q", tt.expectedBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestETagHandlerValidators(t *testing.T) {
+	router := goapi.NewRouter()
+	router.Use(ETagWithConfig(ETagConfig{Weak: true}))
+	router.GET("/versioned", func(w http.ResponseWriter, r *http.Request) {
+		goapi.SetETag(w, "v7")
+		goapi.SetLastModified(w, time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+		w.Write([]byte("content"))
+	})
+	router.GET("/computed", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("content"))
+	})
+
+	rec := etagRequest(router, http.MethodGet, "/versioned", map[string]string{"If-None-Match": `"v7"`})
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("expected the handler's ETag to be used, got %d", rec.Code)
+	}
+
+	rec = etagRequest(router, http.MethodGet, "/versioned", map[string]string{
+		"If-Modified-Since": "Wed, 01 May 2024 12:00:00 GMT",
+	})
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("expected Last-Modified to be honored, got %d", rec.Code)
+	}
+
+	rec = etagRequest(router, http.MethodGet, "/computed", nil)
+	if etag := rec.Header().Get("ETag"); !strings.HasPrefix(etag, `W/"`) {
+		t.Errorf("expected a weak ETag, got %q", etag)
+	}
+}
+
+func TestETagSkipsLargeAndErrorResponses(t *testing.T) {
+	router := goapi.NewRouter()
+	router.Use(ETagWithConfig(ETagConfig{MaxBufferSize: 10}))
+	router.GET("/large", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("x", 8)))
+		w.Write([]byte(strings.Repeat("y", 8)))
+	})
+	router.GET("/missing", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "not here", http.StatusNotFound)
+	})
+
+	rec := etagRequest(router, http.MethodGet, "/large", nil)
+	if rec.Header().Get("ETag") != "" || rec.Body.String() != "xxxxxxxxyyyyyyyy" {
+		t.Errorf("expected a streamed response without ETag, got %q %q", rec.Header().Get("ETag"), rec.Body.String())
+	}
+
+	rec = etagRequest(router, http.MethodGet, "/missing", nil)
+	if rec.Code != http.StatusNotFound || rec.Header().Get("ETag") != "" {
+		t.Errorf("expected an error response without ETag, got %d %q", rec.Code, rec.Header().Get("ETag"))
+	}
+}
+
+func TestETagSkipsUpgrades(t *testing.T) {
+	router := goapi.NewRouter()
+	router.Use(ETag)
+	wrapped := false
+	router.GET("/ws", func(w http.ResponseWriter, r *http.Request) {
+		_, wrapped = w.(*etagWriter)
+		w.Write([]byte("upgraded"))
+	})
+
+	rec := etagRequest(router, http.MethodGet, "/ws", map[string]string{"Connection": "Upgrade", "Upgrade": "websocket"})
+
+	if wrapped {
+		t.Errorf("expected the upgrade request to get the original writer")
+	}
+	if rec.Header().Get("ETag") != "" || rec.Body.String() != "upgraded" {
+		t.Errorf("expected the response to pass through, got %q %q", rec.Header().Get("ETag"), rec.Body.String())
+	}
+}
+
+func TestETagUnsafeMethods(t *testing.T) {
+	var updates int
+	router := goapi.NewRouter()
+	router.Use(ETagWithConfig(ETagConfig{
+		Validators: func(r *http.Request) (string, time.Time, bool) {
+			if goapi.ParamsFromContext(r)["id"] != "1" {
+				return "", time.Time{}, false
+			}
+			return "v2", time.Time{}, true
+		},
+	}))
+	router.PUT("/orders/:id", func(w http.ResponseWriter, r *http.Request) {
+		updates++
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		name           string
+		path           string
+		headers        map[string]string
+		expectedStatus int
+	}{
+		{name: "current version", path: "/orders/1", headers: map[string]string{"If-Match": `"v2"`}, expectedStatus: http.StatusNoContent},
+		{name: "stale version", path: "/orders/1", headers: map[string]string{"If-Match": `"v1"`}, expectedStatus: http.StatusPreconditionFailed},
+		{name: "missing resource", path: "/orders/2", headers: map[string]string{"If-Match": "*"}, expectedStatus: http.StatusPreconditionFailed},
+		{name: "create only", path: "/orders/1", headers: map[string]string{"If-None-Match": "*"}, expectedStatus: http.StatusPreconditionFailed},
+		{name: "unconditional", path: "/orders/1", expectedStatus: http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := updates
+			rec := etagRequest(router, http.MethodPut, tt.path, tt.headers)
+
+			if rec.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if ran := updates > before; ran != (tt.expectedStatus == http.StatusNoContent) {
+				t.Errorf("unexpected handler execution: %v", ran)
+			}
+		})
+	}
+}
@@ -0,0 +1,288 @@
+package middlewares
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+
+	goapi "github.com/carlosealves2/go-api"
+)
+
+// IdempotencyConfig configures the Idempotency middleware.
+type IdempotencyConfig struct {
+	// Store keeps the keys and responses. Defaults to a new MemoryIdempotencyStore.
+	Store IdempotencyStore
+	// TTL is how long a key and its response are kept. Defaults to 24 hours.
+	TTL time.Duration
+	// Header is the request header carrying the key. Defaults to "Idempotency-Key".
+	Header string
+	// Methods are the methods the middleware applies to. Defaults to POST and PATCH.
+	Methods []string
+	// Required rejects requests without a key with 400 Bad Request.
+	Required bool
+	// Wait is how long a request waits for a concurrent request with the same key to complete, to replay
+	// its response. Zero answers 409 Conflict immediately.
+	Wait time.Duration
+	// Scope returns the namespace of the keys of a request, typically the authenticated user, so that
+	// clients cannot replay each other's responses. Defaults to a single namespace.
+	Scope func(r *http.Request) string
+	// MaxBodySize is the largest request body, in bytes, read to fingerprint a request with a key. Larger
+	// requests are rejected with 413 Request Entity Too Large. Defaults to 1 MiB.
+	MaxBodySize int64
+	// MaxResponseSize is the largest response body, in bytes, that is stored for replay. Larger responses
+	// are sent but not stored, and their key is released. Defaults to 1 MiB.
+	MaxResponseSize int
+}
+
+// Idempotency returns a middleware implementing the Idempotency-Key header, which makes retries of unsafe
+// requests, such as payments, safe.
+//
+// The first request with a key is executed and its response (status, headers and body) stored. Requests
+// repeating the key get the stored response, with an "Idempotent-Replayed: true" header, without running the
+// handler. A request reusing a key while the first one is still in progress waits up to Wait for it, then
+// gets 409 Conflict; one reusing it for a different request (method, URL or body) gets 422 Unprocessable
+// Entity. Keys of requests that fail with a 5xx status or a panic are released so the client can retry.
+// Request bodies are buffered up to MaxBodySize and responses stored up to MaxResponseSize.
+//
+// Example:
+//
+//	payments := api.Group("/payments")
+//	payments.Use(middlewares.Idempotency(middlewares.IdempotencyConfig{
+//		Required: true,
+//		Wait:     5 * time.Second,
+//	}))
+func Idempotency(config IdempotencyConfig) goapi.MiddlewareFunc {
+	if config.Store == nil {
+		config.Store = NewMemoryIdempotencyStore()
+	}
+	if config.TTL <= 0 {
+		config.TTL = 24 * time.Hour
+	}
+	if config.Header == "" {
+		config.Header = "Idempotency-Key"
+	}
+	if len(config.Methods) == 0 {
+		config.Methods = []string{http.MethodPost, http.MethodPatch}
+	}
+	if config.MaxBodySize <= 0 {
+		config.MaxBodySize = 1 << 20
+	}
+	if config.MaxResponseSize <= 0 {
+		config.MaxResponseSize = 1 << 20
+	}
+
+	return func(next goapi.HandlerFunc) goapi.HandlerFunc {
+		return func(w http.ResponseWriter, req *http.Request) {
+			if !slices.Contains(config.Methods, req.Method) {
+				next(w, req)
+				return
+			}
+
+			key := req.Header.Get(config.Header)
+			if key == "" {
+				if config.Required {
+					goapi.WriteError(w, req, goapi.NewHTTPError(http.StatusBadRequest, "missing "+config.Header+" header"))
+					return
+				}
+				next(w, req)
+				return
+			}
+			if len(key) > 255 {
+				goapi.WriteError(w, req, goapi.NewHTTPError(http.StatusBadRequest, "invalid "+config.Header+" header"))
+				return
+			}
+			if config.Scope != nil {
+				key = config.Scope(req) + ":" + key
+			}
+
+			fingerprint, err := requestFingerprint(req, config.MaxBodySize)
+			if err != nil {
+				var maxBytesErr *http.MaxBytesError
+				if errors.As(err, &maxBytesErr) {
+					goapi.WriteError(w, req, goapi.NewHTTPError(http.StatusRequestEntityTooLarge, ""))
+					return
+				}
+				goapi.WriteError(w, req, goapi.NewHTTPError(http.StatusBadRequest, "failed to read the request body"))
+				return
+			}
+
+			ctx := req.Context()
+			record, reserved, err := config.Store.Reserve(ctx, key, fingerprint, config.TTL)
+			if err != nil {
+				goapi.WriteError(w, req, err)
+				return
+			}
+			if !reserved {
+				replayIdempotent(w, req, config, key, fingerprint, record)
+				return
+			}
+
+			rec := &idempotencyRecorder{ResponseWriter: w, limit: config.MaxResponseSize}
+			// The outcome is recorded even if the client went away.
+			ctx = context.WithoutCancel(ctx)
+			completed := false
+			defer func() {
+				if !completed {
+					_ = config.Store.Release(ctx, key)
+				}
+			}()
+			next(rec, req)
+
+			status := rec.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			if status >= http.StatusInternalServerError || rec.truncated {
+				return
+			}
+			if err := config.Store.Complete(ctx, key, &IdempotencyRecord{
+				Fingerprint: fingerprint,
+				Completed:   true,
+				Status:      status,
+				Header:      rec.header,
+				Body:        rec.body,
+				Created:     time.Now(),
+			}, config.TTL); err == nil {
+				completed = true
+			}
+		}
+	}
+}
+
+// replayIdempotent answers a request whose key is already in use.
+func replayIdempotent(w http.ResponseWriter, req *http.Request, config IdempotencyConfig, key, fingerprint string, record *IdempotencyRecord) {
+	if record.Fingerprint != fingerprint {
+		goapi.WriteError(w, req, goapi.NewHTTPError(http.StatusUnprocessableEntity,
+			config.Header+" was already used for a different request"))
+		return
+	}
+
+	if !record.Completed && config.Wait > 0 {
+		deadline := time.NewTimer(config.Wait)
+		defer deadline.Stop()
+		ticker := time.NewTicker(10 * time.Millisecond)
+		defer ticker.Stop()
+
+	wait:
+		for {
+			select {
+			case <-deadline.C:
+				break wait
+			case <-req.Context().Done():
+				break wait
+			case <-ticker.C:
+			}
+
+			current, ok, err := config.Store.Get(req.Context(), key)
+			if err != nil {
+				goapi.WriteError(w, req, err)
+				return
+			}
+			if !ok {
+				// The first request failed and released the key.
+				break
+			}
+			if current.Completed {
+				record = current
+				break
+			}
+		}
+	}
+
+	if !record.Completed {
+		w.Header().Set("Retry-After", "1")
+		goapi.WriteError(w, req, goapi.NewHTTPError(http.StatusConflict,
+			"a request with the same "+config.Header+" is in progress"))
+		return
+	}
+
+	// Headers already set by the middlewares of this request, such as its request ID, are kept.
+	header := w.Header()
+	for name, values := range record.Header {
+		if _, ok := header[name]; !ok {
+			header[name] = slices.Clone(values)
+		}
+	}
+	header.Set("Idempotent-Replayed", "true")
+	w.WriteHeader(record.Status)
+	if req.Method != http.MethodHead {
+		_, _ = w.Write(record.Body)
+	}
+}
+
+// requestFingerprint hashes the method, URL and body of the request. The body is read and replaced so the
+// handler can still read it. A body larger than limit bytes is reported as an *http.MaxBytesError.
+func requestFingerprint(req *http.Request, limit int64) (string, error) {
+	hash := sha256.New()
+	_, _ = io.WriteString(hash, req.Method+" "+req.URL.RequestURI()+"\n")
+	if req.Body != nil && req.Body != http.NoBody {
+		if req.ContentLength > limit {
+			return "", &http.MaxBytesError{Limit: limit}
+		}
+		body, err := io.ReadAll(io.LimitReader(req.Body, limit+1))
+		if err != nil {
+			return "", err
+		}
+		if int64(len(body)) > limit {
+			return "", &http.MaxBytesError{Limit: limit}
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		hash.Write(body)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// idempotencyRecorder records the response sent to the client, as long as its body fits in limit bytes.
+type idempotencyRecorder struct {
+	http.ResponseWriter
+	header    http.Header
+	status    int
+	body      []byte
+	limit     int
+	truncated bool
+}
+
+func (rec *idempotencyRecorder) WriteHeader(code int) {
+	if rec.status != 0 {
+		return
+	}
+	if code >= 100 && code < 200 && code != http.StatusSwitchingProtocols {
+		rec.ResponseWriter.WriteHeader(code)
+		return
+	}
+	rec.status = code
+	rec.header = rec.ResponseWriter.Header().Clone()
+	rec.ResponseWriter.WriteHeader(code)
+}
+
+func (rec *idempotencyRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.WriteHeader(http.StatusOK)
+	}
+	if !rec.truncated {
+		if len(rec.body)+len(b) > rec.limit {
+			rec.truncated, rec.body = true, nil
+		} else {
+			rec.body = append(rec.body, b...)
+		}
+	}
+	return rec.ResponseWriter.Write(b)
+}
+
+func (rec *idempotencyRecorder) Flush() {
+	if rec.status == 0 {
+		rec.WriteHeader(http.StatusOK)
+	}
+	_ = http.NewResponseController(rec.ResponseWriter).Flush()
+}
+
+// Unwrap returns the wrapped writer, so http.ResponseController reaches its other features.
+func (rec *idempotencyRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
@@ -0,0 +1,214 @@
+package middlewares
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	goapi "github.com/carlosealves2/go-api"
+)
+
+func idempotencyRequest(router *goapi.Router, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/payments", strings.NewReader(body))
+	if key != "" {
+		req.Header.Set("Idempotency-Key", key)
+	}
+	rec := httptest.NewRecorder()
+	router.ServerHTTP(rec, req)
+	return rec
+}
+
+func TestIdempotency(t *testing.T) {
+	var calls atomic.Int64
+	router := goapi.NewRouter()
+	router.Use(Idempotency(IdempotencyConfig{}))
+	router.POST("/payments", func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		w.Header().Set("X-Payment", strconv.FormatInt(n, 10))
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("payment " + strconv.FormatInt(n, 10)))
+	})
+
+	first := idempotencyRequest(router, "key-1", `{"amount":10}`)
+	if first.Code != http.StatusCreated || first.Body.String() != "payment 1" {
+		t.Fatalf("unexpected response %d %q", first.Code, first.Body.String())
+	}
+
+	tests := []struct {
+		name           string
+		key            string
+		body           string
+		expectedStatus int
+		expectedBody   string
+		replayed       bool
+	}{
+		{name: "retry is replayed", key: "key-1", body: `{"amount":10}`, expectedStatus: http.StatusCreated, expectedBody: "payment 1", replayed: true},
+		{name: "different body", key: "key-1", body: `{"amount":99}`, expectedStatus: http.StatusUnprocessableEntity, expectedBody: "different request"},
+		{name: "new key", key: "key-2", body: `{"amount":10}`, expectedStatus: http.StatusCreated, expectedBody: "payment 2"},
+		{name: "no key", body: `{"amount":10}`, expectedStatus: http.StatusCreated, expectedBody: "payment 3"},
+		{name: "key too long", key: strings.Repeat("k", 256), expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := idempotencyRequest(router, tt.key, tt.body)
+			if rec.Code != tt.expectedStatus || !strings.Contains(rec.Body.String(), tt.expectedBody) {
+				t.Errorf("expected %d %q, got %d %q", tt.expectedStatus, tt.expectedBody, rec.Code, rec.Body.String())
+			}
+			if replayed := rec.Header().Get("Idempotent-Replayed") == "true"; replayed != tt.replayed {
+				t.Errorf("expected replayed %v, got %v", tt.replayed, replayed)
+			}
+			if tt.replayed && rec.Header().Get("X-Payment") != "1" {
+				t.Errorf("expected the stored headers to be replayed, got %v", rec.Header())
+			}
+		})
+	}
+}
+
+func TestIdempotencyRequired(t *testing.T) {
+	router := goapi.NewRouter()
+	router.Use(Idempotency(IdempotencyConfig{Required: true}))
+	router.POST("/payments", func(w http.ResponseWriter, r *http.Request) {})
+	router.GET("/payments", func(w http.ResponseWriter, r *http.Request) {})
+
+	if rec := idempotencyRequest(router, "", "{}"); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected a missing key to be rejected, got %d", rec.Code)
+	}
+	rec := httptest.NewRecorder()
+	router.ServerHTTP(rec, httptest.NewRequest(http.MethodGet, "/payments", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected safe methods to be ignored, got %d", rec.Code)
+	}
+}
+
+func TestIdempotencyConcurrentRequests(t *testing.T) {
+	tests := []struct {
+		name           string
+		wait           time.Duration
+		expectedStatus int
+		expectedBody   string
+	}{
+		{name: "conflict", expectedStatus: http.StatusConflict, expectedBody: "in progress"},
+		{name: "wait for the first request", wait: time.Second, expectedStatus: http.StatusCreated, expectedBody: "done"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			started := make(chan struct{})
+			release := make(chan struct{})
+			router := goapi.NewRouter()
+			router.Use(Idempotency(IdempotencyConfig{Wait: tt.wait}))
+			router.POST("/payments", func(w http.ResponseWriter, r *http.Request) {
+				close(started)
+				<-release
+				w.WriteHeader(http.StatusCreated)
+				w.Write([]byte("done"))
+			})
+
+			first := make(chan *httptest.ResponseRecorder, 1)
+			go func() {
+				first <- idempotencyRequest(router, "key", "{}")
+			}()
+			<-started
+
+			if tt.wait > 0 {
+				time.AfterFunc(30*time.Millisecond, func() { close(release) })
+			}
+			rec := idempotencyRequest(router, "key", "{}")
+			if tt.wait == 0 {
+				close(release)
+			}
+
+			if rec.Code != tt.expectedStatus || !strings.Contains(rec.Body.String(), tt.expectedBody) {
+				t.Errorf("expected %d %q, got %d %q", tt.expectedStatus, tt.expectedBody, rec.Code, rec.Body.String())
+			}
+			if result := <-first; result.Code != http.StatusCreated {
+				t.Errorf("expected the first request to succeed, got %d", result.Code)
+			}
+		})
+	}
+}
+
+func TestIdempotencyReleasesFailedRequests(t *testing.T) {
+	var fail atomic.Bool
+	fail.Store(true)
+	router := goapi.NewRouter()
+	router.Use(Idempotency(IdempotencyConfig{}))
+	router.POST("/payments", func(w http.ResponseWriter, r *http.Request) {
+		if fail.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	if rec := idempotencyRequest(router, "key", "{}"); rec.Code != http.StatusBadGateway {
+		t.Fatalf("expected 502, got %d", rec.Code)
+	}
+	fail.Store(false)
+	rec := idempotencyRequest(router, "key", "{}")
+	if rec.Code != http.StatusCreated || rec.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("expected the retry to run the handler, got %d %v", rec.Code, rec.Header())
+	}
+}
+
+func TestIdempotencySizeLimits(t *testing.T) {
+	var calls atomic.Int64
+	router := goapi.NewRouter()
+	router.Use(Idempotency(IdempotencyConfig{MaxBodySize: 16, MaxResponseSize: 8}))
+	router.POST("/payments", func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Write([]byte(strings.Repeat("r", 5)))
+		w.Write([]byte(strings.Repeat("s", 5)))
+	})
+
+	if rec := idempotencyRequest(router, "key-1", strings.Repeat("x", 17)); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413 for a body over MaxBodySize, got %d", rec.Code)
+	}
+
+	// A body of unknown length is also limited.
+	req := httptest.NewRequest(http.MethodPost, "/payments", io.MultiReader(strings.NewReader(strings.Repeat("x", 17))))
+	req.Header.Set("Idempotency-Key", "key-2")
+	rec := httptest.NewRecorder()
+	router.ServerHTTP(rec, req)
+	if rec.Code != http.StatusRequestEntityTooLarge || calls.Load() != 0 {
+		t.Errorf("expected 413 without running the handler, got %d after %d calls", rec.Code, calls.Load())
+	}
+
+	for i := 0; i < 2; i++ {
+		rec := idempotencyRequest(router, "key-3", "{}")
+		if rec.Code != http.StatusOK || rec.Body.String() != "rrrrrsssss" || rec.Header().Get("Idempotent-Replayed") != "" {
+			t.Errorf("expected a response over MaxResponseSize to be sent without being stored, got %d %q %v",
+				rec.Code, rec.Body.String(), rec.Header())
+		}
+	}
+	if calls.Load() != 2 {
+		t.Errorf("expected the key of an unstored response to be released, got %d calls", calls.Load())
+	}
+}
+
+func TestIdempotencyScope(t *testing.T) {
+	var calls atomic.Int64
+	router := goapi.NewRouter()
+	router.Use(Idempotency(IdempotencyConfig{
+		Scope: func(r *http.Request) string { return r.Header.Get("X-User") },
+	}))
+	router.POST("/payments", func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	})
+
+	for _, user := range []string{"alice", "bob", "alice"} {
+		req := httptest.NewRequest(http.MethodPost, "/payments", strings.NewReader("{}"))
+		req.Header.Set("Idempotency-Key", "shared")
+		req.Header.Set("X-User", user)
+		router.ServerHTTP(httptest.NewRecorder(), req)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("expected keys to be scoped per user, got %d calls", calls.Load())
+	}
+}
@@ -0,0 +1,120 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// IdempotencyRecord is the state of an idempotency key: the request that used it and, once completed, its
+// response.
+type IdempotencyRecord struct {
+	// Fingerprint identifies the request that reserved the key, so that reusing the key for a different
+	// request can be detected.
+	Fingerprint string
+	// Completed reports whether the response is stored. It is false while the first request is in progress.
+	Completed bool
+	// Status, Header and Body are the stored response.
+	Status int
+	Header http.Header
+	Body   []byte
+	// Created is when the key was reserved.
+	Created time.Time
+}
+
+// IdempotencyStore keeps idempotency keys and the responses of their requests. Implementations must be safe
+// for concurrent use, and Reserve must be atomic so that concurrent requests with the same key cannot both
+// reserve it; a shared backend lets several instances of a service honor the same keys.
+type IdempotencyStore interface {
+	// Reserve reserves key for a request with the given fingerprint, for ttl. If the key is already in use
+	// it returns its record and false instead.
+	Reserve(ctx context.Context, key, fingerprint string, ttl time.Duration) (*IdempotencyRecord, bool, error)
+	// Get returns the record of key, if it is in use.
+	Get(ctx context.Context, key string) (*IdempotencyRecord, bool, error)
+	// Complete stores the response of the request that reserved key. It is kept for ttl.
+	Complete(ctx context.Context, key string, record *IdempotencyRecord, ttl time.Duration) error
+	// Release frees a key whose request did not complete, so that it can be retried.
+	Release(ctx context.Context, key string) error
+}
+
+// MemoryIdempotencyStore is an in-memory IdempotencyStore.
+type MemoryIdempotencyStore struct {
+	mu        sync.Mutex
+	records   map[string]*memoryIdempotencyRecord
+	lastSweep time.Time
+}
+
+type memoryIdempotencyRecord struct {
+	record  IdempotencyRecord
+	expires time.Time
+}
+
+// NewMemoryIdempotencyStore creates an empty MemoryIdempotencyStore.
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{records: make(map[string]*memoryIdempotencyRecord)}
+}
+
+// Reserve reserves key unless it is in use.
+func (s *MemoryIdempotencyStore) Reserve(ctx context.Context, key, fingerprint string, ttl time.Duration) (*IdempotencyRecord, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.lastSweep) > time.Minute {
+		s.lastSweep = now
+		for key, entry := range s.records {
+			if now.After(entry.expires) {
+				delete(s.records, key)
+			}
+		}
+	}
+
+	if entry, ok := s.records[key]; ok && now.Before(entry.expires) {
+		record := entry.record
+		return &record, false, nil
+	}
+	s.records[key] = &memoryIdempotencyRecord{
+		record:  IdempotencyRecord{Fingerprint: fingerprint, Created: now},
+		expires: now.Add(ttl),
+	}
+	return nil, true, nil
+}
+
+// Get returns the record of key.
+func (s *MemoryIdempotencyStore) Get(ctx context.Context, key string) (*IdempotencyRecord, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.records[key]
+	if !ok || time.Now().After(entry.expires) {
+		return nil, false, nil
+	}
+	record := entry.record
+	return &record, true, nil
+}
+
+// Complete stores the response of key.
+func (s *MemoryIdempotencyStore) Complete(ctx context.Context, key string, record *IdempotencyRecord, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.records[key] = &memoryIdempotencyRecord{record: *record, expires: time.Now().Add(ttl)}
+	return nil
+}
+
+// Release frees key.
+func (s *MemoryIdempotencyStore) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, key)
+	return nil
+}
+
+// Len returns the number of keys in use.
+func (s *MemoryIdempotencyStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.records)
+}
@@ -0,0 +1,49 @@
+package middlewares
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryIdempotencyStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryIdempotencyStore()
+
+	if _, reserved, _ := store.Reserve(ctx, "k", "fp", time.Minute); !reserved {
+		t.Fatal("expected a new key to be reserved")
+	}
+	record, reserved, _ := store.Reserve(ctx, "k", "other", time.Minute)
+	if reserved || record.Fingerprint != "fp" || record.Completed {
+		t.Fatalf("expected the pending reservation to be returned, got %+v (%v)", record, reserved)
+	}
+
+	store.Complete(ctx, "k", &IdempotencyRecord{Fingerprint: "fp", Completed: true, Status: 201, Body: []byte("ok")}, time.Minute)
+	record, ok, _ := store.Get(ctx, "k")
+	if !ok || !record.Completed || record.Status != 201 || string(record.Body) != "ok" {
+		t.Errorf("expected the completed record, got %+v", record)
+	}
+
+	store.Release(ctx, "k")
+	if _, ok, _ := store.Get(ctx, "k"); ok {
+		t.Errorf("expected the released key to be gone")
+	}
+}
+
+func TestMemoryIdempotencyStoreExpiry(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryIdempotencyStore()
+
+	store.Reserve(ctx, "k", "fp", 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+
+	if _, ok, _ := store.Get(ctx, "k"); ok {
+		t.Errorf("expected the key to expire")
+	}
+	if _, reserved, _ := store.Reserve(ctx, "k", "fp2", time.Minute); !reserved {
+		t.Errorf("expected an expired key to be reserved again")
+	}
+	if store.Len() != 1 {
+		t.Errorf("expected 1 key, got %d", store.Len())
+	}
+}
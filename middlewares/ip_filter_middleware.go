@@ -0,0 +1,137 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+	"net/netip"
+	"sync/atomic"
+
+	goapi "github.com/carlosealves2/go-api"
+)
+
+// IPFilterConfig configures an IPFilter.
+type IPFilterConfig struct {
+	// Allow lists the IP addresses and CIDR ranges, IPv4 or IPv6, that may access the routes. When empty,
+	// every client not denied is allowed.
+	Allow []string
+	// Deny lists the IP addresses and CIDR ranges that may not access the routes. It takes precedence over
+	// Allow.
+	Deny []string
+	// Status is the status code of rejected requests. Defaults to 403 Forbidden.
+	Status int
+	// Message is the message of the error written for rejected requests. Defaults to the status text.
+	Message string
+	// Denied writes the response to rejected requests instead of the router's error handler.
+	Denied goapi.HandlerFunc
+}
+
+// IPFilter restricts access to the clients whose IP address is in an allowlist, or not in a denylist.
+// The lists can be replaced at runtime with Reload.
+type IPFilter struct {
+	config IPFilterConfig
+	rules  atomic.Pointer[ipRules]
+}
+
+type ipRules struct {
+	allow []netip.Prefix
+	deny  []netip.Prefix
+}
+
+// NewIPFilter creates an IPFilter with the given configuration.
+//
+// The client IP is the one resolved by the router, so requests forwarded by trusted proxies are filtered on
+// the address of the original client (see goapi.Router.TrustedProxies). Requests whose client IP cannot be
+// parsed are rejected.
+//
+// Returns:
+// - *IPFilter: The filter, whose Middleware method is registered on a router or group.
+// - error: An error if an entry of Allow or Deny is neither an IP address nor a CIDR range.
+//
+// Example:
+//
+//	filter, err := middlewares.NewIPFilter(middlewares.IPFilterConfig{
+//		Allow: []string{"203.0.113.0/24", "2001:db8:10::/48"}, // office and VPN
+//	})
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	admin := api.Group("/admin")
+//	admin.Use(filter.Middleware)
+func NewIPFilter(config IPFilterConfig) (*IPFilter, error) {
+	if config.Status == 0 {
+		config.Status = http.StatusForbidden
+	}
+	filter := &IPFilter{config: config}
+	if err := filter.Reload(config.Allow, config.Deny); err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
+
+// Reload replaces the allowlist and the denylist. Requests being filtered keep using the previous lists,
+// and the lists are unchanged if an entry is invalid.
+func (f *IPFilter) Reload(allow, deny []string) error {
+	rules := &ipRules{}
+	var err error
+	if rules.allow, err = parsePrefixes(allow); err != nil {
+		return err
+	}
+	if rules.deny, err = parsePrefixes(deny); err != nil {
+		return err
+	}
+	f.rules.Store(rules)
+	return nil
+}
+
+// Allowed reports whether a client IP passes the filter.
+func (f *IPFilter) Allowed(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap().WithZone("")
+
+	rules := f.rules.Load()
+	if containsAddr(rules.deny, addr) {
+		return false
+	}
+	return len(rules.allow) == 0 || containsAddr(rules.allow, addr)
+}
+
+// Middleware rejects the requests of clients that do not pass the filter.
+func (f *IPFilter) Middleware(next goapi.HandlerFunc) goapi.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if f.Allowed(goapi.ClientIPFromContext(req)) {
+			next(w, req)
+			return
+		}
+		if f.config.Denied != nil {
+			f.config.Denied(w, req)
+			return
+		}
+		goapi.WriteError(w, req, goapi.NewHTTPError(f.config.Status, f.config.Message))
+	}
+}
+
+// parsePrefixes parses a list of IP addresses and CIDR ranges.
+func parsePrefixes(entries []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(entries))
+	for _, entry := range entries {
+		prefix, err := goapi.ParsePrefix(entry)
+		if err != nil {
+			return nil, fmt.Errorf("middlewares: invalid IP filter entry %q: %w", entry, err)
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	return prefixes, nil
+}
+
+// containsAddr reports whether one of the prefixes contains the address.
+func containsAddr(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,147 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	goapi "github.com/carlosealves2/go-api"
+)
+
+func TestIPFilter(t *testing.T) {
+	filter, err := NewIPFilter(IPFilterConfig{
+		Allow: []string{"203.0.113.0/24", "2001:db8:10::/48", "198.51.100.7"},
+		Deny:  []string{"203.0.113.66"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	router := goapi.NewRouter()
+	if err := router.TrustedProxies("10.0.0.0/8"); err != nil {
+		t.Fatal(err)
+	}
+	router.Use(filter.Middleware)
+	router.GET("/admin", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+
+	tests := []struct {
+		name           string
+		remoteAddr     string
+		forwardedFor   string
+		expectedStatus int
+	}{
+		{name: "allowed range", remoteAddr: "203.0.113.10:1000", expectedStatus: http.StatusOK},
+		{name: "allowed address", remoteAddr: "198.51.100.7:1000", expectedStatus: http.StatusOK},
+		{name: "allowed ipv6 range", remoteAddr: "[2001:db8:10::5]:1000", expectedStatus: http.StatusOK},
+		{name: "ipv4-mapped ipv6", remoteAddr: "[::ffff:203.0.113.10]:1000", expectedStatus: http.StatusOK},
+		{name: "denied address", remoteAddr: "203.0.113.66:1000", expectedStatus: http.StatusForbidden},
+		{name: "not allowed", remoteAddr: "192.0.2.1:1000", expectedStatus: http.StatusForbidden},
+		{name: "client behind trusted proxy", remoteAddr: "10.0.0.1:1000", forwardedFor: "203.0.113.10", expectedStatus: http.StatusOK},
+		{name: "proxy itself is not allowed", remoteAddr: "10.0.0.1:1000", forwardedFor: "192.0.2.1", expectedStatus: http.StatusForbidden},
+		{name: "untrusted forwarded header", remoteAddr: "192.0.2.1:1000", forwardedFor: "203.0.113.10", expectedStatus: http.StatusForbidden},
+		{name: "invalid address", remoteAddr: "pipe", expectedStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwardedFor != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwardedFor)
+			}
+			rec := httptest.NewRecorder()
+			router.ServerHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected %d, got %d", tt.expectedStatus, rec.Code)
+			}
+		})
+	}
+}
+
+func TestIPFilterDenyOnly(t *testing.T) {
+	filter, err := NewIPFilter(IPFilterConfig{Deny: []string{"192.0.2.0/24"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if filter.Allowed("192.0.2.9") {
+		t.Errorf("expected a denied address to be rejected")
+	}
+	if !filter.Allowed("198.51.100.1") {
+		t.Errorf("expected other addresses to be allowed")
+	}
+}
+
+func TestIPFilterReload(t *testing.T) {
+	filter, err := NewIPFilter(IPFilterConfig{Allow: []string{"192.0.2.0/24"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := filter.Reload([]string{"198.51.100.0/24"}, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if filter.Allowed("192.0.2.1") || !filter.Allowed("198.51.100.1") {
+		t.Errorf("expected the new allowlist to apply")
+	}
+
+	if err := filter.Reload([]string{"office"}, nil); err == nil {
+		t.Fatal("expected an error for an invalid entry")
+	}
+	if !filter.Allowed("198.51.100.1") {
+		t.Errorf("expected the lists to be unchanged after a failed reload")
+	}
+
+	if _, err := NewIPFilter(IPFilterConfig{Deny: []string{"10.0.0.0/40"}}); err == nil {
+		t.Errorf("expected NewIPFilter to reject an invalid range")
+	}
+}
+
+func TestIPFilterResponse(t *testing.T) {
+	tests := []struct {
+		name           string
+		config         IPFilterConfig
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "custom status and message",
+			config:         IPFilterConfig{Allow: []string{"192.0.2.0/24"}, Status: http.StatusNotFound, Message: "no such page"},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   "no such page",
+		},
+		{
+			name: "custom handler",
+			config: IPFilterConfig{Allow: []string{"192.0.2.0/24"}, Denied: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusUnavailableForLegalReasons)
+				w.Write([]byte("blocked"))
+			}},
+			expectedStatus: http.StatusUnavailableForLegalReasons,
+			expectedBody:   "blocked",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := NewIPFilter(tt.config)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			router := goapi.NewRouter()
+			router.Use(filter.Middleware)
+			router.GET("/", func(w http.ResponseWriter, r *http.Request) {})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = "198.51.100.1:1000"
+			rec := httptest.NewRecorder()
+			router.ServerHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus || !strings.Contains(rec.Body.String(), tt.expectedBody) {
+				t.Errorf("expected %d %q, got %d %q", tt.expectedStatus, tt.expectedBody, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
@@ -4,11 +4,22 @@ import (
 	goapi "github.com/carlosealves2/go-api"
 	"log"
 	"net/http"
+	"time"
 )
 
+// LoggingMiddleware logs every request once its response has been written, with the method, path,
+// status code, number of body bytes and latency.
 func LoggingMiddleware(next goapi.HandlerFunc) goapi.HandlerFunc {
 	return func(w http.ResponseWriter, req *http.Request) {
-		log.Printf("%s %s", req.Method, req.URL.Path)
-		next(w, req)
+		start := time.Now()
+		ww := goapi.WrapResponseWriter(w)
+
+		next(ww, req)
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		log.Printf("%s %s %d %dB %s", req.Method, req.URL.Path, status, ww.BytesWritten(), time.Since(start))
 	}
 }
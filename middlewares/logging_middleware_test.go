@@ -35,7 +35,33 @@ func TestLoggingMiddleware(t *testing.T) {
 		t.Errorf("expected body %q, got %q", expectedBody, rec.Body.String())
 	}
 
-	expectedLog := "GET /test-path"
+	expectedLog := "GET /test-path 200 2B"
+	if !strings.Contains(logOutput.String(), expectedLog) {
+		t.Errorf("expected log to contain %q, got %q", expectedLog, logOutput.String())
+	}
+}
+
+func TestLoggingMiddlewareRecordsStatus(t *testing.T) {
+	mockHandler := func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "missing", http.StatusNotFound)
+	}
+
+	var logOutput bytes.Buffer
+	log.SetOutput(&logOutput)
+	defer log.SetOutput(nil)
+
+	handler := LoggingMiddleware(mockHandler)
+
+	req := httptest.NewRequest("GET", "/missing", nil)
+	rec := httptest.NewRecorder()
+
+	handler(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected status %d, got %d", http.StatusNotFound, rec.Code)
+	}
+
+	expectedLog := "GET /missing 404 8B"
 	if !strings.Contains(logOutput.String(), expectedLog) {
 		t.Errorf("expected log to contain %q, got %q", expectedLog, logOutput.String())
 	}
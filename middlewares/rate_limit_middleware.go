@@ -0,0 +1,168 @@
+package middlewares
+
+import (
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	goapi "github.com/carlosealves2/go-api"
+)
+
+// RateLimitKeyFunc returns the key a request is counted under. Requests with the same key share a quota.
+type RateLimitKeyFunc func(r *http.Request) string
+
+// RateLimitConfig configures the RateLimit middleware.
+type RateLimitConfig struct {
+	// Algorithm selects TokenBucket (the default) or SlidingWindow.
+	Algorithm RateLimitAlgorithm
+	// Limit is the number of requests allowed per Window for each key. Required.
+	Limit int
+	// Window is the period the limit applies to. Defaults to one minute.
+	Window time.Duration
+	// Burst is the number of requests a token bucket allows at once. Defaults to Limit.
+	Burst int
+	// Key returns the key requests are counted under. Defaults to KeyByIP.
+	Key RateLimitKeyFunc
+	// Store keeps the counters. Defaults to a new MemoryRateLimitStore.
+	Store RateLimitStore
+	// Name prefixes the keys in the store, so that several limits can share it. Defaults to a name unique
+	// to the middleware; set it when the store is shared between instances of a service.
+	Name string
+	// Logger receives a warning when the store fails. Defaults to slog.Default().
+	Logger *slog.Logger
+}
+
+// rateLimitCount numbers the RateLimit middlewares to give each one its own keys.
+var rateLimitCount atomic.Int64
+
+// RateLimit returns a middleware that limits how many requests each key can make.
+//
+// Every response carries the RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers, plus
+// RateLimit-Policy describing the quota. Requests over the limit are answered with 429 Too Many Requests
+// and a Retry-After header, through the router's error handler. If the store fails, the request is allowed
+// and the error logged, so that an unavailable backend does not take the service down.
+//
+// The middleware keeps one quota per key. Registered on a group, the limit covers every route of the group
+// and of its subgroups; a subgroup can add a stricter limit of its own, and both then apply.
+//
+// Parameters:
+// - config: The limit, the algorithm and how requests are keyed.
+//
+// Example:
+//
+//	api := router.Group("/api")
+//	api.Use(middlewares.RateLimit(middlewares.RateLimitConfig{Limit: 100, Window: time.Minute}))
+//
+//	search := api.Group("/search")
+//	search.Use(auth.APIKey(apiKeyConfig), middlewares.RateLimit(middlewares.RateLimitConfig{
+//		Algorithm: middlewares.SlidingWindow,
+//		Limit:     10,
+//		Window:    time.Minute,
+//		Key:       middlewares.KeyByUser,
+//	}))
+func RateLimit(config RateLimitConfig) goapi.MiddlewareFunc {
+	if config.Limit <= 0 {
+		panic("middlewares: RateLimit requires a positive Limit")
+	}
+	if config.Window <= 0 {
+		config.Window = time.Minute
+	}
+	if config.Key == nil {
+		config.Key = KeyByIP
+	}
+	if config.Store == nil {
+		config.Store = NewMemoryRateLimitStore(0)
+	}
+	if config.Logger == nil {
+		config.Logger = slog.Default()
+	}
+	if config.Name == "" {
+		config.Name = "ratelimit-" + strconv.FormatInt(rateLimitCount.Add(1), 10)
+	}
+
+	policy := RateLimitPolicy{
+		Algorithm: config.Algorithm,
+		Limit:     config.Limit,
+		Window:    config.Window,
+		Burst:     config.Burst,
+	}
+	policyHeader := strconv.Itoa(config.Limit) + ";w=" + strconv.Itoa(ceilSeconds(config.Window))
+	if config.Algorithm == TokenBucket && config.Burst > 0 {
+		policyHeader += ";burst=" + strconv.Itoa(config.Burst)
+	}
+
+	return func(next goapi.HandlerFunc) goapi.HandlerFunc {
+		return func(w http.ResponseWriter, req *http.Request) {
+			key := config.Name + ":" + config.Key(req)
+			result, err := config.Store.Take(req.Context(), key, policy, time.Now())
+			if err != nil {
+				config.Logger.WarnContext(req.Context(), "rate limit store failed",
+					slog.String("key", key),
+					slog.String("error", err.Error()),
+				)
+				next(w, req)
+				return
+			}
+
+			header := w.Header()
+			header.Set("RateLimit-Policy", policyHeader)
+			header.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+			header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			header.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+
+			if !result.Allowed {
+				header.Set("Retry-After", strconv.Itoa(max(1, ceilSeconds(result.RetryAfter))))
+				goapi.WriteError(w, req, goapi.NewHTTPError(http.StatusTooManyRequests, "rate limit exceeded"))
+				return
+			}
+			next(w, req)
+		}
+	}
+}
+
+// KeyByIP keys requests by the IP address of the client, resolved by the router from the headers of trusted
+// proxies (see goapi.Router.TrustedProxies).
+func KeyByIP(r *http.Request) string {
+	return "ip:" + goapi.ClientIPFromContext(r)
+}
+
+// KeyByUser keys requests by the authenticated principal (see goapi.PrincipalFromContext). Requests without
+// a principal are keyed by IP: unverified credentials are ignored, so that a client cannot pick its bucket.
+// Place the rate limiter after the authentication middleware; to limit each API key, authenticate it with
+// auth.APIKey.
+func KeyByUser(r *http.Request) string {
+	if principal, ok := goapi.PrincipalFromContext(r); ok && principal.ID != "" {
+		return "user:" + principal.ID
+	}
+	return KeyByIP(r)
+}
+
+// KeyByRoute keys requests by the method and pattern of the matched route, so that the limit is shared by
+// all the clients of a route.
+func KeyByRoute(r *http.Request) string {
+	if route, ok := goapi.RouteFromContext(r); ok {
+		return "route:" + route.Method + " " + route.Pattern
+	}
+	return "route:" + r.Method + " " + r.URL.Path
+}
+
+// CombineKeys returns a RateLimitKeyFunc joining the keys of several functions, for example to limit each
+// client on each route with CombineKeys(KeyByIP, KeyByRoute).
+func CombineKeys(keys ...RateLimitKeyFunc) RateLimitKeyFunc {
+	return func(r *http.Request) string {
+		parts := make([]string, len(keys))
+		for i, key := range keys {
+			parts[i] = key(r)
+		}
+		return strings.Join(parts, "|")
+	}
+}
+
+// ceilSeconds rounds a duration up to whole seconds.
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
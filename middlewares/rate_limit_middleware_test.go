@@ -0,0 +1,181 @@
+package middlewares
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	goapi "github.com/carlosealves2/go-api"
+)
+
+type failingRateLimitStore struct{}
+
+func (failingRateLimitStore) Take(context.Context, string, RateLimitPolicy, time.Time) (RateLimitResult, error) {
+	return RateLimitResult{}, errors.New("unavailable")
+}
+
+func rateLimitRequest(router *goapi.Router, path, remoteAddr string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.RemoteAddr = remoteAddr
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	rec := httptest.NewRecorder()
+	router.ServerHTTP(rec, req)
+	return rec
+}
+
+func TestRateLimit(t *testing.T) {
+	router := goapi.NewRouter()
+	router.Use(RateLimit(RateLimitConfig{Limit: 2, Window: time.Minute}))
+	router.GET("/items", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	for i := 0; i < 2; i++ {
+		rec := rateLimitRequest(router, "/items", "10.0.0.1:1234", nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected request %d to be allowed, got %d", i+1, rec.Code)
+		}
+	}
+
+	rec := rateLimitRequest(router, "/items", "10.0.0.1:1234", nil)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", rec.Code)
+	}
+	expectedHeaders := map[string]string{
+		"RateLimit-Policy":    "2;w=60",
+		"RateLimit-Limit":     "2",
+		"RateLimit-Remaining": "0",
+		"RateLimit-Reset":     "60",
+		"Retry-After":         "30",
+	}
+	for name, expected := range expectedHeaders {
+		if value := rec.Header().Get(name); value != expected {
+			t.Errorf("expected %s %q, got %q", name, expected, value)
+		}
+	}
+
+	if rec := rateLimitRequest(router, "/items", "10.0.0.2:1234", nil); rec.Code != http.StatusOK {
+		t.Errorf("expected another client to have its own quota, got %d", rec.Code)
+	}
+}
+
+func TestRateLimitGroups(t *testing.T) {
+	router := goapi.NewRouter()
+	api := router.Group.Group("/api")
+	api.Use(RateLimit(RateLimitConfig{Limit: 3, Window: time.Minute}))
+	api.GET("/items", func(w http.ResponseWriter, r *http.Request) {})
+	search := api.Group("/search")
+	search.Use(RateLimit(RateLimitConfig{Limit: 1, Window: time.Minute}))
+	search.GET("", func(w http.ResponseWriter, r *http.Request) {})
+
+	steps := []struct {
+		path           string
+		expectedStatus int
+	}{
+		{path: "/api/search", expectedStatus: http.StatusOK},
+		{path: "/api/search", expectedStatus: http.StatusTooManyRequests},
+		{path: "/api/items", expectedStatus: http.StatusOK},
+		// The group limit counted both search requests and the items request.
+		{path: "/api/items", expectedStatus: http.StatusTooManyRequests},
+	}
+	for _, step := range steps {
+		rec := rateLimitRequest(router, step.path, "10.0.0.1:1234", nil)
+		if rec.Code != step.expectedStatus {
+			t.Errorf("%s: expected %d, got %d", step.path, step.expectedStatus, rec.Code)
+		}
+	}
+}
+
+func TestRateLimitKeys(t *testing.T) {
+	router := goapi.NewRouter()
+	router.Use(func(next goapi.HandlerFunc) goapi.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if id := r.Header.Get("X-Principal"); id != "" {
+				r = goapi.WithPrincipal(r, &goapi.Principal{ID: id})
+			}
+			next(w, r)
+		}
+	})
+	router.GET("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(CombineKeys(KeyByIP, KeyByUser, KeyByRoute)(r)))
+	})
+
+	tests := []struct {
+		name     string
+		headers  map[string]string
+		expected string
+	}{
+		{
+			name:     "anonymous",
+			expected: "ip:10.0.0.1|ip:10.0.0.1|route:GET /users/:id",
+		},
+		{
+			name:     "unverified credentials",
+			headers:  map[string]string{"X-API-Key": "k1", "Authorization": "Basic YWxpY2U6c2VjcmV0"},
+			expected: "ip:10.0.0.1|ip:10.0.0.1|route:GET /users/:id",
+		},
+		{
+			name:     "principal",
+			headers:  map[string]string{"X-Principal": "bob", "Authorization": "Basic YWxpY2U6c2VjcmV0"},
+			expected: "ip:10.0.0.1|user:bob|route:GET /users/:id",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := rateLimitRequest(router, "/users/7", "10.0.0.1:1234", tt.headers)
+			if rec.Body.String() != tt.expected {
+				t.Errorf("expected key %q, got %q", tt.expected, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestRateLimitStoreFailureAllowsRequests(t *testing.T) {
+	router := goapi.NewRouter()
+	var logOutput bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logOutput, nil))
+	router.Use(RateLimit(RateLimitConfig{Limit: 1, Store: failingRateLimitStore{}, Logger: logger}))
+	router.GET("/", func(w http.ResponseWriter, r *http.Request) {})
+
+	for i := 0; i < 3; i++ {
+		if rec := rateLimitRequest(router, "/", "10.0.0.1:1234", nil); rec.Code != http.StatusOK {
+			t.Errorf("expected requests to be allowed when the store fails, got %d", rec.Code)
+		}
+	}
+	if !strings.Contains(logOutput.String(), "error=unavailable") {
+		t.Errorf("expected the store failure to be logged, got %q", logOutput.String())
+	}
+}
+
+func TestRateLimitTrustedProxy(t *testing.T) {
+	router := goapi.NewRouter()
+	if err := router.TrustedProxies("10.0.0.0/8"); err != nil {
+		t.Fatal(err)
+	}
+	router.Use(RateLimit(RateLimitConfig{Limit: 1, Window: time.Minute}))
+	router.GET("/", func(w http.ResponseWriter, r *http.Request) {})
+
+	steps := []struct {
+		forwardedFor   string
+		expectedStatus int
+	}{
+		{forwardedFor: "198.51.100.1", expectedStatus: http.StatusOK},
+		{forwardedFor: "198.51.100.2", expectedStatus: http.StatusOK},
+		{forwardedFor: "198.51.100.1", expectedStatus: http.StatusTooManyRequests},
+	}
+	for _, step := range steps {
+		rec := rateLimitRequest(router, "/", "10.0.0.1:1234", map[string]string{"X-Forwarded-For": step.forwardedFor})
+		if rec.Code != step.expectedStatus {
+			t.Errorf("%s: expected %d, got %d", step.forwardedFor, step.expectedStatus, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package middlewares
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"sync"
+	"time"
+)
+
+// RateLimitAlgorithm selects how requests are counted.
+type RateLimitAlgorithm int
+
+const (
+	// TokenBucket refills the bucket continuously at Limit tokens per Window and allows bursts of up to
+	// Burst requests.
+	TokenBucket RateLimitAlgorithm = iota
+	// SlidingWindow allows Limit requests in any Window, approximated by weighting the count of the
+	// previous fixed window.
+	SlidingWindow
+)
+
+// RateLimitPolicy is the limit applied to a key.
+type RateLimitPolicy struct {
+	Algorithm RateLimitAlgorithm
+	// Limit is the number of requests allowed per Window.
+	Limit int
+	// Window is the period the limit applies to.
+	Window time.Duration
+	// Burst is the capacity of the token bucket. Defaults to Limit. Unused by SlidingWindow.
+	Burst int
+}
+
+// RateLimitResult is the outcome of counting a request.
+type RateLimitResult struct {
+	// Allowed reports whether the request is within the limit.
+	Allowed bool
+	// Limit is the number of requests allowed per window.
+	Limit int
+	// Remaining is the number of requests still allowed now.
+	Remaining int
+	// Reset is the time until the quota is fully restored.
+	Reset time.Duration
+	// RetryAfter is the time until a rejected request may be retried.
+	RetryAfter time.Duration
+}
+
+// RateLimitStore keeps the rate limiting state of each key. Implementations must be safe for concurrent
+// use; a shared backend lets several instances of a service enforce a common limit.
+type RateLimitStore interface {
+	// Take counts a request for key under the policy and reports whether it is allowed.
+	Take(ctx context.Context, key string, policy RateLimitPolicy, now time.Time) (RateLimitResult, error)
+}
+
+// MemoryRateLimitStore is an in-memory RateLimitStore. Keys are spread over shards with their own lock
+// to reduce contention, and idle keys are removed periodically.
+type MemoryRateLimitStore struct {
+	shards []*rateLimitShard
+}
+
+type rateLimitShard struct {
+	mu        sync.Mutex
+	states    map[string]*rateLimitState
+	lastSweep time.Time
+}
+
+type rateLimitState struct {
+	// Token bucket state.
+	tokens float64
+	last   time.Time
+	// Sliding window state.
+	windowStart time.Time
+	previous    int
+	current     int
+	// expires is when the state becomes equivalent to a fresh one and can be dropped.
+	expires time.Time
+}
+
+// NewMemoryRateLimitStore creates a MemoryRateLimitStore with the given number of shards.
+// Zero selects 64 shards.
+func NewMemoryRateLimitStore(shards int) *MemoryRateLimitStore {
+	if shards <= 0 {
+		shards = 64
+	}
+	store := &MemoryRateLimitStore{shards: make([]*rateLimitShard, shards)}
+	for i := range store.shards {
+		store.shards[i] = &rateLimitShard{states: make(map[string]*rateLimitState)}
+	}
+	return store
+}
+
+// Take counts a request for key under the policy.
+func (s *MemoryRateLimitStore) Take(ctx context.Context, key string, policy RateLimitPolicy, now time.Time) (RateLimitResult, error) {
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(key))
+	shard := s.shards[hash.Sum32()%uint32(len(s.shards))]
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	if now.Sub(shard.lastSweep) > time.Minute {
+		shard.sweep(now)
+	}
+
+	state, ok := shard.states[key]
+	if !ok {
+		state = &rateLimitState{}
+		shard.states[key] = state
+	}
+	if policy.Algorithm == SlidingWindow {
+		return state.takeSlidingWindow(policy, now), nil
+	}
+	return state.takeTokenBucket(policy, now), nil
+}
+
+// Len returns the number of keys being tracked.
+func (s *MemoryRateLimitStore) Len() int {
+	n := 0
+	for _, shard := range s.shards {
+		shard.mu.Lock()
+		n += len(shard.states)
+		shard.mu.Unlock()
+	}
+	return n
+}
+
+func (shard *rateLimitShard) sweep(now time.Time) {
+	shard.lastSweep = now
+	for key, state := range shard.states {
+		if now.After(state.expires) {
+			delete(shard.states, key)
+		}
+	}
+}
+
+func (state *rateLimitState) takeTokenBucket(policy RateLimitPolicy, now time.Time) RateLimitResult {
+	capacity := float64(policy.Burst)
+	if capacity <= 0 {
+		capacity = float64(policy.Limit)
+	}
+	rate := float64(policy.Limit) / policy.Window.Seconds()
+
+	if state.last.IsZero() {
+		state.tokens = capacity
+	} else if elapsed := now.Sub(state.last).Seconds(); elapsed > 0 {
+		state.tokens = math.Min(capacity, state.tokens+elapsed*rate)
+	}
+	state.last = now
+
+	result := RateLimitResult{Limit: policy.Limit}
+	if state.tokens >= 1 {
+		state.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - state.tokens) / rate)
+	}
+	result.Remaining = int(state.tokens)
+	result.Reset = seconds((capacity - state.tokens) / rate)
+	state.expires = now.Add(result.Reset)
+	return result
+}
+
+func (state *rateLimitState) takeSlidingWindow(policy RateLimitPolicy, now time.Time) RateLimitResult {
+	window := policy.Window
+	start := now.Truncate(window)
+	switch {
+	case start.Equal(state.windowStart):
+	case start.Sub(state.windowStart) == window:
+		state.previous, state.current = state.current, 0
+		state.windowStart = start
+	default:
+		state.previous, state.current = 0, 0
+		state.windowStart = start
+	}
+
+	elapsed := now.Sub(start)
+	weight := 1 - float64(elapsed)/float64(window)
+	limit := policy.Limit
+	count := float64(state.previous)*weight + float64(state.current)
+
+	result := RateLimitResult{Limit: limit, Reset: window - elapsed}
+	if count+1 <= float64(limit) {
+		state.current++
+		count++
+		result.Allowed = true
+	} else {
+		result.RetryAfter = state.slidingRetryAfter(limit, window, elapsed)
+	}
+	result.Remaining = max(0, limit-int(math.Ceil(count)))
+	state.expires = start.Add(2 * window)
+	return result
+}
+
+// slidingRetryAfter returns how long until the weighted count leaves room for one more request.
+func (state *rateLimitState) slidingRetryAfter(limit int, window, elapsed time.Duration) time.Duration {
+	room := float64(limit - 1 - state.current)
+	if room >= 0 && state.previous > 0 {
+		// Wait for the previous window's weight to decrease enough.
+		wait := float64(window)*(1-room/float64(state.previous)) - float64(elapsed)
+		return time.Duration(math.Max(wait, 0))
+	}
+	// The current window alone is full: wait for the next one, where it becomes the previous window.
+	wait := window - elapsed
+	if state.current > 0 {
+		wait += time.Duration(math.Max(float64(window)*(1-float64(limit-1)/float64(state.current)), 0))
+	}
+	return wait
+}
+
+// seconds converts a number of seconds into a time.Duration.
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,99 @@
+package middlewares
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryRateLimitStoreTokenBucket(t *testing.T) {
+	store := NewMemoryRateLimitStore(4)
+	policy := RateLimitPolicy{Algorithm: TokenBucket, Limit: 10, Window: 10 * time.Second, Burst: 3}
+	now := time.Unix(1700000000, 0)
+
+	for i := 0; i < 3; i++ {
+		result, _ := store.Take(context.Background(), "k", policy, now)
+		if !result.Allowed {
+			t.Fatalf("expected request %d of the burst to be allowed", i+1)
+		}
+		if result.Remaining != 2-i {
+			t.Errorf("expected %d remaining, got %d", 2-i, result.Remaining)
+		}
+	}
+
+	result, _ := store.Take(context.Background(), "k", policy, now)
+	if result.Allowed {
+		t.Fatalf("expected the request after the burst to be rejected")
+	}
+	if result.RetryAfter != time.Second {
+		t.Errorf("expected to retry after 1s, got %v", result.RetryAfter)
+	}
+	if result.Reset != 3*time.Second {
+		t.Errorf("expected the bucket to refill in 3s, got %v", result.Reset)
+	}
+
+	result, _ = store.Take(context.Background(), "k", policy, now.Add(time.Second))
+	if !result.Allowed {
+		t.Errorf("expected a refilled token to allow the request")
+	}
+	if result, _ := store.Take(context.Background(), "other", policy, now); !result.Allowed {
+		t.Errorf("expected keys to have separate buckets")
+	}
+}
+
+func TestMemoryRateLimitStoreSlidingWindow(t *testing.T) {
+	store := NewMemoryRateLimitStore(0)
+	policy := RateLimitPolicy{Algorithm: SlidingWindow, Limit: 4, Window: time.Minute}
+	start := time.Unix(1700000000, 0).Truncate(time.Minute)
+
+	for i := 0; i < 4; i++ {
+		if result, _ := store.Take(context.Background(), "k", policy, start.Add(10*time.Second)); !result.Allowed {
+			t.Fatalf("expected request %d to be allowed", i+1)
+		}
+	}
+	result, _ := store.Take(context.Background(), "k", policy, start.Add(10*time.Second))
+	if result.Allowed || result.Remaining != 0 {
+		t.Fatalf("expected the fifth request to be rejected, got %+v", result)
+	}
+	if result.Reset != 50*time.Second {
+		t.Errorf("expected the window to reset in 50s, got %v", result.Reset)
+	}
+	// The current window is full: in the next one, the weight of these 4 requests must drop to 3.
+	if result.RetryAfter != 65*time.Second {
+		t.Errorf("expected to retry after 65s, got %v", result.RetryAfter)
+	}
+
+	// Halfway through the next window the previous 4 requests weigh 2.
+	next := start.Add(90 * time.Second)
+	for i := 0; i < 2; i++ {
+		if result, _ := store.Take(context.Background(), "k", policy, next); !result.Allowed {
+			t.Fatalf("expected request %d in the next window to be allowed", i+1)
+		}
+	}
+	if result, _ := store.Take(context.Background(), "k", policy, next); result.Allowed {
+		t.Errorf("expected the weighted count to reject the request")
+	}
+
+	// After two windows without requests the count starts over.
+	later := start.Add(5 * time.Minute)
+	if result, _ := store.Take(context.Background(), "k", policy, later); !result.Allowed || result.Remaining != 3 {
+		t.Errorf("expected a fresh window, got %+v", result)
+	}
+}
+
+func TestMemoryRateLimitStoreSweep(t *testing.T) {
+	store := NewMemoryRateLimitStore(1)
+	policy := RateLimitPolicy{Limit: 1, Window: time.Second}
+	now := time.Unix(1700000000, 0)
+
+	store.Take(context.Background(), "a", policy, now)
+	store.Take(context.Background(), "b", policy, now)
+	if store.Len() != 2 {
+		t.Fatalf("expected 2 keys, got %d", store.Len())
+	}
+
+	store.Take(context.Background(), "c", policy, now.Add(2*time.Minute))
+	if store.Len() != 1 {
+		t.Errorf("expected idle keys to be swept, got %d keys", store.Len())
+	}
+}
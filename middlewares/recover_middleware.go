@@ -0,0 +1,116 @@
+package middlewares
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	goapi "github.com/carlosealves2/go-api"
+)
+
+// PanicError is the error reported to the router's error handler when a handler panics.
+type PanicError struct {
+	// Value is the value passed to panic.
+	Value any
+	// Stack is the stack trace of the goroutine at the time of the panic.
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Unwrap returns the panic value when it is an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// RecoverConfig configures the RecoverWithConfig middleware.
+type RecoverConfig struct {
+	// Logger receives an error record for every recovered panic. Defaults to slog.Default().
+	Logger *slog.Logger
+}
+
+// Recover is a middleware that converts panics into 500 Internal Server Error responses, written through
+// the router's error handler, and logs them with their stack trace using slog.Default().
+// See RecoverWithConfig for the details.
+func Recover(next goapi.HandlerFunc) goapi.HandlerFunc {
+	return RecoverWithConfig(RecoverConfig{})(next)
+}
+
+// RecoverWithConfig returns a middleware that recovers from panics in the handlers it wraps.
+//
+// A recovered panic is logged with its stack trace, the route pattern and the request ID, and reported to
+// goapi.WriteError as a 500 *goapi.HTTPError wrapping a *PanicError. If the response had already started,
+// a new status cannot be sent; the handler is aborted with http.ErrAbortHandler instead, so the server
+// closes the connection and the client does not mistake the truncated response for a complete one.
+// Panics with http.ErrAbortHandler itself are passed through without being logged.
+//
+// Register it first so it covers the other middlewares:
+//
+//	api := goapi.NewRouter()
+//	api.Use(middlewares.RecoverWithConfig(middlewares.RecoverConfig{Logger: logger}))
+func RecoverWithConfig(config RecoverConfig) goapi.MiddlewareFunc {
+	return func(next goapi.HandlerFunc) goapi.HandlerFunc {
+		return func(w http.ResponseWriter, req *http.Request) {
+			ww := goapi.WrapResponseWriter(w)
+
+			defer func() {
+				value := recover()
+				if value == nil {
+					return
+				}
+				if value == http.ErrAbortHandler {
+					panic(value)
+				}
+
+				// Middlewares running handlers in other goroutines, such as Timeout, raise the panic
+				// again as a *PanicError with the original stack.
+				panicErr, ok := value.(*PanicError)
+				if !ok {
+					panicErr = &PanicError{Value: value, Stack: debug.Stack()}
+				}
+				logPanic(config.Logger, req, ww, panicErr)
+
+				if ww.Hijacked() {
+					return
+				}
+				if ww.Written() {
+					panic(http.ErrAbortHandler)
+				}
+
+				ww.Header().Del("Content-Length")
+				goapi.WriteError(ww, req, &goapi.HTTPError{
+					Status:  http.StatusInternalServerError,
+					Message: http.StatusText(http.StatusInternalServerError),
+					Err:     panicErr,
+				})
+			}()
+
+			next(ww, req)
+		}
+	}
+}
+
+// logPanic records a recovered panic.
+func logPanic(logger *slog.Logger, req *http.Request, w http.ResponseWriter, panicErr *PanicError) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	route := ""
+	if info, ok := goapi.RouteFromContext(req); ok {
+		route = info.Pattern
+	}
+
+	logger.ErrorContext(req.Context(), "panic recovered",
+		slog.String("panic", fmt.Sprint(panicErr.Value)),
+		slog.String("method", req.Method),
+		slog.String("path", req.URL.Path),
+		slog.String("route", route),
+		slog.String("request_id", goapi.RequestIDFromContext(req)),
+		slog.String("stack", string(panicErr.Stack)),
+	)
+}
@@ -0,0 +1,119 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	goapi "github.com/carlosealves2/go-api"
+)
+
+func TestRecover(t *testing.T) {
+	var logOutput bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logOutput, nil))
+
+	router := goapi.NewRouter()
+	router.Use(RequestID)
+	router.Use(RecoverWithConfig(RecoverConfig{Logger: logger}))
+	router.GET("/orders/:id", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "100")
+		panic("boom")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/orders/7", nil)
+	req.Header.Set("X-Request-ID", "req-42")
+	rec := httptest.NewRecorder()
+	router.ServerHTTP(rec, req)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected status %d, got %d", http.StatusInternalServerError, rec.Code)
+	}
+	if rec.Header().Get("Content-Length") == "100" {
+		t.Errorf("expected the stale Content-Length to be removed")
+	}
+	if strings.Contains(rec.Body.String(), "boom") {
+		t.Errorf("expected the panic value not to leak to the client, got %q", rec.Body.String())
+	}
+
+	var record map[string]any
+	if err := json.Unmarshal(logOutput.Bytes(), &record); err != nil {
+		t.Fatalf("expected a log record, got %q", logOutput.String())
+	}
+	if record["panic"] != "boom" || record["route"] != "/orders/:id" || record["request_id"] != "req-42" {
+		t.Errorf("unexpected log record %v", record)
+	}
+	if stack, _ := record["stack"].(string); !strings.Contains(stack, "recover_middleware_test.go") {
+		t.Errorf("expected the stack to point at the panicking handler, got %q", stack)
+	}
+}
+
+func TestRecoverUsesRouterErrorHandler(t *testing.T) {
+	var reported error
+	router := goapi.NewRouter()
+	router.ErrorHandler(func(w http.ResponseWriter, r *http.Request, err error) {
+		reported = err
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	router.Use(RecoverWithConfig(RecoverConfig{Logger: slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))}))
+
+	cause := errors.New("database unavailable")
+	router.GET("/", func(w http.ResponseWriter, r *http.Request) {
+		panic(cause)
+	})
+
+	rec := httptest.NewRecorder()
+	router.ServerHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected the router error handler to write the response, got %d", rec.Code)
+	}
+	var panicErr *PanicError
+	if !errors.As(reported, &panicErr) || !errors.Is(reported, cause) {
+		t.Errorf("expected a PanicError wrapping the panic value, got %v", reported)
+	}
+}
+
+func TestRecoverAfterHeadersSent(t *testing.T) {
+	handler := RecoverWithConfig(RecoverConfig{Logger: slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))})(
+		func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("partial"))
+			panic("late failure")
+		})
+
+	rec := httptest.NewRecorder()
+	defer func() {
+		if value := recover(); value != http.ErrAbortHandler {
+			t.Errorf("expected http.ErrAbortHandler, got %v", value)
+		}
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected the original status to be kept, got %d", rec.Code)
+		}
+	}()
+
+	handler(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	t.Errorf("expected the handler to be aborted")
+}
+
+func TestRecoverPassesThroughErrAbortHandler(t *testing.T) {
+	var logOutput bytes.Buffer
+	handler := RecoverWithConfig(RecoverConfig{Logger: slog.New(slog.NewTextHandler(&logOutput, nil))})(
+		func(w http.ResponseWriter, r *http.Request) {
+			panic(http.ErrAbortHandler)
+		})
+
+	defer func() {
+		if value := recover(); value != http.ErrAbortHandler {
+			t.Errorf("expected http.ErrAbortHandler to propagate, got %v", value)
+		}
+		if logOutput.Len() != 0 {
+			t.Errorf("expected aborted handlers not to be logged, got %q", logOutput.String())
+		}
+	}()
+
+	handler(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
@@ -0,0 +1,143 @@
+package middlewares
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"net/http"
+	"sync"
+	"time"
+
+	goapi "github.com/carlosealves2/go-api"
+)
+
+// DefaultRequestIDHeader is the header used by RequestID to read and echo request IDs.
+const DefaultRequestIDHeader = "X-Request-ID"
+
+// RequestIDConfig configures the RequestIDWithConfig middleware.
+type RequestIDConfig struct {
+	// Header is the request and response header carrying the ID. Defaults to DefaultRequestIDHeader.
+	Header string
+	// MaxLength is the maximum length of an inbound ID. Longer IDs are replaced. Defaults to 64.
+	MaxLength int
+	// IgnoreInbound always generates a new ID, ignoring the one sent by the client.
+	IgnoreInbound bool
+	// Generator creates new IDs. Defaults to NewRequestID.
+	Generator func() string
+}
+
+// RequestID is a middleware that assigns every request an ID, reusing a valid inbound X-Request-ID header
+// or generating one with NewRequestID. See RequestIDWithConfig for the details.
+func RequestID(next goapi.HandlerFunc) goapi.HandlerFunc {
+	return RequestIDWithConfig(RequestIDConfig{})(next)
+}
+
+// RequestIDWithConfig returns a middleware that assigns every request an ID.
+//
+// An inbound ID is accepted when it is at most MaxLength characters long and made only of letters, digits
+// and the characters "-", "_", ".", ":", "+", "=" and "/"; otherwise a new one is generated. The ID is stored
+// in the request context, where goapi.RequestIDFromContext reads it, and echoed in the response header.
+// AccessLog and Recover pick it up automatically.
+//
+// Example:
+//
+//	api := goapi.NewRouter()
+//	api.Use(middlewares.RequestID)
+//	api.GET("/", func(w http.ResponseWriter, r *http.Request) {
+//		log.Printf("handling %s", goapi.RequestIDFromContext(r))
+//	})
+func RequestIDWithConfig(config RequestIDConfig) goapi.MiddlewareFunc {
+	if config.Header == "" {
+		config.Header = DefaultRequestIDHeader
+	}
+	if config.MaxLength <= 0 {
+		config.MaxLength = 64
+	}
+	if config.Generator == nil {
+		config.Generator = NewRequestID
+	}
+
+	return func(next goapi.HandlerFunc) goapi.HandlerFunc {
+		return func(w http.ResponseWriter, req *http.Request) {
+			id := req.Header.Get(config.Header)
+			if config.IgnoreInbound || !validRequestID(id, config.MaxLength) {
+				id = config.Generator()
+			}
+
+			w.Header().Set(config.Header, id)
+			next(w, goapi.WithRequestID(req, id))
+		}
+	}
+}
+
+// validRequestID reports whether an inbound ID is safe to propagate to logs and downstream services.
+func validRequestID(id string, maxLength int) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':', c == '+', c == '=', c == '/':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// crockford is the Crockford base32 alphabet, whose ordering matches the byte ordering of the encoded values.
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+var requestIDState struct {
+	mu      sync.Mutex
+	lastMs  uint64
+	entropy [10]byte
+}
+
+// NewRequestID generates a 26-character, lexicographically sortable unique ID in the ULID format:
+// a 48-bit millisecond timestamp followed by 80 random bits. IDs generated within the same millisecond
+// increment the random part, so they remain strictly ordered within a process.
+func NewRequestID() string {
+	ms := uint64(time.Now().UnixMilli())
+
+	requestIDState.mu.Lock()
+	if ms <= requestIDState.lastMs {
+		ms = requestIDState.lastMs
+		incrementEntropy(&requestIDState.entropy)
+	} else {
+		requestIDState.lastMs = ms
+		_, _ = rand.Read(requestIDState.entropy[:])
+	}
+	var raw [16]byte
+	binary.BigEndian.PutUint16(raw[0:2], uint16(ms>>32))
+	binary.BigEndian.PutUint32(raw[2:6], uint32(ms))
+	copy(raw[6:], requestIDState.entropy[:])
+	requestIDState.mu.Unlock()
+
+	return encodeCrockford(raw)
+}
+
+// incrementEntropy adds one to the big-endian random part, wrapping around on overflow.
+func incrementEntropy(entropy *[10]byte) {
+	for i := len(entropy) - 1; i >= 0; i-- {
+		entropy[i]++
+		if entropy[i] != 0 {
+			return
+		}
+	}
+}
+
+// encodeCrockford encodes 128 bits as 26 base32 characters, most significant bits first.
+func encodeCrockford(raw [16]byte) string {
+	hi := binary.BigEndian.Uint64(raw[0:8])
+	lo := binary.BigEndian.Uint64(raw[8:16])
+
+	var out [26]byte
+	for i := 25; i >= 0; i-- {
+		out[i] = crockford[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
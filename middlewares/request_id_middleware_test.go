@@ -0,0 +1,106 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	goapi "github.com/carlosealves2/go-api"
+)
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name      string
+		inbound   string
+		expectNew bool
+	}{
+		{name: "generated when missing", inbound: "", expectNew: true},
+		{name: "inbound ID propagated", inbound: "req-42", expectNew: false},
+		{name: "uuid propagated", inbound: "0b7c4e8a-6f1d-4c5e-9a3b-2d8f7e6c5b4a", expectNew: false},
+		{name: "invalid characters replaced", inbound: "bad id\r\nX-Injected: 1", expectNew: true},
+		{name: "too long replaced", inbound: strings.Repeat("a", 65), expectNew: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen string
+			handler := RequestID(func(w http.ResponseWriter, r *http.Request) {
+				seen = goapi.RequestIDFromContext(r)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.inbound != "" {
+				req.Header.Set("X-Request-ID", tt.inbound)
+			}
+			rec := httptest.NewRecorder()
+			handler(rec, req)
+
+			if seen == "" {
+				t.Fatalf("expected a request ID in the context")
+			}
+			if echoed := rec.Header().Get("X-Request-ID"); echoed != seen {
+				t.Errorf("expected the response to echo %q, got %q", seen, echoed)
+			}
+			if tt.expectNew {
+				if seen == tt.inbound || len(seen) != 26 {
+					t.Errorf("expected a generated ID, got %q", seen)
+				}
+			} else if seen != tt.inbound {
+				t.Errorf("expected the inbound ID %q, got %q", tt.inbound, seen)
+			}
+		})
+	}
+}
+
+func TestRequestIDWithConfig(t *testing.T) {
+	handler := RequestIDWithConfig(RequestIDConfig{
+		Header:        "X-Correlation-ID",
+		IgnoreInbound: true,
+		Generator:     func() string { return "fixed" },
+	})(func(w http.ResponseWriter, r *http.Request) {})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Correlation-ID", "from-client")
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+
+	if id := rec.Header().Get("X-Correlation-ID"); id != "fixed" {
+		t.Errorf("expected the generated ID to replace the inbound one, got %q", id)
+	}
+}
+
+func TestNewRequestIDIsSortable(t *testing.T) {
+	ids := make([]string, 1000)
+	seen := make(map[string]bool, len(ids))
+	for i := range ids {
+		ids[i] = NewRequestID()
+		if len(ids[i]) != 26 {
+			t.Fatalf("expected a 26-character ID, got %q", ids[i])
+		}
+		if seen[ids[i]] {
+			t.Fatalf("duplicate ID %q", ids[i])
+		}
+		seen[ids[i]] = true
+	}
+	if !sort.StringsAreSorted(ids) {
+		t.Errorf("expected IDs generated in sequence to sort in generation order")
+	}
+}
+
+func TestRequestIDInAccessLog(t *testing.T) {
+	var output strings.Builder
+	router := goapi.NewRouter()
+	router.Use(AccessLog(AccessLogConfig{Format: AccessLogJSON, Output: &output}))
+	router.Use(RequestID)
+	router.GET("/", func(w http.ResponseWriter, r *http.Request) {})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Request-ID", "trace-7")
+	router.ServerHTTP(httptest.NewRecorder(), req)
+
+	if !strings.Contains(output.String(), `"request_id":"trace-7"`) {
+		t.Errorf("expected the access log to include the request ID, got %q", output.String())
+	}
+}
@@ -0,0 +1,212 @@
+package middlewares
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	goapi "github.com/carlosealves2/go-api"
+)
+
+// TimeoutConfig configures the TimeoutWithConfig middleware.
+type TimeoutConfig struct {
+	// Timeout is the time a handler has to produce its response. Routes can override it with
+	// goapi.Route.Timeout.
+	Timeout time.Duration
+	// Status is the status code sent when the handler runs out of time. Defaults to 503 Service Unavailable;
+	// 504 Gateway Timeout suits handlers that mostly wait for upstream services.
+	Status int
+	// Message is the message of the error written on timeout. Defaults to the status text.
+	Message string
+}
+
+// Timeout returns a middleware that gives handlers a limited time to respond. See TimeoutWithConfig.
+//
+// Example:
+//
+//	api.Use(middlewares.Timeout(5 * time.Second))
+func Timeout(timeout time.Duration) goapi.MiddlewareFunc {
+	return TimeoutWithConfig(TimeoutConfig{Timeout: timeout})
+}
+
+// TimeoutWithConfig returns a middleware that sets a deadline on the request context and answers with an
+// error if the handler has not finished by then.
+//
+// The handler runs in its own goroutine and writes into a buffer, so the timeout response never races with
+// its writes: once the deadline has passed, further writes by the handler fail with http.ErrHandlerTimeout.
+// Handlers should watch r.Context() and pass it to downstream calls, which then share the deadline;
+// goapi.TimeRemaining returns the time left. A handler that flushes its response streams it directly, and
+// the deadline then only cancels its context since the response can no longer be replaced.
+//
+// The route's own timeout, set with goapi.Route.Timeout, takes precedence over config.Timeout.
+// Requests with an Upgrade header, such as WebSocket handshakes, are passed through without a deadline.
+// Panics in the handler are propagated to the middlewares registered before this one as a *PanicError
+// carrying the stack trace of the handler.
+func TimeoutWithConfig(config TimeoutConfig) goapi.MiddlewareFunc {
+	if config.Status == 0 {
+		config.Status = http.StatusServiceUnavailable
+	}
+
+	return func(next goapi.HandlerFunc) goapi.HandlerFunc {
+		return func(w http.ResponseWriter, req *http.Request) {
+			timeout := config.Timeout
+			if route, ok := goapi.RouteFromContext(req); ok && route.Timeout != 0 {
+				timeout = route.Timeout
+			}
+			if timeout <= 0 || req.Header.Get("Upgrade") != "" {
+				next(w, req)
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(req.Context(), timeout)
+			defer cancel()
+			req = req.WithContext(ctx)
+
+			// The handler starts from the headers set by the previous middlewares, such as Vary or CORS
+			// headers, so that its own headers are added to them.
+			tw := &timeoutWriter{w: w, header: w.Header().Clone()}
+			done := make(chan struct{})
+			panicked := make(chan any, 1)
+			go func() {
+				defer func() {
+					if p := recover(); p != nil {
+						// The stack of this goroutine is lost once the panic is raised again in the
+						// goroutine of the request.
+						if p != http.ErrAbortHandler {
+							p = &PanicError{Value: p, Stack: debug.Stack()}
+						}
+						panicked <- p
+					}
+				}()
+				next(tw, req)
+				close(done)
+			}()
+
+			select {
+			case p := <-panicked:
+				panic(p)
+			case <-done:
+				tw.finish()
+			case <-ctx.Done():
+				tw.mu.Lock()
+				if tw.streaming {
+					// The response has started: let the handler end it.
+					tw.mu.Unlock()
+					select {
+					case p := <-panicked:
+						panic(p)
+					case <-done:
+					}
+					return
+				}
+				tw.timedOut = true
+				tw.mu.Unlock()
+
+				if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+					goapi.WriteError(w, req, goapi.NewHTTPError(config.Status, config.Message))
+				}
+			}
+		}
+	}
+}
+
+// timeoutWriter buffers the response of a handler running under a deadline.
+type timeoutWriter struct {
+	w           http.ResponseWriter
+	header      http.Header
+	mu          sync.Mutex
+	buf         bytes.Buffer
+	status      int
+	wroteHeader bool
+	timedOut    bool
+	streaming   bool
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+// Unwrap returns the underlying http.ResponseWriter, for http.ResponseController.
+func (tw *timeoutWriter) Unwrap() http.ResponseWriter {
+	return tw.w
+}
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	tw.writeHeaderLocked(code)
+}
+
+func (tw *timeoutWriter) writeHeaderLocked(code int) {
+	// Informational responses cannot be buffered and are optional, so they are dropped.
+	if tw.timedOut || tw.wroteHeader || code < 200 {
+		return
+	}
+	tw.wroteHeader = true
+	tw.status = code
+}
+
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	tw.writeHeaderLocked(http.StatusOK)
+	if tw.streaming {
+		return tw.w.Write(b)
+	}
+	return tw.buf.Write(b)
+}
+
+// Flush sends the buffered response and streams the rest of it, as long as the deadline has not passed.
+func (tw *timeoutWriter) Flush() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut {
+		return
+	}
+	tw.writeHeaderLocked(http.StatusOK)
+	tw.sendLocked()
+	_ = http.NewResponseController(tw.w).Flush()
+}
+
+// finish sends the response of a handler that returned in time.
+func (tw *timeoutWriter) finish() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	tw.writeHeaderLocked(http.StatusOK)
+	tw.sendLocked()
+}
+
+// sendLocked writes the header and the buffered body to the underlying writer and switches to streaming.
+// tw.header started as a copy of the underlying header, so it replaces it, keeping the headers that the
+// handler did not change or delete.
+func (tw *timeoutWriter) sendLocked() {
+	if tw.streaming {
+		return
+	}
+	tw.streaming = true
+
+	header := tw.w.Header()
+	for name := range header {
+		if _, ok := tw.header[name]; !ok {
+			delete(header, name)
+		}
+	}
+	for name, values := range tw.header {
+		header[name] = values
+	}
+	tw.w.WriteHeader(tw.status)
+	if tw.buf.Len() > 0 {
+		_, _ = tw.w.Write(tw.buf.Bytes())
+		tw.buf.Reset()
+	}
+}
@@ -0,0 +1,236 @@
+package middlewares
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	goapi "github.com/carlosealves2/go-api"
+)
+
+func TestTimeout(t *testing.T) {
+	lateWrite := make(chan error, 1)
+
+	router := goapi.NewRouter()
+	router.Use(Timeout(50 * time.Millisecond))
+	router.GET("/fast", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Handler", "fast")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("done"))
+	})
+	router.GET("/slow", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("partial"))
+		<-r.Context().Done()
+		time.Sleep(20 * time.Millisecond)
+		_, err := w.Write([]byte("late"))
+		lateWrite <- err
+	})
+	router.GET("/report", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte("report"))
+	}).Timeout(time.Second)
+	router.GET("/unlimited", func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Deadline(); ok {
+			t.Error("expected no deadline on a route with the timeout disabled")
+		}
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte("unlimited"))
+	}).Timeout(-1)
+
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{name: "in time", path: "/fast", expectedStatus: http.StatusCreated, expectedBody: "done"},
+		{name: "too slow", path: "/slow", expectedStatus: http.StatusServiceUnavailable, expectedBody: "Service Unavailable"},
+		{name: "route override", path: "/report", expectedStatus: http.StatusOK, expectedBody: "report"},
+		{name: "route disabled", path: "/unlimited", expectedStatus: http.StatusOK, expectedBody: "unlimited"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServerHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if !strings.Contains(rec.Body.String(), tt.expectedBody) || strings.Contains(rec.Body.String(), "partial") {
+				t.Errorf("expected body with %q, got %q", tt.expectedBody, rec.Body.String())
+			}
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServerHTTP(rec, httptest.NewRequest(http.MethodGet, "/fast", nil))
+	if rec.Header().Get("X-Handler") != "fast" {
+		t.Errorf("expected the handler's headers to be sent, got %v", rec.Header())
+	}
+
+	select {
+	case err := <-lateWrite:
+		if !errors.Is(err, http.ErrHandlerTimeout) {
+			t.Errorf("expected a late write to fail with ErrHandlerTimeout, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Error("expected the slow handler to observe the deadline")
+	}
+}
+
+func TestTimeoutWithConfig(t *testing.T) {
+	router := goapi.NewRouter()
+	router.Use(TimeoutWithConfig(TimeoutConfig{
+		Timeout: 20 * time.Millisecond,
+		Status:  http.StatusGatewayTimeout,
+		Message: "upstream too slow",
+	}))
+	router.GET("/", func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+
+	rec := httptest.NewRecorder()
+	router.ServerHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusGatewayTimeout || !strings.Contains(rec.Body.String(), "upstream too slow") {
+		t.Errorf("expected 504 with the configured message, got %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestTimeoutRemainingBudget(t *testing.T) {
+	var remaining time.Duration
+	var ok bool
+
+	router := goapi.NewRouter()
+	router.Use(Timeout(time.Second))
+	router.GET("/", func(w http.ResponseWriter, r *http.Request) {
+		remaining, ok = goapi.TimeRemaining(r)
+	})
+	router.ServerHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if !ok || remaining <= 0 || remaining > time.Second {
+		t.Errorf("expected a remaining budget within 1s, got %v (%v)", remaining, ok)
+	}
+}
+
+func TestTimeoutStreaming(t *testing.T) {
+	router := goapi.NewRouter()
+	router.Use(Timeout(20 * time.Millisecond))
+	router.GET("/events", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("event 1\n"))
+		http.NewResponseController(w).Flush()
+		<-r.Context().Done()
+		w.Write([]byte("event 2\n"))
+	})
+
+	rec := httptest.NewRecorder()
+	router.ServerHTTP(rec, httptest.NewRequest(http.MethodGet, "/events", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "event 1\nevent 2\n" {
+		t.Errorf("expected the streamed response to be completed, got %d %q", rec.Code, rec.Body.String())
+	}
+	if !rec.Flushed {
+		t.Errorf("expected the response to be flushed")
+	}
+}
+
+func TestTimeoutPropagatesPanics(t *testing.T) {
+	router := goapi.NewRouter()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	router.Use(RecoverWithConfig(RecoverConfig{Logger: logger}), Timeout(time.Second))
+	router.GET("/", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+
+	rec := httptest.NewRecorder()
+	router.ServerHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected the panic to reach Recover, got %d", rec.Code)
+	}
+}
+
+func TestTimeoutKeepsOuterHeaders(t *testing.T) {
+	router := goapi.NewRouter()
+	router.Use(func(next goapi.HandlerFunc) goapi.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Vary", "Accept-Encoding")
+			w.Header().Set("X-Request-ID", "req-1")
+			w.Header().Set("X-Removed", "yes")
+			next(w, r)
+		}
+	}, Timeout(time.Second))
+	router.GET("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Language")
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Del("X-Removed")
+		w.Write([]byte("ok"))
+	})
+
+	rec := httptest.NewRecorder()
+	router.ServerHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if got := strings.Join(rec.Header().Values("Vary"), ", "); got != "Accept-Encoding, Accept-Language" {
+		t.Errorf("expected the Vary headers to be merged, got %q", got)
+	}
+	if rec.Header().Get("X-Request-ID") != "req-1" || rec.Header().Get("Content-Type") != "text/plain" {
+		t.Errorf("expected the outer and handler headers, got %v", rec.Header())
+	}
+	if _, ok := rec.Header()["X-Removed"]; ok {
+		t.Errorf("expected the header deleted by the handler to be removed")
+	}
+}
+
+func TestTimeoutSkipsUpgrades(t *testing.T) {
+	router := goapi.NewRouter()
+	router.Use(Timeout(time.Millisecond))
+	var original bool
+	router.GET("/ws", func(w http.ResponseWriter, r *http.Request) {
+		_, original = w.(*httptest.ResponseRecorder)
+		time.Sleep(5 * time.Millisecond)
+		w.WriteHeader(http.StatusSwitchingProtocols)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/ws", nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	rec := httptest.NewRecorder()
+	router.ServerHTTP(rec, req)
+
+	if !original || rec.Code != http.StatusSwitchingProtocols {
+		t.Errorf("expected the upgrade to reach the original writer without a deadline, got %d", rec.Code)
+	}
+}
+
+func TestTimeoutWriterUnwrap(t *testing.T) {
+	rec := httptest.NewRecorder()
+	tw := &timeoutWriter{w: rec, header: make(http.Header)}
+	if tw.Unwrap() != rec {
+		t.Errorf("expected Unwrap to return the underlying writer")
+	}
+}
+
+func panickingHandler(w http.ResponseWriter, r *http.Request) {
+	panic("boom")
+}
+
+func TestTimeoutPanicStack(t *testing.T) {
+	router := goapi.NewRouter()
+	var logged bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logged, nil))
+	router.Use(RecoverWithConfig(RecoverConfig{Logger: logger}), Timeout(time.Second))
+	router.GET("/", panickingHandler)
+
+	router.ServerHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if !strings.Contains(logged.String(), "panickingHandler") {
+		t.Errorf("expected the logged stack to include the handler, got %q", logged.String())
+	}
+	if !strings.Contains(logged.String(), "panic=boom") {
+		t.Errorf("expected the original panic value to be logged, got %q", logged.String())
+	}
+}
@@ -0,0 +1,249 @@
+package goapi
+
+import (
+	"encoding"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// MsgPackCodec encodes values as MessagePack.
+//
+// Structs are encoded as maps keyed by the `msgpack` tag of each exported field, falling back to the
+// `json` tag and then to the field name; the omitempty option is honored. Values implementing
+// encoding.TextMarshaler, such as time.Time, are encoded as strings.
+type MsgPackCodec struct{}
+
+func (MsgPackCodec) ContentType() string { return "application/msgpack" }
+
+func (MsgPackCodec) Encode(w io.Writer, v any) error {
+	enc := &msgpackEncoder{}
+	if err := enc.encode(reflect.ValueOf(v)); err != nil {
+		return err
+	}
+	_, err := w.Write(enc.buf)
+	return err
+}
+
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+type msgpackEncoder struct {
+	buf []byte
+}
+
+func (e *msgpackEncoder) encode(value reflect.Value) error {
+	if !value.IsValid() {
+		e.buf = append(e.buf, 0xc0)
+		return nil
+	}
+
+	if value.Type().Implements(textMarshalerType) {
+		if value.Kind() == reflect.Pointer && value.IsNil() {
+			e.buf = append(e.buf, 0xc0)
+			return nil
+		}
+		text, err := value.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return err
+		}
+		e.encodeString(string(text))
+		return nil
+	}
+
+	switch value.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if value.IsNil() {
+			e.buf = append(e.buf, 0xc0)
+			return nil
+		}
+		return e.encode(value.Elem())
+	case reflect.Bool:
+		if value.Bool() {
+			e.buf = append(e.buf, 0xc3)
+		} else {
+			e.buf = append(e.buf, 0xc2)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.encodeInt(value.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		e.encodeUint(value.Uint())
+	case reflect.Float32:
+		e.buf = append(e.buf, 0xca)
+		e.buf = binary.BigEndian.AppendUint32(e.buf, math.Float32bits(float32(value.Float())))
+	case reflect.Float64:
+		e.buf = append(e.buf, 0xcb)
+		e.buf = binary.BigEndian.AppendUint64(e.buf, math.Float64bits(value.Float()))
+	case reflect.String:
+		e.encodeString(value.String())
+	case reflect.Slice, reflect.Array:
+		if value.Kind() == reflect.Slice && value.IsNil() {
+			e.buf = append(e.buf, 0xc0)
+			return nil
+		}
+		if value.Type().Elem().Kind() == reflect.Uint8 {
+			e.encodeBinary(value)
+			return nil
+		}
+		e.encodeHeader(value.Len(), 0x90, 0xdc, 0xdd)
+		for i := 0; i < value.Len(); i++ {
+			if err := e.encode(value.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if value.IsNil() {
+			e.buf = append(e.buf, 0xc0)
+			return nil
+		}
+		keys := value.MapKeys()
+		if value.Type().Key().Kind() == reflect.String {
+			sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		}
+		e.encodeHeader(len(keys), 0x80, 0xde, 0xdf)
+		for _, key := range keys {
+			if err := e.encode(key); err != nil {
+				return err
+			}
+			if err := e.encode(value.MapIndex(key)); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		return e.encodeStruct(value)
+	default:
+		return fmt.Errorf("msgpack: unsupported type %s", value.Type())
+	}
+	return nil
+}
+
+func (e *msgpackEncoder) encodeStruct(value reflect.Value) error {
+	type structField struct {
+		name  string
+		value reflect.Value
+	}
+
+	var fields []structField
+	structType := value.Type()
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag, ok := field.Tag.Lookup("msgpack")
+		if !ok {
+			tag = field.Tag.Get("json")
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "-" && options == "" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		fieldValue := value.Field(i)
+		if strings.Contains(options, "omitempty") && fieldValue.IsZero() {
+			continue
+		}
+		fields = append(fields, structField{name: name, value: fieldValue})
+	}
+
+	e.encodeHeader(len(fields), 0x80, 0xde, 0xdf)
+	for _, field := range fields {
+		e.encodeString(field.name)
+		if err := e.encode(field.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *msgpackEncoder) encodeInt(n int64) {
+	switch {
+	case n >= 0:
+		e.encodeUint(uint64(n))
+	case n >= -32:
+		e.buf = append(e.buf, byte(n))
+	case n >= math.MinInt8:
+		e.buf = append(e.buf, 0xd0, byte(n))
+	case n >= math.MinInt16:
+		e.buf = append(e.buf, 0xd1)
+		e.buf = binary.BigEndian.AppendUint16(e.buf, uint16(n))
+	case n >= math.MinInt32:
+		e.buf = append(e.buf, 0xd2)
+		e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(n))
+	default:
+		e.buf = append(e.buf, 0xd3)
+		e.buf = binary.BigEndian.AppendUint64(e.buf, uint64(n))
+	}
+}
+
+func (e *msgpackEncoder) encodeUint(n uint64) {
+	switch {
+	case n <= 0x7f:
+		e.buf = append(e.buf, byte(n))
+	case n <= math.MaxUint8:
+		e.buf = append(e.buf, 0xcc, byte(n))
+	case n <= math.MaxUint16:
+		e.buf = append(e.buf, 0xcd)
+		e.buf = binary.BigEndian.AppendUint16(e.buf, uint16(n))
+	case n <= math.MaxUint32:
+		e.buf = append(e.buf, 0xce)
+		e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(n))
+	default:
+		e.buf = append(e.buf, 0xcf)
+		e.buf = binary.BigEndian.AppendUint64(e.buf, n)
+	}
+}
+
+func (e *msgpackEncoder) encodeString(s string) {
+	switch n := len(s); {
+	case n < 32:
+		e.buf = append(e.buf, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		e.buf = append(e.buf, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		e.buf = append(e.buf, 0xda)
+		e.buf = binary.BigEndian.AppendUint16(e.buf, uint16(n))
+	default:
+		e.buf = append(e.buf, 0xdb)
+		e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(n))
+	}
+	e.buf = append(e.buf, s...)
+}
+
+func (e *msgpackEncoder) encodeBinary(value reflect.Value) {
+	n := value.Len()
+	switch {
+	case n <= math.MaxUint8:
+		e.buf = append(e.buf, 0xc4, byte(n))
+	case n <= math.MaxUint16:
+		e.buf = append(e.buf, 0xc5)
+		e.buf = binary.BigEndian.AppendUint16(e.buf, uint16(n))
+	default:
+		e.buf = append(e.buf, 0xc6)
+		e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(n))
+	}
+	for i := 0; i < n; i++ {
+		e.buf = append(e.buf, byte(value.Index(i).Uint()))
+	}
+}
+
+// encodeHeader writes an array or map header, choosing the fixed, 16-bit or 32-bit form.
+func (e *msgpackEncoder) encodeHeader(n int, fixPrefix, prefix16, prefix32 byte) {
+	switch {
+	case n < 16:
+		e.buf = append(e.buf, fixPrefix|byte(n))
+	case n <= math.MaxUint16:
+		e.buf = append(e.buf, prefix16)
+		e.buf = binary.BigEndian.AppendUint16(e.buf, uint16(n))
+	default:
+		e.buf = append(e.buf, prefix32)
+		e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(n))
+	}
+}
@@ -0,0 +1,73 @@
+package goapi
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestMsgPackCodec(t *testing.T) {
+	type payload struct {
+		ID      int    `json:"id"`
+		Name    string `msgpack:"n"`
+		Skipped string `json:"-"`
+		Empty   string `json:"empty,omitempty"`
+	}
+
+	tests := []struct {
+		name     string
+		value    any
+		expected []byte
+	}{
+		{"Nil", nil, []byte{0xc0}},
+		{"Bools", []bool{true, false}, []byte{0x92, 0xc3, 0xc2}},
+		{"Positive fixint", 5, []byte{0x05}},
+		{"Negative fixint", -3, []byte{0xfd}},
+		{"Uint8", 200, []byte{0xcc, 0xc8}},
+		{"Int16", -300, []byte{0xd1, 0xfe, 0xd4}},
+		{"Uint32", 70000, []byte{0xce, 0x00, 0x01, 0x11, 0x70}},
+		{"Float64", 1.5, []byte{0xcb, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0}},
+		{"Fixstr", "hi", []byte{0xa2, 'h', 'i'}},
+		{"Binary", []byte{1, 2}, []byte{0xc4, 0x02, 0x01, 0x02}},
+		{"Sorted map", map[string]int{"b": 2, "a": 1}, []byte{0x82, 0xa1, 'a', 0x01, 0xa1, 'b', 0x02}},
+		{"Struct", payload{ID: 1, Name: "x", Skipped: "s"}, []byte{0x82, 0xa2, 'i', 'd', 0x01, 0xa1, 'n', 0xa1, 'x'}},
+		{"Pointer", &payload{ID: 2}, []byte{0x82, 0xa2, 'i', 'd', 0x02, 0xa1, 'n', 0xa0}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := (MsgPackCodec{}).Encode(&buf, test.value); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if !bytes.Equal(buf.Bytes(), test.expected) {
+				t.Errorf("expected % x, got % x", test.expected, buf.Bytes())
+			}
+		})
+	}
+}
+
+func TestMsgPackCodecLengthPrefixes(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (MsgPackCodec{}).Encode(&buf, strings.Repeat("a", 40)); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if buf.Bytes()[0] != 0xd9 || buf.Bytes()[1] != 40 {
+		t.Errorf("expected str8 header, got % x", buf.Bytes()[:2])
+	}
+
+	buf.Reset()
+	if err := (MsgPackCodec{}).Encode(&buf, make([]int, 20)); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !bytes.Equal(buf.Bytes()[:3], []byte{0xdc, 0x00, 0x14}) {
+		t.Errorf("expected array16 header, got % x", buf.Bytes()[:3])
+	}
+}
+
+func TestMsgPackCodecUnsupported(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (MsgPackCodec{}).Encode(&buf, make(chan int)); err == nil {
+		t.Errorf("expected an error for channels")
+	}
+}
@@ -0,0 +1,67 @@
+package goapi
+
+import (
+	"context"
+	"net/http"
+	"slices"
+)
+
+var principalKey = contextKey("principal")
+
+// Principal is the authenticated identity of a request, stored in the request context by authentication
+// middlewares such as those of the middlewares/auth package.
+type Principal struct {
+	// ID identifies the principal, such as a user name, a token subject or the owner of an API key.
+	ID string
+	// Scheme is the authentication scheme that identified the principal (e.g., "basic", "bearer", "api-key").
+	Scheme string
+	// Roles are the roles granted to the principal.
+	Roles []string
+	// Scopes are the permissions granted to the principal, such as OAuth scopes.
+	Scopes []string
+	// Attributes holds any other information about the principal, such as token claims.
+	Attributes map[string]any
+}
+
+// HasRole reports whether the principal was granted the role.
+func (p *Principal) HasRole(role string) bool {
+	return p != nil && slices.Contains(p.Roles, role)
+}
+
+// HasScope reports whether the principal was granted the scope.
+func (p *Principal) HasScope(scope string) bool {
+	return p != nil && slices.Contains(p.Scopes, scope)
+}
+
+// WithPrincipal returns a shallow copy of the request whose context carries the authenticated principal.
+// It is used by authentication middlewares; handlers read it back with PrincipalFromContext.
+func WithPrincipal(r *http.Request, principal *Principal) *http.Request {
+	if state := stateFromContext(r); state != nil {
+		state.principal.Store(principal)
+	}
+	return r.WithContext(context.WithValue(r.Context(), principalKey, principal))
+}
+
+// PrincipalFromContext retrieves the principal stored by WithPrincipal.
+// Middlewares registered before the authentication middleware, such as access loggers, also get it once
+// their next handler returned. The second return value is false when the request is not authenticated.
+//
+// Example:
+//
+//	api.GET("/me", func(w http.ResponseWriter, r *http.Request) {
+//		principal, ok := goapi.PrincipalFromContext(r)
+//		if !ok {
+//			goapi.WriteError(w, r, goapi.NewHTTPError(http.StatusUnauthorized, ""))
+//			return
+//		}
+//		fmt.Fprintf(w, "hello %s", principal.ID)
+//	})
+func PrincipalFromContext(r *http.Request) (*Principal, bool) {
+	principal, ok := r.Context().Value(principalKey).(*Principal)
+	if !ok {
+		if state := stateFromContext(r); state != nil {
+			principal = state.principal.Load()
+		}
+	}
+	return principal, principal != nil
+}
@@ -0,0 +1,47 @@
+package goapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPrincipalFromContext(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if _, ok := PrincipalFromContext(req); ok {
+		t.Errorf("expected no principal")
+	}
+	if _, ok := PrincipalFromContext(WithPrincipal(req, nil)); ok {
+		t.Errorf("expected a nil principal to be ignored")
+	}
+
+	principal := &Principal{ID: "alice", Scheme: "basic", Roles: []string{"admin"}, Scopes: []string{"orders:read"}}
+	got, ok := PrincipalFromContext(WithPrincipal(req, principal))
+	if !ok || got != principal {
+		t.Fatalf("expected the stored principal, got %v", got)
+	}
+}
+
+func TestPrincipalRolesAndScopes(t *testing.T) {
+	principal := &Principal{Roles: []string{"admin"}, Scopes: []string{"orders:read"}}
+
+	tests := []struct {
+		name     string
+		result   bool
+		expected bool
+	}{
+		{name: "granted role", result: principal.HasRole("admin"), expected: true},
+		{name: "missing role", result: principal.HasRole("auditor"), expected: false},
+		{name: "granted scope", result: principal.HasScope("orders:read"), expected: true},
+		{name: "missing scope", result: principal.HasScope("orders:write"), expected: false},
+		{name: "nil principal", result: (*Principal)(nil).HasRole("admin"), expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.result != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, tt.result)
+			}
+		})
+	}
+}
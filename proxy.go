@@ -0,0 +1,271 @@
+package goapi
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+var clientKey = contextKey("client")
+
+// ForwardingHeader names the header from which the router reads the client of requests coming from
+// trusted proxies. See Router.ForwardingHeader.
+type ForwardingHeader string
+
+const (
+	// HeaderXForwardedFor reads the client from X-Forwarded-For, and the scheme and host from
+	// X-Forwarded-Proto and X-Forwarded-Host. It is the default.
+	HeaderXForwardedFor ForwardingHeader = "X-Forwarded-For"
+	// HeaderForwarded reads the client, scheme and host from the RFC 7239 Forwarded header.
+	HeaderForwarded ForwardingHeader = "Forwarded"
+	// HeaderXRealIP reads the client from X-Real-IP, as set by nginx's realip module.
+	HeaderXRealIP ForwardingHeader = "X-Real-IP"
+)
+
+// ClientInfo describes the client of a request as seen by the first proxy in front of the application.
+type ClientInfo struct {
+	// IP is the IP address of the client.
+	IP string
+	// Scheme is the scheme the client used, "http" or "https".
+	Scheme string
+	// Host is the host the client requested.
+	Host string
+}
+
+// TrustedProxies sets the proxies whose forwarding headers the router believes, as IP addresses or CIDR
+// ranges (e.g., "10.0.0.0/8", "::1"). Calling it again replaces the list; calling it without arguments
+// trusts no proxy, which is the default.
+//
+// For every request, the router resolves the client IP, scheme and host and stores them in the request
+// context, where ClientFromContext and ClientIPFromContext read them. When the request comes from a
+// trusted proxy, only the header selected with ForwardingHeader is used, X-Forwarded-For by default. The
+// chain of addresses is walked from the nearest hop, skipping trusted proxies, so the client IP is the
+// first address that was not added by a proxy you control and cannot be spoofed by the client. Otherwise
+// the peer address, the TLS state and the Host header are used.
+//
+// Parameters:
+// - cidrs: The trusted IP addresses and CIDR ranges.
+//
+// Returns:
+// - error: An error if an entry is neither an IP address nor a CIDR range. The list is then unchanged.
+//
+// Example:
+//
+//	r := goapi.NewRouter()
+//	if err := r.TrustedProxies("10.0.0.0/8", "fd00::/8"); err != nil {
+//		log.Fatal(err)
+//	}
+func (r *Router) TrustedProxies(cidrs ...string) error {
+	prefixes := make([]netip.Prefix, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		prefix, err := ParsePrefix(cidr)
+		if err != nil {
+			return fmt.Errorf("goapi: invalid trusted proxy: %w", err)
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	r.trustedProxies = prefixes
+	return nil
+}
+
+// ForwardingHeader selects the one header that the trusted proxies set, X-Forwarded-For by default. The
+// other forwarding headers are ignored: proxies usually pass through the headers they do not set, so a
+// client could otherwise choose its IP by sending one of them.
+//
+// Parameters:
+// - header: HeaderXForwardedFor, HeaderForwarded or HeaderXRealIP. It panics for any other value.
+//
+// Example:
+//
+//	r.TrustedProxies("10.0.0.0/8")
+//	r.ForwardingHeader(goapi.HeaderForwarded) // the load balancer sets RFC 7239 Forwarded
+func (r *Router) ForwardingHeader(header ForwardingHeader) {
+	switch header {
+	case HeaderXForwardedFor, HeaderForwarded, HeaderXRealIP:
+		r.forwardingHeader = header
+	default:
+		panic(fmt.Sprintf("goapi: unsupported forwarding header %q", header))
+	}
+}
+
+// ParsePrefix parses an IP address or a CIDR range into a netip.Prefix. An address is a range containing
+// only itself.
+func ParsePrefix(s string) (netip.Prefix, error) {
+	s = strings.TrimSpace(s)
+	if strings.Contains(s, "/") {
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		return prefix.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// ClientFromContext returns the client information resolved by the router. For a request not dispatched
+// by a router, it is derived from the request itself, ignoring forwarding headers.
+func ClientFromContext(r *http.Request) ClientInfo {
+	if client, ok := r.Context().Value(clientKey).(ClientInfo); ok {
+		return client
+	}
+	return directClient(r)
+}
+
+// ClientIPFromContext returns the IP address of the client resolved by the router, taking trusted proxies
+// into account. See Router.TrustedProxies.
+func ClientIPFromContext(r *http.Request) string {
+	return ClientFromContext(r).IP
+}
+
+// withClient stores the resolved client information in the request context.
+func (r *Router) withClient(req *http.Request) *http.Request {
+	client := resolveClient(req, r.trustedProxies, r.forwardingHeader)
+	return req.WithContext(context.WithValue(req.Context(), clientKey, client))
+}
+
+// directClient returns the client information of a request that did not go through a trusted proxy.
+func directClient(r *http.Request) ClientInfo {
+	client := ClientInfo{IP: r.RemoteAddr, Scheme: "http", Host: r.Host}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		client.IP = host
+	}
+	if r.TLS != nil {
+		client.Scheme = "https"
+	}
+	return client
+}
+
+// resolveClient applies the forwarding header of trusted proxies. An empty header selects
+// HeaderXForwardedFor.
+func resolveClient(r *http.Request, trusted []netip.Prefix, header ForwardingHeader) ClientInfo {
+	client := directClient(r)
+	if len(trusted) == 0 || !isTrusted(client.IP, trusted) {
+		return client
+	}
+
+	switch header {
+	case HeaderForwarded:
+		elements := parseForwarded(r.Header.Values("Forwarded"))
+		hops := make([]string, len(elements))
+		for i, element := range elements {
+			hops[i] = element["for"]
+		}
+		if i, ok := clientHop(hops, trusted); ok {
+			client.IP = hops[i]
+			if proto := strings.ToLower(elements[i]["proto"]); proto == "http" || proto == "https" {
+				client.Scheme = proto
+			}
+			if host := elements[i]["host"]; host != "" {
+				client.Host = host
+			}
+		}
+	case HeaderXRealIP:
+		if ip, ok := normalizeIP(r.Header.Get("X-Real-IP")); ok {
+			client.IP = ip
+		}
+	default:
+		hops := splitList(r.Header.Values("X-Forwarded-For"))
+		if i, ok := clientHop(hops, trusted); ok {
+			client.IP = hops[i]
+		}
+		if protos := splitList(r.Header.Values("X-Forwarded-Proto")); len(protos) > 0 {
+			if proto := strings.ToLower(protos[len(protos)-1]); proto == "http" || proto == "https" {
+				client.Scheme = proto
+			}
+		}
+		if hosts := splitList(r.Header.Values("X-Forwarded-Host")); len(hosts) > 0 {
+			client.Host = hosts[len(hosts)-1]
+		}
+	}
+	return client
+}
+
+// clientHop returns the index of the client in a chain of forwarded addresses, ordered from the client to
+// the nearest proxy: the last address that is not a trusted proxy. If the chain holds an invalid address,
+// the hop after it is used, since nothing before it can be believed. The addresses are normalized in place.
+func clientHop(hops []string, trusted []netip.Prefix) (int, bool) {
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip, ok := normalizeIP(hops[i])
+		if !ok {
+			if i == len(hops)-1 {
+				return 0, false
+			}
+			return i + 1, true
+		}
+		hops[i] = ip
+		if i == 0 || !isTrusted(ip, trusted) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// isTrusted reports whether ip belongs to one of the trusted ranges.
+func isTrusted(ip string, trusted []netip.Prefix) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap().WithZone("")
+	for _, prefix := range trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeIP extracts the IP address of a forwarded node, which may be quoted, bracketed or carry a port
+// (e.g., `"[2001:db8::1]:4711"`).
+func normalizeIP(node string) (string, bool) {
+	node = strings.Trim(strings.TrimSpace(node), `"`)
+	if host, _, err := net.SplitHostPort(node); err == nil {
+		node = host
+	}
+	node = strings.TrimSuffix(strings.TrimPrefix(node, "["), "]")
+	addr, err := netip.ParseAddr(node)
+	if err != nil {
+		return "", false
+	}
+	return addr.Unmap().String(), true
+}
+
+// parseForwarded parses the elements of RFC 7239 Forwarded headers into their lowercased parameters.
+func parseForwarded(values []string) []map[string]string {
+	var elements []map[string]string
+	for _, value := range values {
+		for _, element := range strings.Split(value, ",") {
+			params := make(map[string]string)
+			for _, pair := range strings.Split(element, ";") {
+				name, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if !ok {
+					continue
+				}
+				params[strings.ToLower(name)] = strings.Trim(value, `"`)
+			}
+			elements = append(elements, params)
+		}
+	}
+	return elements
+}
+
+// splitList splits comma-separated header values into their trimmed, non-empty items.
+func splitList(values []string) []string {
+	var items []string
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+	}
+	return items
+}
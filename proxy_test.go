@@ -0,0 +1,200 @@
+package goapi
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRouterTrustedProxies(t *testing.T) {
+	r := NewRouter()
+	if err := r.TrustedProxies("10.0.0.0/8", "192.168.1.1", "fd00::/8"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var client ClientInfo
+	r.GET("/", func(w http.ResponseWriter, req *http.Request) {
+		client = ClientFromContext(req)
+	})
+
+	tests := []struct {
+		name       string
+		header     ForwardingHeader
+		remoteAddr string
+		headers    map[string][]string
+		expected   ClientInfo
+	}{
+		{
+			name:       "direct request",
+			remoteAddr: "203.0.113.5:1234",
+			expected:   ClientInfo{IP: "203.0.113.5", Scheme: "http", Host: "example.com"},
+		},
+		{
+			name:       "untrusted peer headers are ignored",
+			remoteAddr: "203.0.113.5:1234",
+			headers:    map[string][]string{"X-Forwarded-For": {"1.2.3.4"}, "X-Forwarded-Proto": {"https"}},
+			expected:   ClientInfo{IP: "203.0.113.5", Scheme: "http", Host: "example.com"},
+		},
+		{
+			name:       "x-forwarded-for skips trusted hops",
+			remoteAddr: "10.0.0.2:1234",
+			headers: map[string][]string{
+				"X-Forwarded-For":   {"6.6.6.6, 198.51.100.7", "10.1.1.1"},
+				"X-Forwarded-Proto": {"https"},
+				"X-Forwarded-Host":  {"api.example.com"},
+			},
+			expected: ClientInfo{IP: "198.51.100.7", Scheme: "https", Host: "api.example.com"},
+		},
+		{
+			name:       "x-forwarded-for through trusted proxies only",
+			remoteAddr: "192.168.1.1:1234",
+			headers:    map[string][]string{"X-Forwarded-For": {"10.0.0.9, 10.0.0.8"}},
+			expected:   ClientInfo{IP: "10.0.0.9", Scheme: "http", Host: "example.com"},
+		},
+		{
+			name:       "invalid x-forwarded-for",
+			remoteAddr: "10.0.0.2:1234",
+			headers:    map[string][]string{"X-Forwarded-For": {"unknown"}},
+			expected:   ClientInfo{IP: "10.0.0.2", Scheme: "http", Host: "example.com"},
+		},
+		{
+			name:       "client cannot choose its IP with headers the proxy does not set",
+			remoteAddr: "10.0.0.2:1234",
+			headers: map[string][]string{
+				"X-Forwarded-For": {"198.51.100.7"},
+				"Forwarded":       {"for=1.2.3.4;proto=https;host=evil.example.com"},
+				"X-Real-Ip":       {"1.2.3.4"},
+			},
+			expected: ClientInfo{IP: "198.51.100.7", Scheme: "http", Host: "example.com"},
+		},
+		{
+			name:       "x-real-ip ignored by default",
+			remoteAddr: "10.0.0.2:1234",
+			headers:    map[string][]string{"X-Real-Ip": {"1.2.3.4"}},
+			expected:   ClientInfo{IP: "10.0.0.2", Scheme: "http", Host: "example.com"},
+		},
+		{
+			name:       "forwarded",
+			header:     HeaderForwarded,
+			remoteAddr: "[fd00::1]:1234",
+			headers: map[string][]string{
+				"Forwarded": {`for=192.0.2.60;proto=https;host=shop.example.com, for="[2001:db8::17]:4711";proto=http, for=10.0.0.3`},
+			},
+			expected: ClientInfo{IP: "2001:db8::17", Scheme: "http", Host: "example.com"},
+		},
+		{
+			name:       "forwarded ignores x-forwarded-for",
+			header:     HeaderForwarded,
+			remoteAddr: "10.0.0.2:1234",
+			headers: map[string][]string{
+				"Forwarded":       {"for=192.0.2.60;proto=https;host=shop.example.com"},
+				"X-Forwarded-For": {"1.2.3.4"},
+			},
+			expected: ClientInfo{IP: "192.0.2.60", Scheme: "https", Host: "shop.example.com"},
+		},
+		{
+			name:       "forwarded missing",
+			header:     HeaderForwarded,
+			remoteAddr: "10.0.0.2:1234",
+			headers:    map[string][]string{"X-Forwarded-For": {"1.2.3.4"}},
+			expected:   ClientInfo{IP: "10.0.0.2", Scheme: "http", Host: "example.com"},
+		},
+		{
+			name:       "x-real-ip",
+			header:     HeaderXRealIP,
+			remoteAddr: "10.0.0.2:1234",
+			headers: map[string][]string{
+				"X-Real-Ip":       {"198.51.100.7"},
+				"X-Forwarded-For": {"1.2.3.4"},
+			},
+			expected: ClientInfo{IP: "198.51.100.7", Scheme: "http", Host: "example.com"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := tt.header
+			if header == "" {
+				header = HeaderXForwardedFor
+			}
+			r.ForwardingHeader(header)
+			req := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for name, values := range tt.headers {
+				req.Header[name] = values
+			}
+			r.ServerHTTP(httptest.NewRecorder(), req)
+
+			if client != tt.expected {
+				t.Errorf("expected %+v, got %+v", tt.expected, client)
+			}
+		})
+	}
+}
+
+func TestRouterForwardingHeaderUnsupported(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected a panic for an unsupported header")
+		}
+	}()
+	NewRouter().ForwardingHeader("X-Client-IP")
+}
+
+func TestRouterTrustedProxiesInvalid(t *testing.T) {
+	r := NewRouter()
+	if err := r.TrustedProxies("10.0.0.0/8"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := r.TrustedProxies("10.0.0.0/8", "not-an-ip"); err == nil {
+		t.Fatal("expected an error for an invalid entry")
+	}
+	if len(r.trustedProxies) != 1 {
+		t.Errorf("expected the previous list to be kept, got %v", r.trustedProxies)
+	}
+}
+
+func TestClientFromContextWithoutRouter(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "https://example.com/", nil)
+	req.RemoteAddr = "198.51.100.7:4000"
+	req.TLS = &tls.ConnectionState{}
+	req.Header.Set("X-Forwarded-For", "1.2.3.4")
+
+	expected := ClientInfo{IP: "198.51.100.7", Scheme: "https", Host: "example.com"}
+	if client := ClientFromContext(req); client != expected {
+		t.Errorf("expected %+v, got %+v", expected, client)
+	}
+	if ip := ClientIPFromContext(req); ip != "198.51.100.7" {
+		t.Errorf("expected 198.51.100.7, got %q", ip)
+	}
+}
+
+func TestParsePrefix(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		err      bool
+	}{
+		{input: "10.1.2.3/8", expected: "10.0.0.0/8"},
+		{input: "192.168.0.1", expected: "192.168.0.1/32"},
+		{input: "::ffff:192.168.0.1", expected: "192.168.0.1/32"},
+		{input: "2001:db8::/32", expected: "2001:db8::/32"},
+		{input: "10.0.0.0/33", err: true},
+		{input: "office", err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			prefix, err := ParsePrefix(tt.input)
+			if tt.err {
+				if err == nil {
+					t.Errorf("expected an error, got %v", prefix)
+				}
+				return
+			}
+			if err != nil || prefix.String() != tt.expected {
+				t.Errorf("expected %s, got %v (%v)", tt.expected, prefix, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,232 @@
+package goapi
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var codecsKey = contextKey("codecs")
+
+// ErrNotAcceptable is returned by Render when none of the available codecs satisfies the Accept header.
+var ErrNotAcceptable = errors.New("goapi: no codec matches the Accept header")
+
+// Codec encodes response values into one media type.
+type Codec interface {
+	// ContentType returns the value of the Content-Type header written with the encoded body,
+	// for example "application/json". Its media type is matched against the Accept header.
+	ContentType() string
+	// Encode writes the encoded form of v to w.
+	Encode(w io.Writer, v any) error
+}
+
+// CodecRegistry is an ordered set of codecs used by Render. When the client accepts several of
+// them with the same preference, the codec registered first wins.
+type CodecRegistry struct {
+	codecs []Codec
+}
+
+// DefaultCodecs is the registry used by Render when no group along the route configured one.
+// It serves JSON, XML, CSV and MessagePack, with JSON as the default.
+var DefaultCodecs = NewCodecRegistry(JSONCodec{}, XMLCodec{}, CSVCodec{}, MsgPackCodec{})
+
+// NewCodecRegistry creates a registry holding the given codecs, in order of preference.
+func NewCodecRegistry(codecs ...Codec) *CodecRegistry {
+	registry := &CodecRegistry{}
+	for _, codec := range codecs {
+		registry.Register(codec)
+	}
+	return registry
+}
+
+// Register adds a codec to the registry. A codec with the same media type as an existing one replaces it.
+func (c *CodecRegistry) Register(codec Codec) {
+	mediaType := codecMediaType(codec)
+	for i, existing := range c.codecs {
+		if codecMediaType(existing) == mediaType {
+			c.codecs[i] = codec
+			return
+		}
+	}
+	c.codecs = append(c.codecs, codec)
+}
+
+// Only returns a new registry restricted to the codecs whose media type is listed, in the order given.
+func (c *CodecRegistry) Only(mediaTypes ...string) *CodecRegistry {
+	restricted := &CodecRegistry{}
+	for _, mediaType := range mediaTypes {
+		for _, codec := range c.codecs {
+			if codecMediaType(codec) == strings.ToLower(mediaType) {
+				restricted.codecs = append(restricted.codecs, codec)
+			}
+		}
+	}
+	return restricted
+}
+
+// With returns a copy of the registry with the given codecs registered on top of the existing ones.
+func (c *CodecRegistry) With(codecs ...Codec) *CodecRegistry {
+	extended := &CodecRegistry{codecs: append([]Codec(nil), c.codecs...)}
+	for _, codec := range codecs {
+		extended.Register(codec)
+	}
+	return extended
+}
+
+// Negotiate selects the codec that best satisfies the given Accept header value.
+// An empty header accepts anything and selects the first codec.
+func (c *CodecRegistry) Negotiate(accept string) (Codec, bool) {
+	if len(c.codecs) == 0 {
+		return nil, false
+	}
+	if strings.TrimSpace(accept) == "" {
+		return c.codecs[0], true
+	}
+
+	ranges := parseAccept(accept)
+	var best Codec
+	bestQ := 0.0
+	for _, codec := range c.codecs {
+		if q := acceptQuality(ranges, codecMediaType(codec)); q > bestQ {
+			best, bestQ = codec, q
+		}
+	}
+	return best, best != nil
+}
+
+// Codecs sets the codec registry used by Render for the routes of the current group and its subgroups.
+// It can restrict the default set or override it with custom codecs.
+//
+// Example:
+//
+//	api := goapi.NewRouter()
+//	reports := api.Group("/reports")
+//	reports.Codecs(goapi.DefaultCodecs.Only("text/csv", "application/json"))
+func (g *Group) Codecs(registry *CodecRegistry) {
+	g.codecs = registry
+}
+
+// lookupCodecs returns the codec registry of the nearest group that configured one.
+func (g *Group) lookupCodecs() *CodecRegistry {
+	for group := g; group != nil; group = group.parent {
+		if group.codecs != nil {
+			return group.codecs
+		}
+	}
+	return nil
+}
+
+// Render encodes value with the codec that best matches the request's Accept header and writes it
+// with the given status code.
+//
+// The codecs come from the nearest group that called Codecs, or DefaultCodecs. When no codec is
+// acceptable a 406 Not Acceptable error is written and ErrNotAcceptable is returned. When encoding fails
+// a 500 error is written and the encoding error is returned. In every case a response has been written
+// once Render returns, so the error is only informative.
+//
+// Parameters:
+// - w: The http.ResponseWriter to write the response.
+// - r: The *http.Request whose Accept header drives the negotiation.
+// - status: The HTTP status code of the response.
+// - value: The value to encode.
+//
+// Example:
+//
+//	api.GET("/users", func(w http.ResponseWriter, r *http.Request) {
+//		_ = goapi.Render(w, r, http.StatusOK, users)
+//	})
+func Render(w http.ResponseWriter, r *http.Request, status int, value any) error {
+	registry := DefaultCodecs
+	if codecs, ok := r.Context().Value(codecsKey).(*CodecRegistry); ok {
+		registry = codecs
+	}
+
+	w.Header().Add("Vary", "Accept")
+
+	codec, ok := registry.Negotiate(r.Header.Get("Accept"))
+	if !ok {
+		WriteError(w, r, &HTTPError{Status: http.StatusNotAcceptable, Message: "none of the accepted media types can be produced", Err: ErrNotAcceptable})
+		return ErrNotAcceptable
+	}
+
+	var buf bytes.Buffer
+	if err := codec.Encode(&buf, value); err != nil {
+		err = fmt.Errorf("goapi: encoding %s response: %w", codecMediaType(codec), err)
+		WriteError(w, r, err)
+		return err
+	}
+
+	w.Header().Set("Content-Type", codec.ContentType())
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.WriteHeader(status)
+	if r.Method != http.MethodHead {
+		_, _ = buf.WriteTo(w)
+	}
+	return nil
+}
+
+// acceptRange is a single media range of an Accept header.
+type acceptRange struct {
+	mediaType string
+	q         float64
+}
+
+// parseAccept parses an Accept header into its media ranges. Ranges that cannot be parsed are ignored.
+func parseAccept(accept string) []acceptRange {
+	var ranges []acceptRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			if strings.TrimSpace(part) != "*" {
+				continue
+			}
+			mediaType = "*/*"
+		}
+
+		q := 1.0
+		if value, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil && parsed >= 0 && parsed <= 1 {
+				q = parsed
+			}
+		}
+		ranges = append(ranges, acceptRange{mediaType: mediaType, q: q})
+	}
+	return ranges
+}
+
+// acceptQuality returns the quality the client assigns to mediaType, using the most specific matching range.
+func acceptQuality(ranges []acceptRange, mediaType string) float64 {
+	mainType, _, _ := strings.Cut(mediaType, "/")
+	q, specificity := 0.0, -1
+	for _, ar := range ranges {
+		var s int
+		switch {
+		case ar.mediaType == mediaType:
+			s = 2
+		case ar.mediaType == mainType+"/*":
+			s = 1
+		case ar.mediaType == "*/*":
+			s = 0
+		default:
+			continue
+		}
+		if s > specificity {
+			q, specificity = ar.q, s
+		}
+	}
+	return q
+}
+
+// codecMediaType returns the lower-cased media type of a codec's content type, without parameters.
+func codecMediaType(codec Codec) string {
+	mediaType, _, err := mime.ParseMediaType(codec.ContentType())
+	if err != nil {
+		return strings.ToLower(codec.ContentType())
+	}
+	return mediaType
+}
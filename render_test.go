@@ -0,0 +1,195 @@
+package goapi
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type renderUser struct {
+	ID   int    `json:"id" xml:"id" csv:"id"`
+	Name string `json:"name" xml:"name" csv:"name"`
+}
+
+type textCodec struct{}
+
+func (textCodec) ContentType() string { return "text/plain; charset=utf-8" }
+
+func (textCodec) Encode(w io.Writer, v any) error {
+	_, err := io.WriteString(w, "text")
+	return err
+}
+
+func TestRenderNegotiation(t *testing.T) {
+	users := []renderUser{{ID: 1, Name: "Ana"}}
+
+	tests := []struct {
+		name                string
+		accept              string
+		expectedStatus      int
+		expectedContentType string
+		expectedBody        string
+	}{
+		{
+			name:                "No Accept header uses the first codec",
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/json",
+			expectedBody:        `[{"id":1,"name":"Ana"}]`,
+		},
+		{
+			name:                "Exact match",
+			accept:              "text/csv",
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "text/csv; charset=utf-8",
+			expectedBody:        "id,name\n1,Ana",
+		},
+		{
+			name:                "Highest q-value wins",
+			accept:              "application/json;q=0.5, application/xml;q=0.9",
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/xml; charset=utf-8",
+		},
+		{
+			name:                "Most specific range decides",
+			accept:              "application/*;q=0.2, application/msgpack;q=0, text/*;q=0.1",
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/json",
+		},
+		{
+			name:                "Wildcard",
+			accept:              "*/*",
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/json",
+		},
+		{
+			name:           "Nothing acceptable",
+			accept:         "image/png",
+			expectedStatus: http.StatusNotAcceptable,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/users", nil)
+			if test.accept != "" {
+				req.Header.Set("Accept", test.accept)
+			}
+			rec := httptest.NewRecorder()
+
+			err := Render(rec, req, http.StatusOK, users)
+
+			if rec.Code != test.expectedStatus {
+				t.Errorf("expected status %d, got %d", test.expectedStatus, rec.Code)
+			}
+			if test.expectedStatus == http.StatusNotAcceptable {
+				if err != ErrNotAcceptable {
+					t.Errorf("expected ErrNotAcceptable, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != test.expectedContentType {
+				t.Errorf("expected content type %q, got %q", test.expectedContentType, ct)
+			}
+			if rec.Header().Get("Vary") != "Accept" {
+				t.Errorf("expected Vary: Accept, got %q", rec.Header().Get("Vary"))
+			}
+			if test.expectedBody != "" && strings.TrimSpace(rec.Body.String()) != test.expectedBody {
+				t.Errorf("expected body %q, got %q", test.expectedBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestRenderEncodingError(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+
+	if err := Render(rec, req, http.StatusOK, func() {}); err == nil {
+		t.Fatalf("expected an encoding error")
+	}
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected status %d, got %d", http.StatusInternalServerError, rec.Code)
+	}
+}
+
+func TestGroupCodecs(t *testing.T) {
+	root := &Group{}
+	root.GET("/default", func(w http.ResponseWriter, r *http.Request) {
+		_ = Render(w, r, http.StatusOK, "value")
+	})
+
+	reports := root.Group("/reports")
+	reports.Codecs(DefaultCodecs.Only("text/csv"))
+	reports.GET("/daily", func(w http.ResponseWriter, r *http.Request) {
+		_ = Render(w, r, http.StatusOK, [][]string{{"a", "b"}})
+	})
+
+	custom := reports.Group("/custom")
+	custom.Codecs(NewCodecRegistry(textCodec{}))
+	custom.GET("/text", func(w http.ResponseWriter, r *http.Request) {
+		_ = Render(w, r, http.StatusOK, "ignored")
+	})
+
+	tests := []struct {
+		name           string
+		path           string
+		accept         string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{"Root uses defaults", "/default", "application/json", http.StatusOK, `"value"`},
+		{"Restricted group rejects JSON", "/reports/daily", "application/json", http.StatusNotAcceptable, ""},
+		{"Restricted group serves CSV", "/reports/daily", "", http.StatusOK, "a,b"},
+		{"Subgroup override", "/reports/custom/text", "text/plain", http.StatusOK, "text"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, test.path, nil)
+			req.Header.Set("Accept", test.accept)
+			rec := httptest.NewRecorder()
+
+			root.handleRequest(rec, req)
+
+			if rec.Code != test.expectedStatus {
+				t.Errorf("expected status %d, got %d", test.expectedStatus, rec.Code)
+			}
+			if test.expectedBody != "" && strings.TrimSpace(rec.Body.String()) != test.expectedBody {
+				t.Errorf("expected body %q, got %q", test.expectedBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestCodecRegistryRegisterReplaces(t *testing.T) {
+	registry := NewCodecRegistry(JSONCodec{}, XMLCodec{})
+	registry.Register(customJSON{})
+
+	codec, ok := registry.Negotiate("application/json")
+	if !ok {
+		t.Fatalf("expected a codec")
+	}
+	if _, isCustom := codec.(customJSON); !isCustom {
+		t.Errorf("expected the custom JSON codec to replace the default, got %T", codec)
+	}
+
+	var buf bytes.Buffer
+	if err := codec.Encode(&buf, nil); err != nil || buf.String() != "custom" {
+		t.Errorf("expected custom encoding, got %q (%v)", buf.String(), err)
+	}
+}
+
+type customJSON struct{}
+
+func (customJSON) ContentType() string { return "application/json; charset=utf-8" }
+
+func (customJSON) Encode(w io.Writer, v any) error {
+	_, err := io.WriteString(w, "custom")
+	return err
+}
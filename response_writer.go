@@ -0,0 +1,207 @@
+package goapi
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+// ResponseWriter is an http.ResponseWriter that records the response status, the number of body bytes
+// written and when the response started, while still exposing the optional interfaces of the writer it wraps.
+//
+// The writer returned by WrapResponseWriter implements http.Flusher, http.Hijacker and http.Pusher exactly
+// when the wrapped writer does, so type assertions report the real capabilities of the connection.
+// ReadFrom is always available and falls back to io.Copy. Unwrap returns the wrapped writer, so
+// http.ResponseController reaches deadlines and other features of the underlying connection.
+type ResponseWriter interface {
+	http.ResponseWriter
+	io.ReaderFrom
+
+	// Status returns the status code sent to the client, or 0 if the header has not been written yet.
+	Status() int
+	// BytesWritten returns the number of body bytes written.
+	BytesWritten() int64
+	// Written reports whether the response header has been sent.
+	Written() bool
+	// FirstByteTime returns when the response header was sent, or the zero time if it has not been.
+	FirstByteTime() time.Time
+	// Hijacked reports whether the connection was taken over through Hijack.
+	Hijacked() bool
+	// Unwrap returns the wrapped http.ResponseWriter.
+	Unwrap() http.ResponseWriter
+}
+
+// WrapResponseWriter wraps w in a ResponseWriter. If w already is a ResponseWriter it is returned unchanged,
+// so several middlewares share the same recorded status and size.
+//
+// Example:
+//
+//	func timing(next goapi.HandlerFunc) goapi.HandlerFunc {
+//		return func(w http.ResponseWriter, r *http.Request) {
+//			start := time.Now()
+//			ww := goapi.WrapResponseWriter(w)
+//			next(ww, r)
+//			log.Printf("%d %d bytes in %s", ww.Status(), ww.BytesWritten(), time.Since(start))
+//		}
+//	}
+func WrapResponseWriter(w http.ResponseWriter) ResponseWriter {
+	if wrapped, ok := w.(ResponseWriter); ok {
+		return wrapped
+	}
+	rw := &responseWriter{ResponseWriter: w}
+
+	_, canFlush := w.(http.Flusher)
+	_, canHijack := w.(http.Hijacker)
+	_, canPush := w.(http.Pusher)
+	f, h, p := flusher{rw}, hijacker{rw}, pusher{rw}
+	switch {
+	case canFlush && canHijack && canPush:
+		return struct {
+			*responseWriter
+			http.Flusher
+			http.Hijacker
+			http.Pusher
+		}{rw, f, h, p}
+	case canFlush && canHijack:
+		return struct {
+			*responseWriter
+			http.Flusher
+			http.Hijacker
+		}{rw, f, h}
+	case canFlush && canPush:
+		return struct {
+			*responseWriter
+			http.Flusher
+			http.Pusher
+		}{rw, f, p}
+	case canHijack && canPush:
+		return struct {
+			*responseWriter
+			http.Hijacker
+			http.Pusher
+		}{rw, h, p}
+	case canFlush:
+		return struct {
+			*responseWriter
+			http.Flusher
+		}{rw, f}
+	case canHijack:
+		return struct {
+			*responseWriter
+			http.Hijacker
+		}{rw, h}
+	case canPush:
+		return struct {
+			*responseWriter
+			http.Pusher
+		}{rw, p}
+	}
+	return rw
+}
+
+// flusher, hijacker and pusher adapt the methods of responseWriter to the optional interfaces, which
+// WrapResponseWriter only exposes when the wrapped writer implements them.
+type (
+	flusher  struct{ rw *responseWriter }
+	hijacker struct{ rw *responseWriter }
+	pusher   struct{ rw *responseWriter }
+)
+
+func (f flusher) Flush() { f.rw.flush() }
+
+func (h hijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) { return h.rw.hijack() }
+
+func (p pusher) Push(target string, opts *http.PushOptions) error { return p.rw.push(target, opts) }
+
+type responseWriter struct {
+	http.ResponseWriter
+	status    int
+	bytes     int64
+	firstByte time.Time
+	hijacked  bool
+}
+
+func (rw *responseWriter) WriteHeader(code int) {
+	if rw.status != 0 || rw.hijacked {
+		return
+	}
+	rw.ResponseWriter.WriteHeader(code)
+	// Informational responses may be followed by the final one.
+	if code >= 100 && code < 200 && code != http.StatusSwitchingProtocols {
+		return
+	}
+	rw.status = code
+	rw.firstByte = time.Now()
+}
+
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	if rw.status == 0 {
+		rw.WriteHeader(http.StatusOK)
+	}
+	n, err := rw.ResponseWriter.Write(b)
+	rw.bytes += int64(n)
+	return n, err
+}
+
+func (rw *responseWriter) ReadFrom(src io.Reader) (int64, error) {
+	if rw.status == 0 {
+		rw.WriteHeader(http.StatusOK)
+	}
+	if readerFrom, ok := rw.ResponseWriter.(io.ReaderFrom); ok {
+		n, err := readerFrom.ReadFrom(src)
+		rw.bytes += n
+		return n, err
+	}
+	// Hide ReadFrom so io.Copy does not call back into this method.
+	return io.Copy(struct{ io.Writer }{rw}, src)
+}
+
+func (rw *responseWriter) flush() {
+	if rw.status == 0 {
+		rw.WriteHeader(http.StatusOK)
+	}
+	rw.ResponseWriter.(http.Flusher).Flush()
+}
+
+func (rw *responseWriter) hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, brw, err := rw.ResponseWriter.(http.Hijacker).Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	rw.hijacked = true
+	if rw.status == 0 {
+		rw.status = http.StatusSwitchingProtocols
+		rw.firstByte = time.Now()
+	}
+	return conn, brw, nil
+}
+
+func (rw *responseWriter) push(target string, opts *http.PushOptions) error {
+	return rw.ResponseWriter.(http.Pusher).Push(target, opts)
+}
+
+func (rw *responseWriter) Status() int {
+	return rw.status
+}
+
+func (rw *responseWriter) BytesWritten() int64 {
+	return rw.bytes
+}
+
+func (rw *responseWriter) Written() bool {
+	return rw.status != 0
+}
+
+func (rw *responseWriter) FirstByteTime() time.Time {
+	return rw.firstByte
+}
+
+func (rw *responseWriter) Hijacked() bool {
+	return rw.hijacked
+}
+
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
@@ -0,0 +1,227 @@
+package goapi
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// plainWriter implements only http.ResponseWriter.
+type plainWriter struct {
+	header http.Header
+	status int
+	body   strings.Builder
+}
+
+func (p *plainWriter) Header() http.Header {
+	if p.header == nil {
+		p.header = make(http.Header)
+	}
+	return p.header
+}
+
+func (p *plainWriter) Write(b []byte) (int, error) { return p.body.Write(b) }
+
+func (p *plainWriter) WriteHeader(code int) { p.status = code }
+
+// fullWriter adds every optional interface on top of plainWriter.
+type fullWriter struct {
+	plainWriter
+	flushed  bool
+	hijacked bool
+	pushed   string
+	readFrom bool
+}
+
+func (f *fullWriter) Flush() { f.flushed = true }
+
+func (f *fullWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	f.hijacked = true
+	server, client := net.Pipe()
+	client.Close()
+	return server, bufio.NewReadWriter(bufio.NewReader(server), bufio.NewWriter(server)), nil
+}
+
+func (f *fullWriter) Push(target string, opts *http.PushOptions) error {
+	f.pushed = target
+	return nil
+}
+
+func (f *fullWriter) ReadFrom(src io.Reader) (int64, error) {
+	f.readFrom = true
+	return io.Copy(&f.plainWriter.body, src)
+}
+
+func TestWrapResponseWriterRecords(t *testing.T) {
+	rec := httptest.NewRecorder()
+	ww := WrapResponseWriter(rec)
+
+	if ww.Written() || ww.Status() != 0 || !ww.FirstByteTime().IsZero() {
+		t.Fatalf("expected a fresh writer to have nothing recorded")
+	}
+
+	before := time.Now()
+	ww.WriteHeader(http.StatusCreated)
+	ww.WriteHeader(http.StatusInternalServerError)
+	_, _ = ww.Write([]byte("hello"))
+	_, _ = ww.Write([]byte(" world"))
+
+	if ww.Status() != http.StatusCreated || rec.Code != http.StatusCreated {
+		t.Errorf("expected status %d, got %d (recorder %d)", http.StatusCreated, ww.Status(), rec.Code)
+	}
+	if ww.BytesWritten() != 11 {
+		t.Errorf("expected 11 bytes, got %d", ww.BytesWritten())
+	}
+	if ww.FirstByteTime().Before(before) {
+		t.Errorf("expected first byte time to be recorded")
+	}
+	if WrapResponseWriter(ww) != ww {
+		t.Errorf("expected wrapping a ResponseWriter to return it unchanged")
+	}
+	if ww.Unwrap() != rec {
+		t.Errorf("expected Unwrap to return the original writer")
+	}
+}
+
+func TestWrapResponseWriterInformational(t *testing.T) {
+	inner := &plainWriter{}
+	ww := WrapResponseWriter(inner)
+
+	ww.WriteHeader(http.StatusEarlyHints)
+	if ww.Written() {
+		t.Errorf("expected informational responses not to count as the final status")
+	}
+	ww.WriteHeader(http.StatusAccepted)
+	if ww.Status() != http.StatusAccepted || inner.status != http.StatusAccepted {
+		t.Errorf("expected final status %d, got %d", http.StatusAccepted, ww.Status())
+	}
+}
+
+func TestWrapResponseWriterImplicitStatus(t *testing.T) {
+	ww := WrapResponseWriter(httptest.NewRecorder())
+	_, _ = ww.Write([]byte("ok"))
+	if ww.Status() != http.StatusOK {
+		t.Errorf("expected implicit status 200, got %d", ww.Status())
+	}
+}
+
+func TestWrapResponseWriterDelegates(t *testing.T) {
+	inner := &fullWriter{}
+	ww := WrapResponseWriter(inner)
+
+	ww.(http.Flusher).Flush()
+	if !inner.flushed || ww.Status() != http.StatusOK {
+		t.Errorf("expected Flush to be delegated and commit the status")
+	}
+
+	if err := ww.(http.Pusher).Push("/style.css", nil); err != nil || inner.pushed != "/style.css" {
+		t.Errorf("expected Push to be delegated, got %v", err)
+	}
+
+	n, err := ww.ReadFrom(strings.NewReader("streamed"))
+	if err != nil || n != 8 || !inner.readFrom || ww.BytesWritten() != 8 {
+		t.Errorf("expected ReadFrom to be delegated and counted, got n=%d err=%v", n, err)
+	}
+
+	conn, _, err := ww.(http.Hijacker).Hijack()
+	if err != nil || !inner.hijacked || !ww.Hijacked() {
+		t.Fatalf("expected Hijack to be delegated, got %v", err)
+	}
+	conn.Close()
+}
+
+func TestWrapResponseWriterUnsupported(t *testing.T) {
+	inner := &plainWriter{}
+	ww := WrapResponseWriter(inner)
+
+	if _, ok := ww.(http.Flusher); ok {
+		t.Errorf("expected no Flush for a writer that cannot flush")
+	}
+	if _, ok := ww.(http.Hijacker); ok {
+		t.Errorf("expected no Hijack for a writer that cannot hijack")
+	}
+	if _, ok := ww.(http.Pusher); ok {
+		t.Errorf("expected no Push for a writer that cannot push")
+	}
+	if err := http.NewResponseController(ww).Flush(); !errors.Is(err, http.ErrNotSupported) {
+		t.Errorf("expected ErrNotSupported from the response controller, got %v", err)
+	}
+
+	n, err := ww.ReadFrom(strings.NewReader("fallback"))
+	if err != nil || n != 8 || inner.body.String() != "fallback" || ww.BytesWritten() != 8 {
+		t.Errorf("expected ReadFrom to fall back to io.Copy, got n=%d err=%v body=%q", n, err, inner.body.String())
+	}
+}
+
+// flushOnlyWriter implements http.Flusher but not http.Hijacker or http.Pusher.
+type flushOnlyWriter struct {
+	plainWriter
+	flushed bool
+}
+
+func (f *flushOnlyWriter) Flush() { f.flushed = true }
+
+func TestWrapResponseWriterPartialCapabilities(t *testing.T) {
+	inner := &flushOnlyWriter{}
+	ww := WrapResponseWriter(inner)
+
+	flusher, ok := ww.(http.Flusher)
+	if !ok {
+		t.Fatalf("expected Flush to be exposed")
+	}
+	flusher.Flush()
+	if !inner.flushed || ww.Status() != http.StatusOK {
+		t.Errorf("expected Flush to be delegated and commit the status")
+	}
+	if _, ok := ww.(http.Hijacker); ok {
+		t.Errorf("expected no Hijack")
+	}
+	if _, ok := ww.(http.Pusher); ok {
+		t.Errorf("expected no Push")
+	}
+	if WrapResponseWriter(ww) != ww {
+		t.Errorf("expected an already wrapped writer to be returned unchanged")
+	}
+}
+
+func TestWrapResponseWriterThroughRouter(t *testing.T) {
+	var recorded ResponseWriter
+	router := NewRouter()
+	router.Use(func(next HandlerFunc) HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			recorded = WrapResponseWriter(w)
+			next(recorded, r)
+		}
+	})
+	router.WebSocket("/ws", func(conn *WebSocketConn, r *http.Request) {})
+	router.GET("/events", func(w http.ResponseWriter, r *http.Request) {
+		stream, err := SSE(w, r)
+		if err == nil {
+			_ = stream.Send(Event{Data: "x"})
+		}
+	})
+
+	server := httptest.NewServer(http.HandlerFunc(router.ServerHTTP))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/events")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "data: x\n\n" || recorded.Status() != http.StatusOK {
+		t.Errorf("expected SSE through the wrapper, got %q with status %d", body, recorded.Status())
+	}
+
+	_, handshake := dialWebSocket(t, server, "/ws", nil)
+	if handshake.StatusCode != http.StatusSwitchingProtocols {
+		t.Errorf("expected WebSocket upgrade through the wrapper, got %d", handshake.StatusCode)
+	}
+}
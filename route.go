@@ -1,15 +1,141 @@
 package goapi
 
 import (
+	"context"
+	"errors"
+	"net/http"
 	"regexp"
 	"strings"
+	"time"
 )
 
+var authorizedKey = contextKey("authorized")
+
+// errRequirementsNotChecked is reported when a route with requirements is reached without authorization.
+var errRequirementsNotChecked = errors.New("goapi: the route requirements were not checked by an authorization middleware")
+
 type route struct {
-	method     string
-	pattern    *regexp.Regexp
-	paramNames []string
-	handler    HandlerFunc
+	method       string
+	path         string
+	pattern      *regexp.Regexp
+	paramNames   []string
+	handler      HandlerFunc
+	name         string
+	timeout      time.Duration
+	requirements []string
+}
+
+// Route is a handle to a registered route. It is returned by Handle and the method shortcuts such as GET
+// so that the route can be configured further.
+//
+// Example:
+//
+//	api.GET("/orders/:id", getOrder).Name("orders.show")
+type Route struct {
+	group *Group
+	index int
+}
+
+// Name sets the name of the route. Handlers and middlewares read it from RouteInfo.Name; the cache
+// middleware, for example, purges the cached responses of a route by name.
+//
+// Returns: The same *Route, for chaining.
+func (r *Route) Name(name string) *Route {
+	r.route().name = name
+	return r
+}
+
+// Timeout overrides the request timeout applied to the route by the Timeout middleware. A negative duration
+// disables the timeout, for example for a streaming endpoint. It has no effect without the middleware.
+//
+// Returns: The same *Route, for chaining.
+//
+// Example:
+//
+//	api.Use(middlewares.Timeout(5 * time.Second))
+//	api.POST("/reports", createReport).Timeout(30 * time.Second)
+func (r *Route) Timeout(timeout time.Duration) *Route {
+	r.route().timeout = timeout
+	return r
+}
+
+// Require declares the permissions, such as scopes or roles, a principal needs to access the route. All of
+// them are required; calling Require again adds to them. They are enforced by an authorization middleware
+// such as auth.Authorize, which decides how a principal is granted a permission, and are listed by Routes
+// for audits.
+//
+// The route fails closed: a request that reaches its handler without having been marked with
+// WithAuthorized, because no authorization middleware covers the route, is answered with 500 Internal
+// Server Error.
+//
+// Returns: The same *Route, for chaining.
+//
+// Example:
+//
+//	api.Use(auth.JWT(jwtConfig), auth.Authorize(auth.AuthorizeConfig{}))
+//	api.GET("/admin/users", listUsers).Require("admin:read")
+func (r *Route) Require(permissions ...string) *Route {
+	rt := r.route()
+	rt.requirements = append(rt.requirements, permissions...)
+	return r
+}
+
+// WithAuthorized returns a copy of the request marked as authorized for the requirements of its route.
+// Authorization middlewares call it once the principal was granted every permission of RouteInfo.Requirements.
+func WithAuthorized(r *http.Request) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), authorizedKey, true))
+}
+
+// AuthorizedFromContext reports whether an authorization middleware marked the request with WithAuthorized.
+func AuthorizedFromContext(r *http.Request) bool {
+	authorized, _ := r.Context().Value(authorizedKey).(bool)
+	return authorized
+}
+
+// requireAuthorization wraps the handler of a route with requirements so that it only runs for requests
+// marked by an authorization middleware.
+func requireAuthorization(handler HandlerFunc) HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !AuthorizedFromContext(r) {
+			WriteError(w, r, &HTTPError{
+				Status:  http.StatusInternalServerError,
+				Message: http.StatusText(http.StatusInternalServerError),
+				Err:     errRequirementsNotChecked,
+			})
+			return
+		}
+		handler(w, r)
+	}
+}
+
+// route returns the registered route the handle points to.
+func (r *Route) route() *route {
+	return &r.group.routes[r.index]
+}
+
+// RouteInfo describes a registered route, such as the one that matched a request.
+type RouteInfo struct {
+	// Method is the HTTP method the route was registered with.
+	Method string
+	// Pattern is the full route pattern, including group prefixes (e.g., "/api/users/:id").
+	Pattern string
+	// Name is the name given to the route with Route.Name, or an empty string.
+	Name string
+	// Timeout is the timeout set with Route.Timeout, or zero.
+	Timeout time.Duration
+	// Requirements are the permissions declared with Route.Require. The slice must not be modified.
+	Requirements []string
+}
+
+// info returns the RouteInfo describing the route.
+func (rt route) info() RouteInfo {
+	return RouteInfo{
+		Method:       rt.method,
+		Pattern:      rt.path,
+		Name:         rt.name,
+		Timeout:      rt.timeout,
+		Requirements: rt.requirements,
+	}
 }
 
 // parsePattern takes a URL pattern string and returns a compiled regular expression and a slice of parameter names.
@@ -1,6 +1,12 @@
 package goapi
 
-import "testing"
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
 
 func TestParsePattern(t *testing.T) {
 	tests := []struct {
@@ -71,3 +77,128 @@ func TestRegexMatching(t *testing.T) {
 		t.Errorf("expected URL %q not to match regex %q, but it did", nonMatchingURL, reg.String())
 	}
 }
+
+func TestRouteName(t *testing.T) {
+	root := &Group{}
+	orders := root.Group("/orders")
+
+	var info RouteInfo
+	route := orders.GET("/:id", func(w http.ResponseWriter, r *http.Request) {
+		info, _ = RouteFromContext(r)
+	})
+	if route.Name("orders.show") != route {
+		t.Errorf("expected Name to return the same route for chaining")
+	}
+	orders.POST("/", mockHandler("created"))
+
+	root.handleRequest(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/orders/7", nil))
+
+	if info.Name != "orders.show" || info.Pattern != "/orders/:id" {
+		t.Errorf("expected route orders.show /orders/:id, got %q %q", info.Name, info.Pattern)
+	}
+}
+
+func TestRouteTimeout(t *testing.T) {
+	root := &Group{}
+
+	var info RouteInfo
+	root.POST("/reports", func(w http.ResponseWriter, r *http.Request) {
+		info, _ = RouteFromContext(r)
+	}).Name("reports.create").Timeout(30 * time.Second)
+
+	root.handleRequest(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/reports", nil))
+
+	if info.Timeout != 30*time.Second || info.Name != "reports.create" {
+		t.Errorf("expected the route timeout to be 30s, got %v", info.Timeout)
+	}
+}
+
+func TestRouteRequire(t *testing.T) {
+	root := &Group{}
+	root.Use(func(next HandlerFunc) HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			next(w, WithAuthorized(r))
+		}
+	})
+
+	var info RouteInfo
+	root.GET("/admin/users", func(w http.ResponseWriter, r *http.Request) {
+		info, _ = RouteFromContext(r)
+	}).Require("admin:read").Require("users:list")
+
+	root.handleRequest(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/admin/users", nil))
+
+	if !slices.Equal(info.Requirements, []string{"admin:read", "users:list"}) {
+		t.Errorf("expected the route requirements, got %v", info.Requirements)
+	}
+
+	// Preflight requests are answered by the router, not by the route.
+	var options RouteInfo
+	root.Use(func(next HandlerFunc) HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			options, _ = RouteFromContext(r)
+			next(w, r)
+		}
+	})
+	root.handleRequest(httptest.NewRecorder(), httptest.NewRequest(http.MethodOptions, "/admin/users", nil))
+	if options.Method != http.MethodOptions || len(options.Requirements) != 0 {
+		t.Errorf("expected no requirements for the preflight request, got %v", options.Requirements)
+	}
+}
+
+func TestRouteRequireFailsClosed(t *testing.T) {
+	root := &Group{}
+	admin := root.Group("/admin")
+	called := false
+	admin.GET("/users", func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}).Require("admin:read")
+	root.GET("/public", mockHandler("public"))
+
+	resp := httptest.NewRecorder()
+	root.handleRequest(resp, httptest.NewRequest(http.MethodGet, "/admin/users", nil))
+	if called || resp.Code != http.StatusInternalServerError {
+		t.Errorf("expected a route with unchecked requirements to answer 500, got %d (handler called: %v)", resp.Code, called)
+	}
+
+	resp = httptest.NewRecorder()
+	root.handleRequest(resp, httptest.NewRequest(http.MethodGet, "/public", nil))
+	if resp.Code != http.StatusOK {
+		t.Errorf("expected routes without requirements to be served, got %d", resp.Code)
+	}
+
+	admin.Use(func(next HandlerFunc) HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			next(w, WithAuthorized(r))
+		}
+	})
+	root.handleRequest(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/admin/users", nil))
+	if !called {
+		t.Errorf("expected the handler to run once the request is authorized")
+	}
+}
+
+func TestRoutes(t *testing.T) {
+	root := &Group{}
+	handler := func(w http.ResponseWriter, r *http.Request) {}
+	root.GET("/health", handler)
+	api := root.Group("/api")
+	api.GET("/orders", handler).Name("orders.list").Require("orders:read")
+	api.Group("/admin").DELETE("/users/:id", handler).Require("admin")
+
+	expected := []RouteInfo{
+		{Method: http.MethodGet, Pattern: "/health"},
+		{Method: http.MethodGet, Pattern: "/api/orders", Name: "orders.list", Requirements: []string{"orders:read"}},
+		{Method: http.MethodDelete, Pattern: "/api/admin/users/:id", Requirements: []string{"admin"}},
+	}
+	routes := root.Routes()
+	if len(routes) != len(expected) {
+		t.Fatalf("expected %d routes, got %v", len(expected), routes)
+	}
+	for i, route := range routes {
+		if route.Method != expected[i].Method || route.Pattern != expected[i].Pattern || route.Name != expected[i].Name ||
+			!slices.Equal(route.Requirements, expected[i].Requirements) {
+			t.Errorf("route %d: expected %+v, got %+v", i, expected[i], route)
+		}
+	}
+}
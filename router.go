@@ -1,9 +1,16 @@
 package goapi
 
-import "net/http"
+import (
+	"context"
+	"net/http"
+	"net/netip"
+)
 
 type Router struct {
 	*Group
+	errorHandler     ErrorHandlerFunc
+	trustedProxies   []netip.Prefix
+	forwardingHeader ForwardingHeader
 }
 
 // NewRouter creates and returns a new instance of Router.
@@ -34,7 +41,27 @@ func NewRouter() *Router {
 // Return:
 // - None.
 func (r *Router) ServerHTTP(w http.ResponseWriter, req *http.Request) {
+	if r.errorHandler != nil {
+		req = req.WithContext(context.WithValue(req.Context(), errorHandlerKey, r.errorHandler))
+	}
+	req = r.withClient(req)
+
 	if !r.handleRequest(w, req) {
 		http.NotFound(w, req)
 	}
 }
+
+// ErrorHandler sets the function used by WriteError to write error responses for every route of the router.
+// The handler must write the response itself and must not call WriteError; it may call DefaultErrorHandler
+// to fall back to the default JSON format.
+//
+// Example:
+//
+//	api := goapi.NewRouter()
+//	api.ErrorHandler(func(w http.ResponseWriter, r *http.Request, err error) {
+//		log.Printf("request failed: %v", err)
+//		goapi.DefaultErrorHandler(w, r, err)
+//	})
+func (r *Router) ErrorHandler(handler ErrorHandlerFunc) {
+	r.errorHandler = handler
+}
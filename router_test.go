@@ -66,3 +66,26 @@ func TestRouterServerHTTP(t *testing.T) {
 		})
 	}
 }
+
+func TestRouterErrorHandler(t *testing.T) {
+	router := NewRouter()
+	var handled error
+	router.ErrorHandler(func(w http.ResponseWriter, r *http.Request, err error) {
+		handled = err
+		w.WriteHeader(http.StatusTeapot)
+	})
+	router.GET("/fail", func(w http.ResponseWriter, r *http.Request) {
+		WriteError(w, r, NewHTTPError(http.StatusBadRequest, "bad"))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/fail", nil)
+	rec := httptest.NewRecorder()
+	router.ServerHTTP(rec, req)
+
+	if rec.Code != http.StatusTeapot {
+		t.Errorf("expected the custom error handler to write the response, got %d", rec.Code)
+	}
+	if handled == nil || handled.Error() != "bad" {
+		t.Errorf("expected the custom error handler to receive the error, got %v", handled)
+	}
+}
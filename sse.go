@@ -0,0 +1,322 @@
+package goapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Event is a single Server-Sent Event.
+type Event struct {
+	// ID sets the client's last event ID, sent back in the Last-Event-ID header on reconnection.
+	ID string
+	// Event is the event type. Clients receive untyped events as "message".
+	Event string
+	// Data is the payload. Multi-line data is split into several data fields.
+	Data string
+	// Retry asks the client to wait this long before reconnecting. Zero leaves it unchanged.
+	Retry time.Duration
+}
+
+// SSEStream writes Server-Sent Events to a client. It is safe for concurrent use.
+type SSEStream struct {
+	mu          sync.Mutex
+	w           http.ResponseWriter
+	controller  *http.ResponseController
+	ctx         context.Context
+	lastEventID string
+}
+
+// SSE starts a Server-Sent Events response: it sets the event-stream headers, disables the server's write
+// deadline for the connection, and flushes the headers to the client.
+//
+// Flushing goes through http.ResponseController, so middlewares that wrap the http.ResponseWriter keep
+// streaming working as long as their wrapper implements Flush or an Unwrap method returning the original
+// writer. An error is returned when the writer cannot flush; the headers have been sent by then, so the
+// handler should simply return.
+//
+// Example:
+//
+//	api.GET("/jobs/:id/progress", func(w http.ResponseWriter, r *http.Request) {
+//		stream, err := goapi.SSE(w, r)
+//		if err != nil {
+//			return
+//		}
+//		_ = stream.Stream(progressEvents, 15*time.Second)
+//	})
+func SSE(w http.ResponseWriter, r *http.Request) (*SSEStream, error) {
+	controller := http.NewResponseController(w)
+
+	header := w.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no")
+	header.Del("Content-Length")
+
+	_ = controller.SetWriteDeadline(time.Time{})
+	w.WriteHeader(http.StatusOK)
+	if err := controller.Flush(); err != nil {
+		return nil, fmt.Errorf("goapi: response writer does not support flushing: %w", err)
+	}
+
+	return &SSEStream{
+		w:           w,
+		controller:  controller,
+		ctx:         r.Context(),
+		lastEventID: r.Header.Get("Last-Event-ID"),
+	}, nil
+}
+
+// LastEventID returns the ID of the last event the client received, as sent in the Last-Event-ID header
+// when it reconnects. It is empty on the first connection.
+func (s *SSEStream) LastEventID() string {
+	return s.lastEventID
+}
+
+// Done returns a channel that is closed when the client disconnects.
+func (s *SSEStream) Done() <-chan struct{} {
+	return s.ctx.Done()
+}
+
+// Send writes an event and flushes it to the client.
+func (s *SSEStream) Send(event Event) error {
+	var b strings.Builder
+	if event.ID != "" {
+		b.WriteString("id: " + sanitizeSSEField(event.ID) + "\n")
+	}
+	if event.Event != "" {
+		b.WriteString("event: " + sanitizeSSEField(event.Event) + "\n")
+	}
+	if event.Retry > 0 {
+		b.WriteString("retry: " + strconv.FormatInt(event.Retry.Milliseconds(), 10) + "\n")
+	}
+	for _, line := range strings.Split(strings.ReplaceAll(event.Data, "\r\n", "\n"), "\n") {
+		b.WriteString("data: " + line + "\n")
+	}
+	b.WriteString("\n")
+	return s.write(b.String())
+}
+
+// Comment writes a comment line, which clients ignore. It is used as a heartbeat to keep idle
+// connections open through proxies.
+func (s *SSEStream) Comment(text string) error {
+	return s.write(": " + sanitizeSSEField(text) + "\n\n")
+}
+
+// Stream sends every event received from events until the channel is closed or the client disconnects,
+// writing a heartbeat comment whenever no event was sent for the heartbeat interval. A heartbeat of
+// zero disables heartbeats. It returns nil when events is closed, the context error when the client
+// disconnects, or the first write error.
+func (s *SSEStream) Stream(events <-chan Event, heartbeat time.Duration) error {
+	var ticks <-chan time.Time
+	if heartbeat > 0 {
+		ticker := time.NewTicker(heartbeat)
+		defer ticker.Stop()
+		ticks = ticker.C
+	}
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return s.ctx.Err()
+		case event, ok := <-events:
+			if !ok {
+				return nil
+			}
+			if err := s.Send(event); err != nil {
+				return err
+			}
+		case <-ticks:
+			if err := s.Comment("heartbeat"); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *SSEStream) write(payload string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.ctx.Err(); err != nil {
+		return err
+	}
+	if _, err := s.w.Write([]byte(payload)); err != nil {
+		return err
+	}
+	return s.controller.Flush()
+}
+
+// sanitizeSSEField removes line breaks, which would otherwise end the field early.
+func sanitizeSSEField(value string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(value)
+}
+
+// ErrHubClosed is returned by Hub.Subscribe after the hub has been closed.
+var ErrHubClosed = errors.New("goapi: hub is closed")
+
+// HubOptions configures a Hub.
+type HubOptions struct {
+	// Buffer is the number of events queued for each subscriber. Subscribers that fall further behind
+	// are disconnected and can resume from the history using Last-Event-ID. Defaults to 16.
+	Buffer int
+	// History is the number of recent events kept to replay to reconnecting clients. Zero disables replay.
+	History int
+	// Heartbeat is the interval of heartbeat comments sent by Handler. Zero disables heartbeats.
+	Heartbeat time.Duration
+}
+
+// Hub fans out published events to every subscriber.
+type Hub struct {
+	mu          sync.Mutex
+	options     HubOptions
+	subscribers map[*Subscription]struct{}
+	history     []Event
+	nextID      uint64
+	closed      bool
+}
+
+// Subscription is a subscriber of a Hub.
+type Subscription struct {
+	hub    *Hub
+	events chan Event
+	once   sync.Once
+}
+
+// NewHub creates a Hub with the given options.
+func NewHub(options HubOptions) *Hub {
+	if options.Buffer <= 0 {
+		options.Buffer = 16
+	}
+	return &Hub{
+		options:     options,
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+// Publish sends an event to every current subscriber without blocking. Events without an ID get a
+// sequential one so that clients can resume after reconnecting.
+func (h *Hub) Publish(event Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return
+	}
+
+	if event.ID == "" {
+		h.nextID++
+		event.ID = strconv.FormatUint(h.nextID, 10)
+	}
+
+	if h.options.History > 0 {
+		h.history = append(h.history, event)
+		if len(h.history) > h.options.History {
+			h.history = h.history[len(h.history)-h.options.History:]
+		}
+	}
+
+	for subscription := range h.subscribers {
+		select {
+		case subscription.events <- event:
+		default:
+			h.removeLocked(subscription)
+		}
+	}
+}
+
+// Subscribe registers a new subscriber. When lastEventID matches an event still in the history, the
+// events published after it are queued first.
+func (h *Hub) Subscribe(lastEventID string) (*Subscription, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil, ErrHubClosed
+	}
+
+	var replay []Event
+	if lastEventID != "" {
+		for i, event := range h.history {
+			if event.ID == lastEventID {
+				replay = h.history[i+1:]
+				break
+			}
+		}
+	}
+
+	subscription := &Subscription{
+		hub:    h,
+		events: make(chan Event, h.options.Buffer+len(replay)),
+	}
+	for _, event := range replay {
+		subscription.events <- event
+	}
+	h.subscribers[subscription] = struct{}{}
+	return subscription, nil
+}
+
+// Close disconnects every subscriber and stops accepting new ones.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for subscription := range h.subscribers {
+		h.removeLocked(subscription)
+	}
+}
+
+// Handler returns a HandlerFunc that streams the hub's events to each client as Server-Sent Events,
+// replaying missed events according to the client's Last-Event-ID header.
+//
+// Example:
+//
+//	hub := goapi.NewHub(goapi.HubOptions{History: 100, Heartbeat: 15 * time.Second})
+//	api.GET("/events", hub.Handler())
+//	hub.Publish(goapi.Event{Event: "progress", Data: `{"percent":40}`})
+func (h *Hub) Handler() HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		subscription, err := h.Subscribe(r.Header.Get("Last-Event-ID"))
+		if err != nil {
+			WriteError(w, r, &HTTPError{Status: http.StatusServiceUnavailable, Message: "event stream is closed", Err: err})
+			return
+		}
+		defer subscription.Close()
+
+		stream, err := SSE(w, r)
+		if err != nil {
+			return
+		}
+		_ = stream.Stream(subscription.Events(), h.options.Heartbeat)
+	}
+}
+
+func (h *Hub) removeLocked(subscription *Subscription) {
+	if _, ok := h.subscribers[subscription]; !ok {
+		return
+	}
+	delete(h.subscribers, subscription)
+	close(subscription.events)
+}
+
+// Events returns the channel of events delivered to the subscriber. It is closed when the subscription
+// ends, either because Close was called, the subscriber fell behind, or the hub was closed.
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Close unsubscribes from the hub.
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.hub.mu.Lock()
+		defer s.hub.mu.Unlock()
+		s.hub.removeLocked(s)
+	})
+}
@@ -0,0 +1,235 @@
+package goapi
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// unwrappingWriter hides the optional interfaces of the writer it wraps, exposing only Unwrap.
+type unwrappingWriter struct {
+	http.ResponseWriter
+}
+
+func (u unwrappingWriter) Unwrap() http.ResponseWriter {
+	return u.ResponseWriter
+}
+
+func wrappingMiddleware(next HandlerFunc) HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		next(unwrappingWriter{w}, r)
+	}
+}
+
+// readEvent reads lines up to the next blank line.
+func readEvent(t *testing.T, reader *bufio.Reader) string {
+	t.Helper()
+
+	var lines []string
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("failed to read event: %v", err)
+		}
+		line = strings.TrimRight(line, "\n")
+		if line == "" {
+			return strings.Join(lines, "\n")
+		}
+		lines = append(lines, line)
+	}
+}
+
+func TestSSESend(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/events", nil)
+	req.Header.Set("Last-Event-ID", "41")
+	rec := httptest.NewRecorder()
+
+	stream, err := SSE(rec, req)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if stream.LastEventID() != "41" {
+		t.Errorf("expected last event ID %q, got %q", "41", stream.LastEventID())
+	}
+
+	if err := stream.Send(Event{ID: "42", Event: "progress", Data: "line one\nline two", Retry: 3 * time.Second}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := stream.Comment("ping"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if ct := rec.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("expected content type text/event-stream, got %q", ct)
+	}
+	if cc := rec.Header().Get("Cache-Control"); cc != "no-cache" {
+		t.Errorf("expected Cache-Control no-cache, got %q", cc)
+	}
+	expected := "id: 42\nevent: progress\nretry: 3000\ndata: line one\ndata: line two\n\n: ping\n\n"
+	if rec.Body.String() != expected {
+		t.Errorf("expected body %q, got %q", expected, rec.Body.String())
+	}
+	if !rec.Flushed {
+		t.Errorf("expected the response to be flushed")
+	}
+}
+
+func TestSSEStreamThroughMiddleware(t *testing.T) {
+	router := NewRouter()
+	router.Use(wrappingMiddleware)
+
+	events := make(chan Event)
+	finished := make(chan error, 1)
+	router.GET("/events", func(w http.ResponseWriter, r *http.Request) {
+		stream, err := SSE(w, r)
+		if err != nil {
+			finished <- err
+			return
+		}
+		finished <- stream.Stream(events, 10*time.Millisecond)
+	})
+
+	server := httptest.NewServer(http.HandlerFunc(router.ServerHTTP))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/events", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	reader := bufio.NewReader(resp.Body)
+
+	if got := readEvent(t, reader); got != ": heartbeat" {
+		t.Errorf("expected a heartbeat, got %q", got)
+	}
+
+	events <- Event{Data: "hello"}
+	for {
+		got := readEvent(t, reader)
+		if got == ": heartbeat" {
+			continue
+		}
+		if got != "data: hello" {
+			t.Errorf("expected %q, got %q", "data: hello", got)
+		}
+		break
+	}
+
+	cancel()
+	select {
+	case err := <-finished:
+		if err != context.Canceled {
+			t.Errorf("expected context.Canceled after disconnect, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("expected the stream to end when the client disconnected")
+	}
+}
+
+func TestHubPublishAndReplay(t *testing.T) {
+	hub := NewHub(HubOptions{Buffer: 4, History: 3})
+
+	first, err := hub.Subscribe("")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for _, data := range []string{"a", "b", "c", "d"} {
+		hub.Publish(Event{Data: data})
+	}
+
+	for i, expected := range []string{"1", "2", "3", "4"} {
+		event := <-first.Events()
+		if event.ID != expected {
+			t.Errorf("event %d: expected ID %q, got %q", i, expected, event.ID)
+		}
+	}
+
+	resumed, _ := hub.Subscribe("2")
+	for _, expected := range []string{"c", "d"} {
+		if event := <-resumed.Events(); event.Data != expected {
+			t.Errorf("expected replayed event %q, got %q", expected, event.Data)
+		}
+	}
+
+	unknown, _ := hub.Subscribe("1")
+	select {
+	case event := <-unknown.Events():
+		t.Errorf("expected no replay for an ID outside the history, got %+v", event)
+	default:
+	}
+}
+
+func TestHubDropsSlowSubscribers(t *testing.T) {
+	hub := NewHub(HubOptions{Buffer: 1})
+	slow, _ := hub.Subscribe("")
+
+	hub.Publish(Event{Data: "one"})
+	hub.Publish(Event{Data: "two"})
+
+	if event := <-slow.Events(); event.Data != "one" {
+		t.Errorf("expected first event, got %q", event.Data)
+	}
+	if _, ok := <-slow.Events(); ok {
+		t.Errorf("expected the slow subscriber to be closed")
+	}
+	slow.Close()
+}
+
+func TestHubClose(t *testing.T) {
+	hub := NewHub(HubOptions{})
+	subscription, _ := hub.Subscribe("")
+
+	hub.Close()
+
+	if _, ok := <-subscription.Events(); ok {
+		t.Errorf("expected subscription to be closed")
+	}
+	if _, err := hub.Subscribe(""); err != ErrHubClosed {
+		t.Errorf("expected ErrHubClosed, got %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/events", nil)
+	rec := httptest.NewRecorder()
+	hub.Handler()(rec, req)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status %d, got %d", http.StatusServiceUnavailable, rec.Code)
+	}
+}
+
+func TestHubHandler(t *testing.T) {
+	hub := NewHub(HubOptions{History: 10})
+	hub.Publish(Event{Data: "missed"})
+
+	router := NewRouter()
+	router.GET("/events", hub.Handler())
+	server := httptest.NewServer(http.HandlerFunc(router.ServerHTTP))
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/events", nil)
+	req.Header.Set("Last-Event-ID", "0")
+	hub.Publish(Event{ID: "0", Data: "marker"})
+	hub.Publish(Event{Data: "after marker"})
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	reader := bufio.NewReader(resp.Body)
+
+	if got := readEvent(t, reader); got != "id: 2\ndata: after marker" {
+		t.Errorf("expected replayed event, got %q", got)
+	}
+
+	hub.Publish(Event{Data: "live"})
+	if got := readEvent(t, reader); got != "id: 3\ndata: live" {
+		t.Errorf("expected live event, got %q", got)
+	}
+}
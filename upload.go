@@ -0,0 +1,295 @@
+package goapi
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// DefaultMaxFieldSize is the maximum size of a non-file form field accepted by ReceiveUploads
+// when UploadOptions.MaxFieldSize is not set.
+const DefaultMaxFieldSize = 1 << 20
+
+// sniffLen is the number of bytes inspected by http.DetectContentType.
+const sniffLen = 512
+
+// UploadOptions configures how ReceiveUploads streams the files of a multipart request.
+type UploadOptions struct {
+	// TempDir is the directory where files are written when Sink is nil. Defaults to os.TempDir().
+	TempDir string
+	// Sink, when set, receives the content of every file instead of TempDir. It is called once the
+	// file's content type has been sniffed; the returned writer is closed after the file is written.
+	Sink func(file *UploadedFile) (io.WriteCloser, error)
+	// MaxFileSize is the maximum size of a single file. Zero means unlimited.
+	MaxFileSize int64
+	// MaxTotalSize is the maximum combined size of all files and form fields. Zero means unlimited.
+	MaxTotalSize int64
+	// MaxFiles is the maximum number of files. Zero means unlimited.
+	MaxFiles int
+	// MaxFieldSize is the maximum size of a non-file form field. Defaults to DefaultMaxFieldSize.
+	MaxFieldSize int64
+	// AllowedTypes lists the accepted content types, as sniffed from the file content. Entries may
+	// use a wildcard subtype, such as "image/*". An empty list accepts every type.
+	AllowedTypes []string
+}
+
+// UploadedFile describes a file received by ReceiveUploads.
+type UploadedFile struct {
+	FieldName   string
+	Filename    string
+	ContentType string
+	Size        int64
+	SHA256      string
+	// Path is the location of the file when it was written to UploadOptions.TempDir.
+	Path string
+}
+
+// Upload is the result of ReceiveUploads.
+type Upload struct {
+	Files  []UploadedFile
+	Values url.Values
+}
+
+// RemoveAll deletes the temporary files written for the upload.
+func (u *Upload) RemoveAll() error {
+	var errs []error
+	for _, file := range u.Files {
+		if file.Path == "" {
+			continue
+		}
+		if err := os.Remove(file.Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ReceiveUploads streams the parts of a multipart/form-data request one at a time, writing each file to
+// a temporary file or to the configured sink while computing its size and SHA-256 checksum. Unlike
+// http.Request.ParseMultipartForm, file contents are never buffered in memory beyond the sniffing window.
+//
+// The returned error is an *HTTPError ready for WriteError: 413 Request Entity Too Large when a size or
+// count limit is exceeded, 415 Unsupported Media Type when a file's sniffed type is not allowed and
+// 400 Bad Request for malformed requests. When an error is returned, temporary files written so far are removed.
+//
+// Example:
+//
+//	api.POST("/avatars", func(w http.ResponseWriter, r *http.Request) {
+//		upload, err := goapi.ReceiveUploads(r, goapi.UploadOptions{
+//			MaxFileSize:  5 << 20,
+//			AllowedTypes: []string{"image/*"},
+//		})
+//		if err != nil {
+//			goapi.WriteError(w, r, err)
+//			return
+//		}
+//		defer upload.RemoveAll()
+//		// ...
+//	})
+func ReceiveUploads(r *http.Request, options UploadOptions) (*Upload, error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, &HTTPError{Status: http.StatusBadRequest, Message: "expected a multipart/form-data request", Err: err}
+	}
+
+	if options.MaxFieldSize <= 0 {
+		options.MaxFieldSize = DefaultMaxFieldSize
+	}
+
+	upload := &Upload{Values: make(url.Values)}
+	var total int64
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return upload, nil
+		}
+		if err != nil {
+			_ = upload.RemoveAll()
+			return nil, uploadReadError(err)
+		}
+
+		remaining := int64(-1)
+		if options.MaxTotalSize > 0 {
+			remaining = options.MaxTotalSize - total
+		}
+
+		if part.FileName() == "" {
+			value, err := readField(part, options.MaxFieldSize, remaining)
+			_ = part.Close()
+			if err != nil {
+				_ = upload.RemoveAll()
+				return nil, err
+			}
+			total += int64(len(value))
+			upload.Values.Add(part.FormName(), value)
+			continue
+		}
+
+		if options.MaxFiles > 0 && len(upload.Files) >= options.MaxFiles {
+			_ = part.Close()
+			_ = upload.RemoveAll()
+			return nil, &HTTPError{Status: http.StatusRequestEntityTooLarge, Message: fmt.Sprintf("too many files, the limit is %d", options.MaxFiles)}
+		}
+
+		file, err := receiveFile(part, options, remaining)
+		_ = part.Close()
+		if file != nil {
+			upload.Files = append(upload.Files, *file)
+		}
+		if err != nil {
+			_ = upload.RemoveAll()
+			return nil, err
+		}
+		total += file.Size
+	}
+}
+
+// readField reads a non-file form field, enforcing the field and remaining total size limits.
+func readField(part io.Reader, maxFieldSize, remaining int64) (string, error) {
+	limit := maxFieldSize
+	if remaining >= 0 && remaining < limit {
+		limit = remaining
+	}
+
+	value, err := io.ReadAll(io.LimitReader(part, limit+1))
+	if err != nil {
+		return "", uploadReadError(err)
+	}
+	if int64(len(value)) > limit {
+		return "", &HTTPError{Status: http.StatusRequestEntityTooLarge, Message: "form field exceeds the size limit"}
+	}
+	return string(value), nil
+}
+
+// receiveFile sniffs, checks and streams a single file part. On failure the returned file, if not nil,
+// still references the partially written temporary file so it can be removed.
+func receiveFile(part *multipart.Part, options UploadOptions, remaining int64) (*UploadedFile, error) {
+	limit := int64(-1)
+	if options.MaxFileSize > 0 {
+		limit = options.MaxFileSize
+	}
+	if remaining >= 0 && (limit < 0 || remaining < limit) {
+		limit = remaining
+	}
+
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(part, head)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, uploadReadError(err)
+	}
+	head = head[:n]
+
+	file := &UploadedFile{
+		FieldName:   part.FormName(),
+		Filename:    part.FileName(),
+		ContentType: http.DetectContentType(head),
+	}
+	if !typeAllowed(file.ContentType, options.AllowedTypes) {
+		return nil, &HTTPError{
+			Status:  http.StatusUnsupportedMediaType,
+			Message: fmt.Sprintf("file %q has unsupported content type %s", file.Filename, file.ContentType),
+		}
+	}
+
+	sink, err := openSink(file, options)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := sha256.New()
+	content := io.MultiReader(bytes.NewReader(head), part)
+	if limit >= 0 {
+		content = io.LimitReader(content, limit+1)
+	}
+
+	storage := &sinkWriter{writer: sink}
+	size, copyErr := io.Copy(io.MultiWriter(storage, hash), content)
+	closeErr := sink.Close()
+	file.Size = size
+	file.SHA256 = hex.EncodeToString(hash.Sum(nil))
+
+	switch {
+	case storage.err != nil:
+		return file, &HTTPError{Status: http.StatusInternalServerError, Message: "failed to store uploaded file", Err: storage.err}
+	case copyErr != nil:
+		return file, uploadReadError(copyErr)
+	case limit >= 0 && size > limit:
+		return file, &HTTPError{Status: http.StatusRequestEntityTooLarge, Message: fmt.Sprintf("file %q exceeds the size limit", file.Filename)}
+	case closeErr != nil:
+		return file, &HTTPError{Status: http.StatusInternalServerError, Message: "failed to store uploaded file", Err: closeErr}
+	}
+	return file, nil
+}
+
+// sinkWriter records the errors of the writer a file is stored to, so that they are reported as server
+// failures rather than as a malformed request.
+type sinkWriter struct {
+	writer io.Writer
+	err    error
+}
+
+func (s *sinkWriter) Write(p []byte) (int, error) {
+	n, err := s.writer.Write(p)
+	if err != nil {
+		s.err = err
+	}
+	return n, err
+}
+
+// openSink returns the writer a file is streamed to: the configured sink or a new temporary file.
+func openSink(file *UploadedFile, options UploadOptions) (io.WriteCloser, error) {
+	if options.Sink != nil {
+		sink, err := options.Sink(file)
+		if err != nil {
+			return nil, &HTTPError{Status: http.StatusInternalServerError, Message: "failed to store uploaded file", Err: err}
+		}
+		return sink, nil
+	}
+
+	tempFile, err := os.CreateTemp(options.TempDir, "upload-*")
+	if err != nil {
+		return nil, &HTTPError{Status: http.StatusInternalServerError, Message: "failed to store uploaded file", Err: err}
+	}
+	file.Path = tempFile.Name()
+	return tempFile, nil
+}
+
+// typeAllowed reports whether contentType matches one of the allowed types.
+func typeAllowed(contentType string, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, candidate := range allowed {
+		candidate = strings.ToLower(candidate)
+		if candidate == mediaType || candidate == "*/*" {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(candidate, "/*"); ok && strings.HasPrefix(mediaType, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// uploadReadError converts an error reading the request body into an *HTTPError.
+func uploadReadError(err error) *HTTPError {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return bodyTooLargeError(maxBytesErr)
+	}
+	return &HTTPError{Status: http.StatusBadRequest, Message: "malformed multipart body", Err: err}
+}
@@ -0,0 +1,260 @@
+package goapi
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+type uploadPart struct {
+	field    string
+	filename string
+	content  []byte
+}
+
+func newUploadRequest(t *testing.T, parts ...uploadPart) *http.Request {
+	t.Helper()
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for _, part := range parts {
+		if part.filename == "" {
+			if err := writer.WriteField(part.field, string(part.content)); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		fileWriter, err := writer.CreateFormFile(part.field, part.filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fileWriter.Write(part.content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/upload", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+func checksum(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestReceiveUploadsToTempDir(t *testing.T) {
+	dir := t.TempDir()
+	image := append(append([]byte{}, pngHeader...), bytes.Repeat([]byte{1}, 2048)...)
+	req := newUploadRequest(t,
+		uploadPart{field: "title", content: []byte("holiday")},
+		uploadPart{field: "photo", filename: "../../etc/photo.png", content: image},
+		uploadPart{field: "notes", filename: "notes.txt", content: []byte("plain text notes")},
+	)
+
+	upload, err := ReceiveUploads(req, UploadOptions{TempDir: dir})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if upload.Values.Get("title") != "holiday" {
+		t.Errorf("expected form value %q, got %q", "holiday", upload.Values.Get("title"))
+	}
+	if len(upload.Files) != 2 {
+		t.Fatalf("expected 2 files, got %d", len(upload.Files))
+	}
+
+	photo := upload.Files[0]
+	if photo.FieldName != "photo" || photo.Filename != "photo.png" {
+		t.Errorf("unexpected file names %q/%q", photo.FieldName, photo.Filename)
+	}
+	if photo.ContentType != "image/png" {
+		t.Errorf("expected sniffed type image/png, got %q", photo.ContentType)
+	}
+	if photo.Size != int64(len(image)) {
+		t.Errorf("expected size %d, got %d", len(image), photo.Size)
+	}
+	if photo.SHA256 != checksum(image) {
+		t.Errorf("expected checksum %s, got %s", checksum(image), photo.SHA256)
+	}
+	if filepath.Dir(photo.Path) != dir {
+		t.Errorf("expected file in %s, got %s", dir, photo.Path)
+	}
+	stored, err := os.ReadFile(photo.Path)
+	if err != nil || !bytes.Equal(stored, image) {
+		t.Errorf("expected stored content to match the upload (%v)", err)
+	}
+
+	if !strings.HasPrefix(upload.Files[1].ContentType, "text/plain") {
+		t.Errorf("expected sniffed type text/plain, got %q", upload.Files[1].ContentType)
+	}
+
+	if err := upload.RemoveAll(); err != nil {
+		t.Fatalf("expected no error removing files, got %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("expected temp dir to be empty, found %d entries", len(entries))
+	}
+}
+
+type bufferSink struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (b *bufferSink) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestReceiveUploadsToSink(t *testing.T) {
+	content := []byte("streamed content")
+	req := newUploadRequest(t, uploadPart{field: "file", filename: "data.txt", content: content})
+
+	sink := &bufferSink{}
+	var seen UploadedFile
+	upload, err := ReceiveUploads(req, UploadOptions{
+		Sink: func(file *UploadedFile) (io.WriteCloser, error) {
+			seen = *file
+			return sink, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if !bytes.Equal(sink.Bytes(), content) || !sink.closed {
+		t.Errorf("expected sink to receive the content and be closed")
+	}
+	if seen.Filename != "data.txt" || seen.ContentType == "" {
+		t.Errorf("expected sink to see file metadata, got %+v", seen)
+	}
+	if upload.Files[0].Path != "" || upload.Files[0].SHA256 != checksum(content) {
+		t.Errorf("unexpected file result %+v", upload.Files[0])
+	}
+}
+
+// failingSink fails every write, like a full disk.
+type failingSink struct{}
+
+func (failingSink) Write(p []byte) (int, error) { return 0, errors.New("no space left on device") }
+func (failingSink) Close() error                { return nil }
+
+func TestReceiveUploadsSinkFailure(t *testing.T) {
+	req := newUploadRequest(t, uploadPart{field: "file", filename: "data.txt", content: []byte("content")})
+	_, err := ReceiveUploads(req, UploadOptions{
+		Sink: func(file *UploadedFile) (io.WriteCloser, error) {
+			return failingSink{}, nil
+		},
+	})
+	if status := toHTTPError(err).Status; status != http.StatusInternalServerError {
+		t.Errorf("expected a write failure to be reported as %d, got %d (%v)", http.StatusInternalServerError, status, err)
+	}
+}
+
+func TestReceiveUploadsErrors(t *testing.T) {
+	tests := []struct {
+		name           string
+		parts          []uploadPart
+		options        UploadOptions
+		expectedStatus int
+	}{
+		{
+			name:           "File over the per-file limit",
+			parts:          []uploadPart{{field: "f", filename: "a.txt", content: bytes.Repeat([]byte("a"), 100)}},
+			options:        UploadOptions{MaxFileSize: 99},
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name: "Files over the total limit",
+			parts: []uploadPart{
+				{field: "f", filename: "a.txt", content: bytes.Repeat([]byte("a"), 60)},
+				{field: "f", filename: "b.txt", content: bytes.Repeat([]byte("b"), 60)},
+			},
+			options:        UploadOptions{MaxTotalSize: 100},
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name: "Too many files",
+			parts: []uploadPart{
+				{field: "f", filename: "a.txt", content: []byte("a")},
+				{field: "f", filename: "b.txt", content: []byte("b")},
+			},
+			options:        UploadOptions{MaxFiles: 1},
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:           "Field over the limit",
+			parts:          []uploadPart{{field: "comment", content: bytes.Repeat([]byte("a"), 20)}},
+			options:        UploadOptions{MaxFieldSize: 10},
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:           "Disallowed sniffed type",
+			parts:          []uploadPart{{field: "f", filename: "fake.png", content: []byte("not really an image")}},
+			options:        UploadOptions{AllowedTypes: []string{"image/*"}},
+			expectedStatus: http.StatusUnsupportedMediaType,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			test.options.TempDir = dir
+
+			_, err := ReceiveUploads(newUploadRequest(t, test.parts...), test.options)
+			if err == nil {
+				t.Fatalf("expected an error")
+			}
+			if status := toHTTPError(err).Status; status != test.expectedStatus {
+				t.Errorf("expected status %d, got %d (%v)", test.expectedStatus, status, err)
+			}
+			if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+				t.Errorf("expected partial files to be removed, found %d", len(entries))
+			}
+		})
+	}
+}
+
+func TestReceiveUploadsNotMultipart(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader("{}"))
+	req.Header.Set("Content-Type", "application/json")
+
+	_, err := ReceiveUploads(req, UploadOptions{})
+	if status := toHTTPError(err).Status; status != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d", http.StatusBadRequest, status)
+	}
+}
+
+func TestReceiveUploadsRespectsMaxBodySize(t *testing.T) {
+	root := &Group{}
+	root.MaxBodySize(64)
+	root.POST("/upload", func(w http.ResponseWriter, r *http.Request) {
+		_, err := ReceiveUploads(r, UploadOptions{TempDir: t.TempDir()})
+		WriteError(w, r, err)
+	})
+
+	req := newUploadRequest(t, uploadPart{field: "f", filename: "a.txt", content: bytes.Repeat([]byte("a"), 1024)})
+	req.ContentLength = -1
+	rec := httptest.NewRecorder()
+
+	root.handleRequest(rec, req)
+
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected status %d, got %d", http.StatusRequestEntityTooLarge, rec.Code)
+	}
+}
@@ -1,6 +1,30 @@
 package goapi
 
-import "net/http"
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	requestIDKey    = contextKey("request_id")
+	requestStateKey = contextKey("request_state")
+)
+
+// requestState records the values that middlewares attach to a request, so that middlewares registered
+// before them, such as access loggers, can read them once the handler returned. The router stores one in
+// the context of every request it dispatches.
+type requestState struct {
+	requestID atomic.Pointer[string]
+	principal atomic.Pointer[Principal]
+}
+
+// stateFromContext returns the state of the request, or nil when the router did not dispatch it.
+func stateFromContext(r *http.Request) *requestState {
+	state, _ := r.Context().Value(requestStateKey).(*requestState)
+	return state
+}
 
 // ParamsFromContext retrieves the route parameters from the HTTP request's context.
 // It returns a map of string to string, where the keys are the parameter names and the values are the corresponding parameter values.
@@ -15,3 +39,63 @@ func ParamsFromContext(r *http.Request) map[string]string {
 	}
 	return nil
 }
+
+// RouteFromContext retrieves the RouteInfo of the route that matched the request.
+// The second return value is false when the request was not dispatched by the router.
+//
+// Middlewares use it to report the route pattern (e.g., "/users/:id") instead of the raw path,
+// which keeps the number of distinct values in logs and metrics bounded.
+func RouteFromContext(r *http.Request) (RouteInfo, bool) {
+	info, ok := r.Context().Value(routeKey).(RouteInfo)
+	return info, ok
+}
+
+// WithRequestID returns a shallow copy of the request whose context carries the given request ID.
+// It is used by middlewares that assign request IDs; handlers read it back with RequestIDFromContext.
+func WithRequestID(r *http.Request, id string) *http.Request {
+	if state := stateFromContext(r); state != nil {
+		state.requestID.Store(&id)
+	}
+	return r.WithContext(context.WithValue(r.Context(), requestIDKey, id))
+}
+
+// RequestIDFromContext retrieves the request ID stored by WithRequestID.
+// Middlewares registered before the one assigning the ID, such as access loggers, also get it once it was assigned.
+// If the request has no ID, it returns an empty string.
+func RequestIDFromContext(r *http.Request) string {
+	if id, ok := r.Context().Value(requestIDKey).(string); ok {
+		return id
+	}
+	if state := stateFromContext(r); state != nil {
+		if id := state.requestID.Load(); id != nil {
+			return *id
+		}
+	}
+	return ""
+}
+
+// AllowedMethodsFromContext returns the HTTP methods registered for the requested path while the router
+// answers an OPTIONS request automatically, that is, for a path without an OPTIONS route of its own.
+// Middlewares such as CORS use it to answer preflight requests. It returns nil for any other request.
+func AllowedMethodsFromContext(r *http.Request) []string {
+	methods, _ := r.Context().Value(allowedMethodsKey).([]string)
+	return methods
+}
+
+// TimeRemaining returns the time left before the deadline of the request context, such as the one set by the
+// Timeout middleware. Handlers use it to budget downstream calls; passing r.Context() to them propagates the
+// deadline directly. The second return value is false when the request has no deadline.
+//
+// Example:
+//
+//	if remaining, ok := goapi.TimeRemaining(r); ok && remaining < 100*time.Millisecond {
+//		goapi.WriteError(w, r, goapi.NewHTTPError(http.StatusServiceUnavailable, "not enough time left"))
+//		return
+//	}
+func TimeRemaining(r *http.Request) (time.Duration, bool) {
+	deadline, ok := r.Context().Deadline()
+	if !ok {
+		return 0, false
+	}
+	return time.Until(deadline), true
+}
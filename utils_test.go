@@ -3,7 +3,9 @@ package goapi
 import (
 	"context"
 	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 func TestParamsFromContext(t *testing.T) {
@@ -44,3 +46,75 @@ func TestParamsFromContext(t *testing.T) {
 		_ = ParamsFromContext(req)
 	})
 }
+
+func TestRouteFromContext(t *testing.T) {
+	root := &Group{}
+	api := root.Group("/api")
+
+	var info RouteInfo
+	var found bool
+	api.GET("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		info, found = RouteFromContext(r)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/users/42", nil)
+	root.handleRequest(httptest.NewRecorder(), req)
+
+	if !found {
+		t.Fatalf("expected route info in the context")
+	}
+	if info.Method != http.MethodGet || info.Pattern != "/api/users/:id" {
+		t.Errorf("expected GET /api/users/:id, got %s %s", info.Method, info.Pattern)
+	}
+
+	if _, ok := RouteFromContext(httptest.NewRequest(http.MethodGet, "/", nil)); ok {
+		t.Errorf("expected no route info outside the router")
+	}
+}
+
+func TestRequestIDFromContext(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if id := RequestIDFromContext(req); id != "" {
+		t.Errorf("expected no request ID, got %q", id)
+	}
+
+	req = WithRequestID(req, "abc-123")
+	if id := RequestIDFromContext(req); id != "abc-123" {
+		t.Errorf("expected request ID %q, got %q", "abc-123", id)
+	}
+}
+
+func TestRequestIDFromContextOuterMiddleware(t *testing.T) {
+	var seen string
+	root := &Group{}
+	root.Use(func(next HandlerFunc) HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			next(w, r)
+			seen = RequestIDFromContext(r)
+		}
+	}, func(next HandlerFunc) HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			next(w, WithRequestID(r, "abc-123"))
+		}
+	})
+	root.GET("/", func(w http.ResponseWriter, r *http.Request) {})
+	root.handleRequest(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if seen != "abc-123" {
+		t.Errorf("expected the outer middleware to see the request ID, got %q", seen)
+	}
+}
+
+func TestTimeRemaining(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if _, ok := TimeRemaining(req); ok {
+		t.Errorf("expected no deadline")
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), time.Minute)
+	defer cancel()
+	remaining, ok := TimeRemaining(req.WithContext(ctx))
+	if !ok || remaining <= 59*time.Second || remaining > time.Minute {
+		t.Errorf("expected about a minute left, got %v (%v)", remaining, ok)
+	}
+}
@@ -0,0 +1,258 @@
+package goapi
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// FieldError describes a single field that failed validation.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+// ValidationErrors is the list of every field that failed validation.
+// It is returned by Validate and Bind and is written as a 422 response by WriteError.
+type ValidationErrors []FieldError
+
+func (v ValidationErrors) Error() string {
+	messages := make([]string, 0, len(v))
+	for _, fieldErr := range v {
+		messages = append(messages, fieldErr.Message)
+	}
+	return "validation failed: " + strings.Join(messages, "; ")
+}
+
+// Validate checks a struct against the rules declared in its `validate` struct tags and
+// returns ValidationErrors with one entry per failing rule, or nil if the value is valid.
+//
+// Supported rules, separated by commas:
+// - required: the value must not be the zero value.
+// - omitempty: skip the remaining rules when the value is the zero value.
+// - min=N, max=N: bounds on the value of numbers or the length of strings, slices and maps.
+// - email: the string must be a bare e-mail address.
+// - oneof=a b c: the value must be one of the space-separated options.
+//
+// Nested structs, pointers to structs and slices of structs are validated recursively. Fields are
+// reported by their JSON name when they have one. Malformed tags are programming errors and panic.
+//
+// Example:
+//
+//	type CreateUser struct {
+//		Name  string `json:"name" validate:"required,min=1,max=64"`
+//		Email string `json:"email" validate:"required,email"`
+//		Role  string `json:"role" validate:"oneof=admin member"`
+//	}
+//
+//	if err := goapi.Validate(input); err != nil {
+//		goapi.WriteError(w, r, err)
+//		return
+//	}
+func Validate(v any) error {
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var errs ValidationErrors
+	validateStruct(value, "", &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// validateStruct applies the tag rules of every exported field of value, appending failures to errs.
+func validateStruct(value reflect.Value, prefix string, errs *ValidationErrors) {
+	structType := value.Type()
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name := prefix + fieldName(field)
+		fieldValue := value.Field(i)
+
+		if tag, ok := field.Tag.Lookup("validate"); ok && tag != "-" {
+			if !validateField(fieldValue, name, tag, errs) {
+				continue
+			}
+		}
+
+		validateNested(fieldValue, name, errs)
+	}
+}
+
+// validateNested descends into struct, pointer-to-struct and slice-of-struct values.
+func validateNested(value reflect.Value, name string, errs *ValidationErrors) {
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return
+		}
+		value = value.Elem()
+	}
+
+	switch value.Kind() {
+	case reflect.Struct:
+		validateStruct(value, name+".", errs)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			validateNested(value.Index(i), fmt.Sprintf("%s[%d]", name, i), errs)
+		}
+	}
+}
+
+// validateField applies the rules in tag to value. It returns false when validation of the
+// field stopped early, either because a rule failed or because an empty optional value was skipped.
+func validateField(value reflect.Value, name, tag string, errs *ValidationErrors) bool {
+	for _, rule := range strings.Split(tag, ",") {
+		ruleName, param, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		if ruleName == "" {
+			continue
+		}
+
+		switch ruleName {
+		case "omitempty":
+			if value.IsZero() {
+				return false
+			}
+		case "required":
+			if value.IsZero() {
+				errs.add(name, ruleName, param, name+" is required")
+				return false
+			}
+		case "min", "max":
+			if !checkBound(value, name, ruleName, param, errs) {
+				return false
+			}
+		case "email":
+			if indirectType(value.Type()).Kind() != reflect.String {
+				panic(fmt.Sprintf("goapi: rule %q used on non-string field %s", ruleName, name))
+			}
+			s, ok := stringValue(value)
+			if !ok {
+				// A nil pointer, such as a JSON null, is an absent value: only required reports it.
+				continue
+			}
+			if addr, err := mail.ParseAddress(s); err != nil || addr.Address != s {
+				errs.add(name, ruleName, param, name+" must be a valid email address")
+				return false
+			}
+		case "oneof":
+			options := strings.Fields(param)
+			if !oneOf(value, options) {
+				errs.add(name, ruleName, param, fmt.Sprintf("%s must be one of [%s]", name, strings.Join(options, ", ")))
+				return false
+			}
+		default:
+			panic(fmt.Sprintf("goapi: unknown validation rule %q on field %s", ruleName, name))
+		}
+	}
+	return true
+}
+
+// checkBound validates a min or max rule, comparing numbers by value and everything else by length.
+func checkBound(value reflect.Value, name, ruleName, param string, errs *ValidationErrors) bool {
+	limit, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		panic(fmt.Sprintf("goapi: invalid %s parameter %q on field %s", ruleName, param, name))
+	}
+
+	value = reflect.Indirect(value)
+	var actual float64
+	var lengthBased bool
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		actual = float64(value.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		actual = float64(value.Uint())
+	case reflect.Float32, reflect.Float64:
+		actual = value.Float()
+	case reflect.String:
+		actual, lengthBased = float64(utf8.RuneCountInString(value.String())), true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		actual, lengthBased = float64(value.Len()), true
+	case reflect.Invalid:
+		return true
+	default:
+		panic(fmt.Sprintf("goapi: rule %q used on unsupported field %s", ruleName, name))
+	}
+
+	if (ruleName == "min" && actual >= limit) || (ruleName == "max" && actual <= limit) {
+		return true
+	}
+
+	var message string
+	switch {
+	case ruleName == "min" && lengthBased:
+		message = fmt.Sprintf("%s must have a length of at least %s", name, param)
+	case ruleName == "min":
+		message = fmt.Sprintf("%s must be at least %s", name, param)
+	case lengthBased:
+		message = fmt.Sprintf("%s must have a length of at most %s", name, param)
+	default:
+		message = fmt.Sprintf("%s must be at most %s", name, param)
+	}
+	errs.add(name, ruleName, param, message)
+	return false
+}
+
+// oneOf reports whether the string representation of value is one of options. A nil pointer is an absent
+// value and passes: only required reports it.
+func oneOf(value reflect.Value, options []string) bool {
+	value = reflect.Indirect(value)
+	if !value.IsValid() {
+		return true
+	}
+	actual := fmt.Sprint(value.Interface())
+	for _, option := range options {
+		if actual == option {
+			return true
+		}
+	}
+	return false
+}
+
+// stringValue returns the string held by value, dereferencing pointers.
+func stringValue(value reflect.Value) (string, bool) {
+	value = reflect.Indirect(value)
+	if value.Kind() != reflect.String {
+		return "", false
+	}
+	return value.String(), true
+}
+
+// indirectType returns the type pointed to by t, through any number of pointers.
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+// fieldName returns the name used to report a field: its JSON name if it has one, otherwise its Go name.
+func fieldName(field reflect.StructField) string {
+	if tag := field.Tag.Get("json"); tag != "" {
+		if name, _, _ := strings.Cut(tag, ","); name != "" && name != "-" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+func (v *ValidationErrors) add(field, rule, param, message string) {
+	*v = append(*v, FieldError{Field: field, Rule: rule, Param: param, Message: message})
+}
@@ -0,0 +1,204 @@
+package goapi
+
+import (
+	"testing"
+)
+
+type address struct {
+	City string `json:"city" validate:"required"`
+}
+
+type signup struct {
+	Name     string    `json:"name" validate:"required,min=2,max=8"`
+	Email    string    `json:"email" validate:"required,email"`
+	Role     string    `json:"role" validate:"oneof=admin member"`
+	Age      int       `json:"age" validate:"min=18,max=130"`
+	Nickname string    `json:"nickname" validate:"omitempty,min=3"`
+	Tags     []string  `json:"tags" validate:"max=2"`
+	Address  address   `json:"address"`
+	Others   []address `json:"others"`
+}
+
+func validSignup() signup {
+	return signup{
+		Name:    "Ana",
+		Email:   "ana@example.com",
+		Role:    "admin",
+		Age:     30,
+		Address: address{City: "Lisbon"},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name           string
+		modify         func(s *signup)
+		expectedFields []string
+		expectedRules  []string
+	}{
+		{
+			name:   "Valid struct",
+			modify: func(s *signup) {},
+		},
+		{
+			name:           "Missing required",
+			modify:         func(s *signup) { s.Name = ""; s.Email = "" },
+			expectedFields: []string{"name", "email"},
+			expectedRules:  []string{"required", "required"},
+		},
+		{
+			name:           "String length bounds",
+			modify:         func(s *signup) { s.Name = "Maximiliano" },
+			expectedFields: []string{"name"},
+			expectedRules:  []string{"max"},
+		},
+		{
+			name:           "Invalid email",
+			modify:         func(s *signup) { s.Email = "Ana <ana@example.com>" },
+			expectedFields: []string{"email"},
+			expectedRules:  []string{"email"},
+		},
+		{
+			name:           "Value not in oneof",
+			modify:         func(s *signup) { s.Role = "root" },
+			expectedFields: []string{"role"},
+			expectedRules:  []string{"oneof"},
+		},
+		{
+			name:           "Numeric bounds",
+			modify:         func(s *signup) { s.Age = 12 },
+			expectedFields: []string{"age"},
+			expectedRules:  []string{"min"},
+		},
+		{
+			name:   "Omitempty skips zero values",
+			modify: func(s *signup) { s.Nickname = "" },
+		},
+		{
+			name:           "Omitempty still validates set values",
+			modify:         func(s *signup) { s.Nickname = "ab" },
+			expectedFields: []string{"nickname"},
+			expectedRules:  []string{"min"},
+		},
+		{
+			name:           "Slice length",
+			modify:         func(s *signup) { s.Tags = []string{"a", "b", "c"} },
+			expectedFields: []string{"tags"},
+			expectedRules:  []string{"max"},
+		},
+		{
+			name:           "Nested structs",
+			modify:         func(s *signup) { s.Address.City = ""; s.Others = []address{{City: "Porto"}, {}} },
+			expectedFields: []string{"address.city", "others[1].city"},
+			expectedRules:  []string{"required", "required"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			input := validSignup()
+			test.modify(&input)
+
+			err := Validate(&input)
+			if len(test.expectedFields) == 0 {
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				return
+			}
+
+			errs, ok := err.(ValidationErrors)
+			if !ok {
+				t.Fatalf("expected ValidationErrors, got %T (%v)", err, err)
+			}
+			if len(errs) != len(test.expectedFields) {
+				t.Fatalf("expected %d errors, got %d: %v", len(test.expectedFields), len(errs), errs)
+			}
+			for i, fieldErr := range errs {
+				if fieldErr.Field != test.expectedFields[i] {
+					t.Errorf("expected field %q, got %q", test.expectedFields[i], fieldErr.Field)
+				}
+				if fieldErr.Rule != test.expectedRules[i] {
+					t.Errorf("expected rule %q, got %q", test.expectedRules[i], fieldErr.Rule)
+				}
+				if fieldErr.Message == "" {
+					t.Errorf("expected a message for field %q", fieldErr.Field)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateNonStruct(t *testing.T) {
+	if err := Validate("plain string"); err != nil {
+		t.Errorf("expected nil for non-struct values, got %v", err)
+	}
+	var nilPointer *signup
+	if err := Validate(nilPointer); err != nil {
+		t.Errorf("expected nil for nil pointers, got %v", err)
+	}
+}
+
+func TestValidateInvalidTagPanics(t *testing.T) {
+	type broken struct {
+		Name string `validate:"unknown"`
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("expected a panic for an unknown rule")
+		}
+	}()
+	_ = Validate(broken{Name: "x"})
+}
+
+func TestValidateNilPointerEmail(t *testing.T) {
+	type contact struct {
+		Email   *string `json:"email" validate:"email"`
+		Backup  *string `json:"backup" validate:"required,email"`
+		Invalid *string `json:"invalid" validate:"email"`
+	}
+
+	bad := "not an email"
+	ok := "ana@example.com"
+	err := Validate(contact{Backup: &ok, Invalid: &bad})
+	errs, isValidation := err.(ValidationErrors)
+	if !isValidation || len(errs) != 1 || errs[0].Field != "invalid" || errs[0].Rule != "email" {
+		t.Fatalf("expected only invalid to fail the email rule, got %v", err)
+	}
+
+	errs, _ = Validate(contact{}).(ValidationErrors)
+	if len(errs) != 1 || errs[0].Field != "backup" || errs[0].Rule != "required" {
+		t.Errorf("expected a nil required email to be reported as required, got %v", errs)
+	}
+}
+
+func TestValidateNilPointerOneOf(t *testing.T) {
+	type filter struct {
+		Sort  *string `json:"sort" validate:"oneof=asc desc"`
+		Order *string `json:"order" validate:"required,oneof=asc desc"`
+	}
+
+	asc, bad := "asc", "random"
+	if err := Validate(filter{Order: &asc}); err != nil {
+		t.Errorf("expected an omitted optional field to pass oneof, got %v", err)
+	}
+
+	errs, _ := Validate(filter{Sort: &bad}).(ValidationErrors)
+	if len(errs) != 2 || errs[0].Field != "sort" || errs[0].Rule != "oneof" || errs[1].Field != "order" || errs[1].Rule != "required" {
+		t.Errorf("expected sort to fail oneof and order to fail required, got %v", errs)
+	}
+}
+
+func TestValidateEmailOnNonStringPanics(t *testing.T) {
+	type broken struct {
+		Age *int `validate:"email"`
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("expected a panic for the email rule on a non-string field")
+		}
+	}()
+	_ = Validate(broken{})
+}
@@ -0,0 +1,541 @@
+package goapi
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// websocketGUID is the magic value appended to the client key to compute Sec-WebSocket-Accept (RFC 6455, section 1.3).
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// DefaultMaxMessageSize is the maximum size of a WebSocket message when WebSocketUpgrader.MaxMessageSize is not set.
+const DefaultMaxMessageSize = 1 << 20
+
+// MessageType is the type of a WebSocket data message.
+type MessageType int
+
+const (
+	TextMessage   MessageType = 1
+	BinaryMessage MessageType = 2
+)
+
+// WebSocket opcodes (RFC 6455, section 5.2).
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xA
+)
+
+// WebSocket close codes (RFC 6455, section 7.4.1).
+const (
+	CloseNormal          = 1000
+	CloseGoingAway       = 1001
+	CloseProtocolError   = 1002
+	CloseUnsupportedData = 1003
+	CloseNoStatus        = 1005
+	CloseInvalidPayload  = 1007
+	ClosePolicyViolation = 1008
+	CloseMessageTooBig   = 1009
+	CloseInternalError   = 1011
+)
+
+// CloseError is returned by WebSocketConn.ReadMessage once the connection has been closed by either side.
+type CloseError struct {
+	Code   int
+	Reason string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("websocket: closed with code %d %s", e.Code, e.Reason)
+}
+
+// WebSocketHandler handles an upgraded WebSocket connection. The connection is closed when it returns.
+type WebSocketHandler func(conn *WebSocketConn, r *http.Request)
+
+// WebSocketUpgrader upgrades HTTP requests to WebSocket connections.
+type WebSocketUpgrader struct {
+	// Subprotocols lists the supported subprotocols in order of preference.
+	Subprotocols []string
+	// CheckOrigin decides whether the request's Origin is allowed. By default requests without an Origin
+	// header and requests whose Origin host equals the Host header are accepted.
+	CheckOrigin func(r *http.Request) bool
+	// MaxMessageSize is the maximum size of a received message, after reassembling fragments.
+	// Larger messages close the connection with CloseMessageTooBig. Defaults to DefaultMaxMessageSize.
+	MaxMessageSize int64
+}
+
+// WebSocket registers a GET route that upgrades matching requests to WebSocket connections and runs handler.
+// The group's middleware chain runs before the upgrade, and route parameters remain available from the request.
+//
+// Example:
+//
+//	api.WebSocket("/ws/:room", func(conn *goapi.WebSocketConn, r *http.Request) {
+//		room := goapi.ParamsFromContext(r)["room"]
+//		for {
+//			messageType, data, err := conn.ReadMessage()
+//			if err != nil {
+//				return
+//			}
+//			_ = conn.WriteMessage(messageType, append([]byte(room+": "), data...))
+//		}
+//	})
+func (g *Group) WebSocket(pattern string, handler WebSocketHandler) *Route {
+	upgrader := &WebSocketUpgrader{}
+	return g.GET(pattern, upgrader.Handler(handler))
+}
+
+// Handler returns a HandlerFunc that upgrades the request and runs handler on the connection.
+func (u *WebSocketUpgrader) Handler(handler WebSocketHandler) HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		conn, err := u.Upgrade(w, r)
+		if err != nil {
+			return
+		}
+		defer conn.Close(CloseNormal, "")
+		handler(conn, r)
+	}
+}
+
+// Upgrade performs the opening handshake and takes over the connection. When the handshake is invalid an
+// error response is written (400, 403, or 426 with the supported version) and the error is returned.
+//
+// The connection is hijacked through http.ResponseController, so middlewares that wrap the
+// http.ResponseWriter must implement Hijack or an Unwrap method returning the original writer.
+func (u *WebSocketUpgrader) Upgrade(w http.ResponseWriter, r *http.Request) (*WebSocketConn, error) {
+	if r.Method != http.MethodGet {
+		return nil, u.fail(w, r, http.StatusMethodNotAllowed, "websocket handshake requires GET")
+	}
+	if !headerContainsToken(r.Header, "Connection", "upgrade") || !headerContainsToken(r.Header, "Upgrade", "websocket") {
+		return nil, u.fail(w, r, http.StatusBadRequest, "missing websocket upgrade headers")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		return nil, u.fail(w, r, http.StatusUpgradeRequired, "unsupported websocket version")
+	}
+
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		return nil, u.fail(w, r, http.StatusBadRequest, "invalid Sec-WebSocket-Key")
+	}
+
+	checkOrigin := u.CheckOrigin
+	if checkOrigin == nil {
+		checkOrigin = sameOrigin
+	}
+	if !checkOrigin(r) {
+		return nil, u.fail(w, r, http.StatusForbidden, "origin not allowed")
+	}
+
+	subprotocol := u.selectSubprotocol(r)
+
+	netConn, rw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		return nil, u.fail(w, r, http.StatusInternalServerError, "websocket upgrade not supported by the response writer")
+	}
+	_ = netConn.SetDeadline(time.Time{})
+
+	var response strings.Builder
+	response.WriteString("HTTP/1.1 101 Switching Protocols\r\n")
+	response.WriteString("Upgrade: websocket\r\n")
+	response.WriteString("Connection: Upgrade\r\n")
+	response.WriteString("Sec-WebSocket-Accept: " + websocketAccept(key) + "\r\n")
+	if subprotocol != "" {
+		response.WriteString("Sec-WebSocket-Protocol: " + subprotocol + "\r\n")
+	}
+	response.WriteString("\r\n")
+
+	if _, err := rw.Writer.WriteString(response.String()); err != nil {
+		_ = netConn.Close()
+		return nil, err
+	}
+	if err := rw.Writer.Flush(); err != nil {
+		_ = netConn.Close()
+		return nil, err
+	}
+
+	maxMessageSize := u.MaxMessageSize
+	if maxMessageSize <= 0 {
+		maxMessageSize = DefaultMaxMessageSize
+	}
+
+	return &WebSocketConn{
+		conn:           netConn,
+		reader:         rw.Reader,
+		subprotocol:    subprotocol,
+		maxMessageSize: maxMessageSize,
+	}, nil
+}
+
+func (u *WebSocketUpgrader) fail(w http.ResponseWriter, r *http.Request, status int, message string) error {
+	err := NewHTTPError(status, message)
+	WriteError(w, r, err)
+	return err
+}
+
+func (u *WebSocketUpgrader) selectSubprotocol(r *http.Request) string {
+	for _, supported := range u.Subprotocols {
+		for _, value := range r.Header.Values("Sec-WebSocket-Protocol") {
+			for _, requested := range strings.Split(value, ",") {
+				if strings.TrimSpace(requested) == supported {
+					return supported
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// sameOrigin accepts requests without an Origin header or whose Origin host matches the Host header.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	parsed, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(parsed.Host, r.Host)
+}
+
+// websocketAccept computes the Sec-WebSocket-Accept value for a client key.
+func websocketAccept(key string) string {
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// headerContainsToken reports whether a comma-separated header contains token, case-insensitively.
+func headerContainsToken(header http.Header, name, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, part := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// WebSocketConn is an upgraded WebSocket connection. One goroutine may read while another writes;
+// writes are serialized internally.
+type WebSocketConn struct {
+	conn           net.Conn
+	reader         *bufio.Reader
+	subprotocol    string
+	maxMessageSize int64
+
+	writeMu   sync.Mutex
+	closeSent bool
+
+	// PongHandler, if set, is called with the payload of every received pong frame.
+	PongHandler func(data []byte)
+}
+
+// Subprotocol returns the negotiated subprotocol, or an empty string.
+func (c *WebSocketConn) Subprotocol() string {
+	return c.subprotocol
+}
+
+// RemoteAddr returns the address of the peer.
+func (c *WebSocketConn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// SetReadDeadline sets the deadline for future ReadMessage calls.
+func (c *WebSocketConn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline sets the deadline for future writes.
+func (c *WebSocketConn) SetWriteDeadline(t time.Time) error {
+	return c.conn.SetWriteDeadline(t)
+}
+
+// ReadMessage returns the next data message, reassembling fragmented messages. Ping frames are answered
+// automatically and pong frames are passed to PongHandler. When the peer closes the connection, or a
+// protocol violation forces it closed, the closing handshake is completed and a *CloseError is returned.
+func (c *WebSocketConn) ReadMessage() (MessageType, []byte, error) {
+	var messageType MessageType
+	var message []byte
+	fragmented := false
+
+	for {
+		frame, err := c.readFrame()
+		if err != nil {
+			var closeErr *CloseError
+			if errors.As(err, &closeErr) {
+				c.failConnection(closeErr.Code, closeErr.Reason)
+			}
+			return 0, nil, err
+		}
+
+		switch frame.opcode {
+		case opPing:
+			if err := c.writeFrame(opPong, frame.payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case opPong:
+			if c.PongHandler != nil {
+				c.PongHandler(frame.payload)
+			}
+			continue
+		case opClose:
+			closeErr, valid := parseClosePayload(frame.payload)
+			if !valid {
+				c.failConnection(closeErr.Code, closeErr.Reason)
+				return 0, nil, closeErr
+			}
+			echo := closeErr.Code
+			if echo == CloseNoStatus {
+				echo = CloseNormal
+			}
+			c.failConnection(echo, "")
+			return 0, nil, closeErr
+		case opText, opBinary:
+			if fragmented {
+				return c.protocolError("new data frame inside a fragmented message")
+			}
+			messageType = MessageType(frame.opcode)
+			message = frame.payload
+		case opContinuation:
+			if !fragmented {
+				return c.protocolError("continuation frame without a message")
+			}
+			message = append(message, frame.payload...)
+		}
+
+		if int64(len(message)) > c.maxMessageSize {
+			err := &CloseError{Code: CloseMessageTooBig, Reason: "message too big"}
+			c.failConnection(err.Code, err.Reason)
+			return 0, nil, err
+		}
+
+		if !frame.fin {
+			fragmented = true
+			continue
+		}
+
+		if messageType == TextMessage && !utf8.Valid(message) {
+			err := &CloseError{Code: CloseInvalidPayload, Reason: "invalid UTF-8 in text message"}
+			c.failConnection(err.Code, err.Reason)
+			return 0, nil, err
+		}
+		if message == nil {
+			message = []byte{}
+		}
+		return messageType, message, nil
+	}
+}
+
+// WriteMessage sends a data message in a single frame.
+func (c *WebSocketConn) WriteMessage(messageType MessageType, data []byte) error {
+	if messageType != TextMessage && messageType != BinaryMessage {
+		return fmt.Errorf("websocket: invalid message type %d", messageType)
+	}
+	return c.writeFrame(byte(messageType), data)
+}
+
+// Ping sends a ping frame. The payload must not exceed 125 bytes.
+func (c *WebSocketConn) Ping(data []byte) error {
+	if len(data) > 125 {
+		return errors.New("websocket: control frame payload exceeds 125 bytes")
+	}
+	return c.writeFrame(opPing, data)
+}
+
+// Close sends a close frame with the given code and reason, unless one was already sent, and closes
+// the underlying connection.
+func (c *WebSocketConn) Close(code int, reason string) error {
+	c.writeMu.Lock()
+	alreadySent := c.closeSent
+	c.writeMu.Unlock()
+
+	var err error
+	if !alreadySent {
+		err = c.writeFrame(opClose, closePayload(code, reason))
+	}
+	if closeErr := c.conn.Close(); err == nil && !errors.Is(closeErr, net.ErrClosed) {
+		err = closeErr
+	}
+	return err
+}
+
+// failConnection sends a close frame and closes the connection after a closing handshake or a protocol violation.
+func (c *WebSocketConn) failConnection(code int, reason string) {
+	_ = c.Close(code, reason)
+}
+
+func (c *WebSocketConn) protocolError(reason string) (MessageType, []byte, error) {
+	err := &CloseError{Code: CloseProtocolError, Reason: reason}
+	c.failConnection(err.Code, err.Reason)
+	return 0, nil, err
+}
+
+// wsFrame is a single decoded frame.
+type wsFrame struct {
+	fin     bool
+	opcode  byte
+	payload []byte
+}
+
+// readFrame reads and unmasks one frame, validating it against RFC 6455. Protocol violations are
+// returned as *CloseError values carrying the close code to send.
+func (c *WebSocketConn) readFrame() (*wsFrame, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(c.reader, header[:]); err != nil {
+		return nil, err
+	}
+
+	frame := &wsFrame{
+		fin:    header[0]&0x80 != 0,
+		opcode: header[0] & 0x0f,
+	}
+	if header[0]&0x70 != 0 {
+		return nil, &CloseError{Code: CloseProtocolError, Reason: "reserved bits set without a negotiated extension"}
+	}
+
+	switch frame.opcode {
+	case opContinuation, opText, opBinary:
+	case opClose, opPing, opPong:
+		if !frame.fin {
+			return nil, &CloseError{Code: CloseProtocolError, Reason: "fragmented control frame"}
+		}
+	default:
+		return nil, &CloseError{Code: CloseProtocolError, Reason: "unknown opcode"}
+	}
+
+	if header[1]&0x80 == 0 {
+		return nil, &CloseError{Code: CloseProtocolError, Reason: "client frames must be masked"}
+	}
+
+	length := uint64(header[1] & 0x7f)
+	switch length {
+	case 126:
+		var extended [2]byte
+		if _, err := io.ReadFull(c.reader, extended[:]); err != nil {
+			return nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(extended[:]))
+	case 127:
+		var extended [8]byte
+		if _, err := io.ReadFull(c.reader, extended[:]); err != nil {
+			return nil, err
+		}
+		length = binary.BigEndian.Uint64(extended[:])
+		if length>>63 != 0 {
+			return nil, &CloseError{Code: CloseProtocolError, Reason: "invalid payload length"}
+		}
+	}
+
+	if frame.opcode >= opClose && length > 125 {
+		return nil, &CloseError{Code: CloseProtocolError, Reason: "control frame payload exceeds 125 bytes"}
+	}
+	if length > uint64(c.maxMessageSize) {
+		return nil, &CloseError{Code: CloseMessageTooBig, Reason: "message too big"}
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(c.reader, mask[:]); err != nil {
+		return nil, err
+	}
+
+	frame.payload = make([]byte, length)
+	if _, err := io.ReadFull(c.reader, frame.payload); err != nil {
+		return nil, err
+	}
+	for i := range frame.payload {
+		frame.payload[i] ^= mask[i%4]
+	}
+	return frame, nil
+}
+
+// writeFrame writes a single unmasked frame with the FIN bit set.
+func (c *WebSocketConn) writeFrame(opcode byte, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if c.closeSent {
+		return &CloseError{Code: CloseNormal, Reason: "close frame already sent"}
+	}
+
+	frame := make([]byte, 0, len(payload)+10)
+	frame = append(frame, 0x80|opcode)
+	switch length := len(payload); {
+	case length <= 125:
+		frame = append(frame, byte(length))
+	case length <= 0xffff:
+		frame = append(frame, 126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(length))
+	default:
+		frame = append(frame, 127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(length))
+	}
+	frame = append(frame, payload...)
+
+	if opcode == opClose {
+		c.closeSent = true
+	}
+	_, err := c.conn.Write(frame)
+	return err
+}
+
+// closePayload encodes a close code and reason, truncating the reason to fit a control frame.
+func closePayload(code int, reason string) []byte {
+	if code == CloseNoStatus {
+		return nil
+	}
+	if len(reason) > 123 {
+		reason = reason[:123]
+	}
+	payload := binary.BigEndian.AppendUint16(nil, uint16(code))
+	return append(payload, reason...)
+}
+
+// parseClosePayload decodes the payload of a received close frame. When the payload is malformed it
+// returns false and a protocol error to answer with.
+func parseClosePayload(payload []byte) (*CloseError, bool) {
+	if len(payload) == 0 {
+		return &CloseError{Code: CloseNoStatus}, true
+	}
+
+	invalid := &CloseError{Code: CloseProtocolError, Reason: "invalid close frame"}
+	if len(payload) == 1 {
+		return invalid, false
+	}
+
+	code := int(binary.BigEndian.Uint16(payload))
+	reason := payload[2:]
+	if !validCloseCode(code) || !utf8.Valid(reason) {
+		return invalid, false
+	}
+	return &CloseError{Code: code, Reason: string(reason)}, true
+}
+
+// validCloseCode reports whether code may be sent in a close frame.
+func validCloseCode(code int) bool {
+	switch {
+	case code >= 3000 && code <= 4999:
+		return true
+	case code < 1000 || code > 1014:
+		return false
+	case code == 1004 || code == CloseNoStatus || code == 1006:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,376 @@
+package goapi
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// wsTestClient is a minimal RFC 6455 client used to exercise the server implementation.
+type wsTestClient struct {
+	t      *testing.T
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func dialWebSocket(t *testing.T, server *httptest.Server, path string, header http.Header) (*wsTestClient, *http.Response) {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+path, nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	if err := req.Write(conn); err != nil {
+		t.Fatalf("handshake write failed: %v", err)
+	}
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		t.Fatalf("handshake read failed: %v", err)
+	}
+
+	client := &wsTestClient{t: t, conn: conn, reader: reader}
+	t.Cleanup(func() { conn.Close() })
+	return client, resp
+}
+
+func (c *wsTestClient) writeFrame(fin bool, opcode byte, payload []byte) {
+	c.t.Helper()
+
+	first := opcode
+	if fin {
+		first |= 0x80
+	}
+	frame := []byte{first}
+	switch length := len(payload); {
+	case length <= 125:
+		frame = append(frame, 0x80|byte(length))
+	case length <= 0xffff:
+		frame = append(frame, 0x80|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(length))
+	default:
+		frame = append(frame, 0x80|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(length))
+	}
+
+	mask := make([]byte, 4)
+	_, _ = rand.Read(mask)
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+
+	if _, err := c.conn.Write(frame); err != nil {
+		c.t.Fatalf("frame write failed: %v", err)
+	}
+}
+
+func (c *wsTestClient) readFrame() (byte, []byte) {
+	c.t.Helper()
+
+	var header [2]byte
+	if _, err := io.ReadFull(c.reader, header[:]); err != nil {
+		c.t.Fatalf("frame read failed: %v", err)
+	}
+	if header[1]&0x80 != 0 {
+		c.t.Fatalf("server frames must not be masked")
+	}
+
+	length := uint64(header[1] & 0x7f)
+	switch length {
+	case 126:
+		var extended [2]byte
+		_, _ = io.ReadFull(c.reader, extended[:])
+		length = uint64(binary.BigEndian.Uint16(extended[:]))
+	case 127:
+		var extended [8]byte
+		_, _ = io.ReadFull(c.reader, extended[:])
+		length = binary.BigEndian.Uint64(extended[:])
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		c.t.Fatalf("payload read failed: %v", err)
+	}
+	return header[0] & 0x0f, payload
+}
+
+func (c *wsTestClient) expectClose(code int) {
+	c.t.Helper()
+
+	opcode, payload := c.readFrame()
+	if opcode != opClose {
+		c.t.Fatalf("expected a close frame, got opcode %d", opcode)
+	}
+	if len(payload) < 2 || int(binary.BigEndian.Uint16(payload)) != code {
+		c.t.Fatalf("expected close code %d, got payload % x", code, payload)
+	}
+}
+
+func newEchoServer(t *testing.T, upgrader *WebSocketUpgrader) *httptest.Server {
+	t.Helper()
+
+	router := NewRouter()
+	router.Use(wrappingMiddleware)
+	handler := func(conn *WebSocketConn, r *http.Request) {
+		room := ParamsFromContext(r)["room"]
+		for {
+			messageType, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := conn.WriteMessage(messageType, append([]byte(room+":"), data...)); err != nil {
+				return
+			}
+		}
+	}
+	if upgrader == nil {
+		router.WebSocket("/ws/:room", handler)
+	} else {
+		router.GET("/ws/:room", upgrader.Handler(handler))
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(router.ServerHTTP))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestWebSocketAccept(t *testing.T) {
+	if got := websocketAccept("dGhlIHNhbXBsZSBub25jZQ=="); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("expected RFC 6455 sample accept value, got %q", got)
+	}
+}
+
+func TestWebSocketEcho(t *testing.T) {
+	server := newEchoServer(t, nil)
+	client, resp := dialWebSocket(t, server, "/ws/lobby", nil)
+
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("expected status 101, got %d", resp.StatusCode)
+	}
+	if resp.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("unexpected accept header %q", resp.Header.Get("Sec-WebSocket-Accept"))
+	}
+
+	t.Run("Text message", func(t *testing.T) {
+		client.writeFrame(true, opText, []byte("hello"))
+		opcode, payload := client.readFrame()
+		if opcode != opText || string(payload) != "lobby:hello" {
+			t.Errorf("expected text echo, got opcode %d payload %q", opcode, payload)
+		}
+	})
+
+	t.Run("Large binary message", func(t *testing.T) {
+		data := make([]byte, 70000)
+		_, _ = rand.Read(data)
+		client.writeFrame(true, opBinary, data)
+		opcode, payload := client.readFrame()
+		if opcode != opBinary || len(payload) != len("lobby:")+len(data) {
+			t.Errorf("expected binary echo of %d bytes, got opcode %d with %d bytes", len(data), opcode, len(payload))
+		}
+	})
+
+	t.Run("Fragmented message with interleaved ping", func(t *testing.T) {
+		client.writeFrame(false, opText, []byte("frag"))
+		client.writeFrame(true, opPing, []byte("are you there"))
+		client.writeFrame(false, opContinuation, []byte("men"))
+		client.writeFrame(true, opContinuation, []byte("ted"))
+
+		opcode, payload := client.readFrame()
+		if opcode != opPong || string(payload) != "are you there" {
+			t.Fatalf("expected pong echoing the ping payload, got opcode %d payload %q", opcode, payload)
+		}
+		opcode, payload = client.readFrame()
+		if opcode != opText || string(payload) != "lobby:fragmented" {
+			t.Errorf("expected reassembled message, got opcode %d payload %q", opcode, payload)
+		}
+	})
+
+	t.Run("Closing handshake", func(t *testing.T) {
+		client.writeFrame(true, opClose, binary.BigEndian.AppendUint16(nil, CloseGoingAway))
+		client.expectClose(CloseGoingAway)
+	})
+}
+
+func TestWebSocketProtocolErrors(t *testing.T) {
+	tests := []struct {
+		name         string
+		send         func(c *wsTestClient)
+		expectedCode int
+	}{
+		{
+			name: "Unmasked frame",
+			send: func(c *wsTestClient) {
+				_, _ = c.conn.Write([]byte{0x81, 0x02, 'h', 'i'})
+			},
+			expectedCode: CloseProtocolError,
+		},
+		{
+			name:         "Reserved bits",
+			send:         func(c *wsTestClient) { c.writeFrame(true, 0x40|opText, []byte("x")) },
+			expectedCode: CloseProtocolError,
+		},
+		{
+			name:         "Unknown opcode",
+			send:         func(c *wsTestClient) { c.writeFrame(true, 0x3, []byte("x")) },
+			expectedCode: CloseProtocolError,
+		},
+		{
+			name:         "Fragmented control frame",
+			send:         func(c *wsTestClient) { c.writeFrame(false, opPing, []byte("x")) },
+			expectedCode: CloseProtocolError,
+		},
+		{
+			name:         "Continuation without start",
+			send:         func(c *wsTestClient) { c.writeFrame(true, opContinuation, []byte("x")) },
+			expectedCode: CloseProtocolError,
+		},
+		{
+			name:         "Invalid UTF-8",
+			send:         func(c *wsTestClient) { c.writeFrame(true, opText, []byte{0xff, 0xfe}) },
+			expectedCode: CloseInvalidPayload,
+		},
+		{
+			name:         "Message too big",
+			send:         func(c *wsTestClient) { c.writeFrame(true, opBinary, make([]byte, 65)) },
+			expectedCode: CloseMessageTooBig,
+		},
+		{
+			name: "Fragments too big",
+			send: func(c *wsTestClient) {
+				c.writeFrame(false, opBinary, make([]byte, 40))
+				c.writeFrame(true, opContinuation, make([]byte, 40))
+			},
+			expectedCode: CloseMessageTooBig,
+		},
+		{
+			name:         "Invalid close code",
+			send:         func(c *wsTestClient) { c.writeFrame(true, opClose, binary.BigEndian.AppendUint16(nil, 1005)) },
+			expectedCode: CloseProtocolError,
+		},
+	}
+
+	server := newEchoServer(t, &WebSocketUpgrader{MaxMessageSize: 64})
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client, resp := dialWebSocket(t, server, "/ws/room", nil)
+			if resp.StatusCode != http.StatusSwitchingProtocols {
+				t.Fatalf("expected status 101, got %d", resp.StatusCode)
+			}
+
+			test.send(client)
+			client.expectClose(test.expectedCode)
+		})
+	}
+}
+
+func TestWebSocketHandshakeErrors(t *testing.T) {
+	server := newEchoServer(t, &WebSocketUpgrader{
+		CheckOrigin: func(r *http.Request) bool { return r.Header.Get("Origin") != "https://evil.example" },
+	})
+
+	tests := []struct {
+		name           string
+		header         http.Header
+		expectedStatus int
+	}{
+		{"Unsupported version", http.Header{"Sec-Websocket-Version": {"8"}}, http.StatusUpgradeRequired},
+		{"Invalid key", http.Header{"Sec-Websocket-Key": {"short"}}, http.StatusBadRequest},
+		{"Missing upgrade", http.Header{"Upgrade": {"h2c"}}, http.StatusBadRequest},
+		{"Rejected origin", http.Header{"Origin": {"https://evil.example"}}, http.StatusForbidden},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, resp := dialWebSocket(t, server, "/ws/room", test.header)
+			if resp.StatusCode != test.expectedStatus {
+				t.Errorf("expected status %d, got %d", test.expectedStatus, resp.StatusCode)
+			}
+			if test.expectedStatus == http.StatusUpgradeRequired && resp.Header.Get("Sec-WebSocket-Version") != "13" {
+				t.Errorf("expected the supported version to be advertised")
+			}
+		})
+	}
+}
+
+func TestWebSocketDefaultOriginCheck(t *testing.T) {
+	server := newEchoServer(t, nil)
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	_, resp := dialWebSocket(t, server, "/ws/room", http.Header{"Origin": {"http://" + host}})
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Errorf("expected same-origin request to be accepted, got %d", resp.StatusCode)
+	}
+
+	_, resp = dialWebSocket(t, server, "/ws/room", http.Header{"Origin": {"http://other.example"}})
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected cross-origin request to be rejected, got %d", resp.StatusCode)
+	}
+}
+
+func TestWebSocketSubprotocolAndServerPing(t *testing.T) {
+	pongs := make(chan string, 1)
+	upgrader := &WebSocketUpgrader{Subprotocols: []string{"v2.chat", "v1.chat"}}
+
+	router := NewRouter()
+	router.GET("/ws", upgrader.Handler(func(conn *WebSocketConn, r *http.Request) {
+		conn.PongHandler = func(data []byte) { pongs <- string(data) }
+		if conn.Subprotocol() != "v1.chat" {
+			_ = conn.Close(ClosePolicyViolation, "wrong subprotocol")
+			return
+		}
+		_ = conn.Ping([]byte("server ping"))
+		_, _, _ = conn.ReadMessage()
+	}))
+	server := httptest.NewServer(http.HandlerFunc(router.ServerHTTP))
+	defer server.Close()
+
+	key := base64.StdEncoding.EncodeToString(make([]byte, 16))
+	client, resp := dialWebSocket(t, server, "/ws", http.Header{
+		"Sec-Websocket-Protocol": {"v0.chat, v1.chat"},
+		"Sec-Websocket-Key":      {key},
+	})
+	if resp.Header.Get("Sec-WebSocket-Protocol") != "v1.chat" {
+		t.Fatalf("expected negotiated subprotocol v1.chat, got %q", resp.Header.Get("Sec-WebSocket-Protocol"))
+	}
+
+	opcode, payload := client.readFrame()
+	if opcode != opPing || string(payload) != "server ping" {
+		t.Fatalf("expected server ping, got opcode %d payload %q", opcode, payload)
+	}
+	client.writeFrame(true, opPong, payload)
+
+	select {
+	case got := <-pongs:
+		if got != "server ping" {
+			t.Errorf("expected pong payload %q, got %q", "server ping", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("expected the pong handler to be called")
+	}
+
+	client.writeFrame(true, opClose, nil)
+	opcode, payload = client.readFrame()
+	if opcode != opClose || int(binary.BigEndian.Uint16(payload)) != CloseNormal {
+		t.Errorf("expected normal close in response to an empty close frame, got opcode %d payload % x", opcode, payload)
+	}
+}